@@ -0,0 +1,2 @@
+ALTER TABLE locations
+DROP COLUMN IF EXISTS require_photo;
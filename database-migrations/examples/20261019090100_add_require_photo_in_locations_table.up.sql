@@ -0,0 +1,2 @@
+ALTER TABLE locations
+ADD require_photo BOOLEAN NOT NULL DEFAULT FALSE;
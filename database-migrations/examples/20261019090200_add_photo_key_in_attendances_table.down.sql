@@ -0,0 +1,3 @@
+ALTER TABLE attendances
+DROP COLUMN IF EXISTS photo_key,
+DROP COLUMN IF EXISTS id;
@@ -0,0 +1,3 @@
+ALTER TABLE attendances
+ADD id SERIAL PRIMARY KEY,
+ADD photo_key VARCHAR(200);
@@ -0,0 +1,2 @@
+ALTER TABLE attendances
+DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,2 @@
+ALTER TABLE attendances
+ADD event_id UUID UNIQUE;
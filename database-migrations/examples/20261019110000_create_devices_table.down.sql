@@ -0,0 +1,9 @@
+ALTER TABLE attendances
+DROP COLUMN IF EXISTS device_id;
+
+ALTER TABLE accounts
+DROP COLUMN IF EXISTS require_bound_device;
+
+DROP TABLE IF EXISTS devices;
+
+DROP TYPE IF EXISTS device_platform;
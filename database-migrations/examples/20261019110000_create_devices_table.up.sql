@@ -0,0 +1,24 @@
+CREATE TYPE device_platform AS ENUM ('android', 'ios', 'web');
+
+CREATE TABLE IF NOT EXISTS devices (
+  id SERIAL PRIMARY KEY,
+  account_id INT NOT NULL REFERENCES "accounts" ON UPDATE CASCADE ON DELETE CASCADE,
+  device_id VARCHAR(100) NOT NULL,
+  platform device_platform NOT NULL,
+  public_key VARCHAR(100) NOT NULL,
+  last_used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS devices_account_id_device_id_active_idx
+  ON devices (account_id, device_id)
+  WHERE revoked_at IS NULL AND deleted_at IS NULL;
+
+ALTER TABLE accounts
+ADD require_bound_device BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE attendances
+ADD device_id INT REFERENCES "devices" ON UPDATE CASCADE ON DELETE SET NULL;
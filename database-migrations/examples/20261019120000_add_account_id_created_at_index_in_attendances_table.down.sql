@@ -0,0 +1 @@
+DROP INDEX IF EXISTS attendances_account_id_created_at_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS attendances_account_id_created_at_idx
+  ON attendances (account_id, created_at DESC)
+  WHERE deleted_at IS NULL;
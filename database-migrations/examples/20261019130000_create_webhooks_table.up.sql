@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+  id SERIAL PRIMARY KEY,
+  url VARCHAR(500) NOT NULL,
+  secret VARCHAR(100) NOT NULL,
+  event_types VARCHAR(500) NOT NULL,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by INT REFERENCES "accounts" ON UPDATE CASCADE ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id SERIAL PRIMARY KEY,
+  subscription_id INT NOT NULL REFERENCES "webhook_subscriptions" ON UPDATE CASCADE ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type VARCHAR(50) NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_error VARCHAR(500),
+  response_status INT,
+  delivered_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx
+  ON webhook_deliveries (next_attempt_at)
+  WHERE status = 'pending';
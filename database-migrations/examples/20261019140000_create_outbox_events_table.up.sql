@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id BIGSERIAL PRIMARY KEY,
+  aggregate_type VARCHAR(50) NOT NULL,
+  aggregate_id INT NOT NULL,
+  event_id UUID NOT NULL UNIQUE,
+  event_type VARCHAR(50) NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_error VARCHAR(500),
+  published_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_unpublished_idx
+  ON outbox_events (id)
+  WHERE published_at IS NULL;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS accounts_manager_id_idx;
+
+ALTER TABLE accounts
+DROP CONSTRAINT IF EXISTS accounts_manager_id_check,
+DROP COLUMN IF EXISTS manager_id,
+DROP COLUMN IF EXISTS team_id,
+DROP COLUMN IF EXISTS department_id;
+
+DROP TABLE IF EXISTS teams;
+
+DROP TABLE IF EXISTS departments;
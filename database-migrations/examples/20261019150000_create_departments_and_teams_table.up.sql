@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS departments (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  description VARCHAR(500),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS departments_name_idx
+  ON departments (name)
+  WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS teams (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  department_id INT NOT NULL REFERENCES "departments" ON UPDATE CASCADE ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS teams_department_id_name_idx
+  ON teams (department_id, name)
+  WHERE deleted_at IS NULL;
+
+ALTER TABLE accounts
+ADD department_id INT REFERENCES "departments" ON UPDATE CASCADE ON DELETE SET NULL,
+ADD team_id INT REFERENCES "teams" ON UPDATE CASCADE ON DELETE SET NULL,
+ADD manager_id INT REFERENCES "accounts" ON UPDATE CASCADE ON DELETE SET NULL,
+ADD CONSTRAINT accounts_manager_id_check CHECK (manager_id <> id);
+
+CREATE INDEX IF NOT EXISTS accounts_manager_id_idx
+  ON accounts (manager_id);
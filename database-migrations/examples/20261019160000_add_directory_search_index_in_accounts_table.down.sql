@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS accounts_department_id_idx;
+
+DROP INDEX IF EXISTS accounts_directory_search_idx;
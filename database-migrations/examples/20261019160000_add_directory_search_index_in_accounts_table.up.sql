@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS accounts_directory_search_idx
+  ON accounts USING GIN ((full_name || ' ' || username || ' ' || COALESCE(employee_number, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS accounts_department_id_idx
+  ON accounts (department_id);
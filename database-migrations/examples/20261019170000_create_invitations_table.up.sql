@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS invitations (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(150) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  department_id INT REFERENCES "departments" ON UPDATE CASCADE ON DELETE SET NULL,
+  team_id INT REFERENCES "teams" ON UPDATE CASCADE ON DELETE SET NULL,
+  invited_by INT REFERENCES "accounts" ON UPDATE CASCADE ON DELETE SET NULL,
+  expires_at TIMESTAMP NOT NULL,
+  accepted_at TIMESTAMP,
+  account_id INT REFERENCES "accounts" ON UPDATE CASCADE ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS invitations_email_idx
+  ON invitations (lower(email))
+  WHERE deleted_at IS NULL AND accepted_at IS NULL;
@@ -0,0 +1,3 @@
+UPDATE accounts SET photo_url = '' WHERE photo_url = 'https://thumbs.dreamstime.com/b/user-profile-avatar-solid-black-line-icon-simple-vector-filled-flat-pictogram-isolated-white-background-134042540.jpg';
+
+UPDATE locations SET photo_url = '' WHERE photo_url = 'https://th.bing.com/th/id/OIP.gBRzG71aa1f6dy_MuGUwOAHaEo?pid=ImgDet&rs=1';
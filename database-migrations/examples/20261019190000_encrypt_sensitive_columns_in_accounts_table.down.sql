@@ -0,0 +1,13 @@
+-- encrypted values cannot be converted back here, the columns have to hold plaintext
+-- again before this runs
+DROP INDEX IF EXISTS accounts_phone_number_index_idx;
+
+DROP INDEX IF EXISTS accounts_ktp_number_index_idx;
+
+ALTER TABLE accounts
+  DROP COLUMN IF EXISTS phone_number_index,
+  DROP COLUMN IF EXISTS ktp_number_index,
+  ALTER COLUMN date_of_birth TYPE DATE USING date_of_birth::date,
+  ALTER COLUMN phone_number TYPE VARCHAR(20),
+  ALTER COLUMN ktp_number TYPE VARCHAR(50),
+  ALTER COLUMN address TYPE VARCHAR(100);
@@ -0,0 +1,13 @@
+ALTER TABLE accounts
+  ALTER COLUMN address TYPE TEXT,
+  ALTER COLUMN ktp_number TYPE TEXT,
+  ALTER COLUMN phone_number TYPE TEXT,
+  ALTER COLUMN date_of_birth TYPE TEXT USING to_char(date_of_birth, 'YYYY-MM-DD'),
+  ADD COLUMN IF NOT EXISTS ktp_number_index VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS phone_number_index VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS accounts_ktp_number_index_idx
+  ON accounts (ktp_number_index);
+
+CREATE INDEX IF NOT EXISTS accounts_phone_number_index_idx
+  ON accounts (phone_number_index);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS erasure_requests (
+  id SERIAL PRIMARY KEY,
+  account_id INT NOT NULL REFERENCES "accounts" ON UPDATE CASCADE ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL,
+  reason VARCHAR(500),
+  processed_by INT REFERENCES "accounts" ON UPDATE CASCADE ON DELETE SET NULL,
+  processed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending_account_id_idx
+  ON erasure_requests (account_id)
+  WHERE status = 'pending' AND deleted_at IS NULL;
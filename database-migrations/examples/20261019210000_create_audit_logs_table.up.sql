@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id BIGSERIAL PRIMARY KEY,
+  actor_id INT,
+  action VARCHAR(50) NOT NULL,
+  entity_type VARCHAR(50) NOT NULL,
+  entity_id INT NOT NULL,
+  changes JSONB NOT NULL DEFAULT '{}',
+  request_id VARCHAR(100),
+  client_ip VARCHAR(45),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_entity_idx
+  ON audit_logs (entity_type, entity_id, id);
+
+CREATE INDEX IF NOT EXISTS audit_logs_actor_id_idx
+  ON audit_logs (actor_id, id);
+
+CREATE INDEX IF NOT EXISTS audit_logs_created_at_idx
+  ON audit_logs (created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE outbox_events
+ADD claimed_until TIMESTAMP;
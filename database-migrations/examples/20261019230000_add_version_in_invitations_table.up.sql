@@ -0,0 +1,2 @@
+ALTER TABLE invitations
+ADD version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS device_signatures (
+  device_id INT NOT NULL REFERENCES "devices" ON UPDATE CASCADE ON DELETE CASCADE,
+  signature VARCHAR(100) NOT NULL,
+  signed_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (device_id, signature)
+);
+
+CREATE INDEX IF NOT EXISTS device_signatures_device_id_signed_at_idx
+  ON device_signatures (device_id, signed_at);
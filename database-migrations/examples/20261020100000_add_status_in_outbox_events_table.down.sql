@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS outbox_events_dead_idx;
+
+DROP INDEX IF EXISTS outbox_events_pending_idx;
+
+CREATE INDEX IF NOT EXISTS outbox_events_unpublished_idx
+  ON outbox_events (id)
+  WHERE published_at IS NULL;
+
+ALTER TABLE outbox_events
+DROP COLUMN IF EXISTS status;
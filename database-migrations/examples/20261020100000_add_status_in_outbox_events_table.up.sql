@@ -0,0 +1,16 @@
+ALTER TABLE outbox_events
+ADD status VARCHAR(20) NOT NULL DEFAULT 'pending';
+
+UPDATE outbox_events
+SET status = 'published'
+WHERE published_at IS NOT NULL;
+
+DROP INDEX IF EXISTS outbox_events_unpublished_idx;
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx
+  ON outbox_events (id)
+  WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS outbox_events_dead_idx
+  ON outbox_events (id)
+  WHERE status = 'dead';
@@ -0,0 +1,64 @@
+// Command reencrypt brings the encrypted account columns up to date with the active
+// field encryption key. Run it after adding a new key version and making it active,
+// the old version can be removed from FIELD_ENCRYPTION_KEYS once it finished.
+//
+//	go run ./src/cmd/reencrypt -batch-size 500
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/pkg/envelope"
+	"go-rest-api/src/pkg/logging"
+	accountRepository "go-rest-api/src/repository/v1/account"
+	encryptionService "go-rest-api/src/service/v1/encryption"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", constant.ReencryptBatchSize, "accounts read per batch")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("load config", err)
+	}
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logging.SetDefault(logging.New(os.Stderr, level))
+
+	keyring, err := envelope.New(cfg.FieldEncryption)
+	if err != nil {
+		fatal("load field encryption keys", err)
+	}
+	envelope.SetDefault(keyring)
+
+	accountRepo := accountRepository.NewRepository(connection.DB{
+		Master: connection.DBMaster(cfg.Database),
+	})
+	encryptionSvc := encryptionService.NewService(accountRepo, keyring)
+
+	// stopping between batches is safe, the next run skips what is already done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scanned, updated, err := encryptionSvc.Reencrypt(ctx, *batchSize)
+	if errors.Is(err, context.Canceled) {
+		logging.Default().Info("re-encrypt accounts stopped", "scanned", scanned, "updated", updated)
+		return
+	} else if err != nil {
+		fatal("re-encrypt accounts", err)
+	}
+	logging.Default().Info("re-encrypt accounts done", "scanned", scanned, "updated", updated, "key_version", keyring.ActiveVersion())
+}
+
+func fatal(msg string, err error) {
+	logging.Default().Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,347 @@
+// Package config loads the settings the server is started with. Values are read
+// from a YAML file when CONFIG_FILE is set, then from the environment and .env,
+// the environment wins. A field without a value falls back to its default tag.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+const redacted = "[redacted]"
+
+type Config struct {
+	ServiceName     string          `yaml:"service_name" env:"SERVICE_NAME" default:"go-rest-api"`
+	Server          Server          `yaml:"server"`
+	Database        Database        `yaml:"database"`
+	JWT             JWT             `yaml:"jwt"`
+	FieldEncryption FieldEncryption `yaml:"field_encryption"`
+	Swagger         Swagger         `yaml:"swagger"`
+	Tracing         Tracing         `yaml:"tracing"`
+	Log             Log             `yaml:"log"`
+	Health          Health          `yaml:"health"`
+	Storage         Storage         `yaml:"storage"`
+	Mail            Mail            `yaml:"mail"`
+	Attendance      Attendance      `yaml:"attendance"`
+	Registration    Registration    `yaml:"registration"`
+	Outbox          Outbox          `yaml:"outbox"`
+	Webhook         Webhook         `yaml:"webhook"`
+}
+
+// Server timeouts of 0 disable the timeout, the write timeout also applies to the
+// attendance stream and exports so it is disabled by default. The shutdown delay
+// keeps serving while readiness already fails, so the orchestrator can stop routing
+// requests before the server drains. The problem type url is where the error codes
+// of problem details responses are documented. The client ip is taken from
+// X-Forwarded-For only when the request comes from one of the trusted proxies, given
+// as CIDRs, and is the address of the connection otherwise.
+type Server struct {
+	Port            int           `yaml:"port" env:"SERVER_PORT" default:"5000" validate:"min=1,max=65535"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"15s" validate:"min=0"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"0s" validate:"min=0"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"60s" validate:"min=0"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" default:"30s" validate:"min=1s"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" default:"0s" validate:"min=0,ltfield=ShutdownTimeout"`
+	TLSCertFile     string        `yaml:"tls_cert_file" env:"SERVER_TLS_CERT_FILE" validate:"required_with=TLSKeyFile"`
+	TLSKeyFile      string        `yaml:"tls_key_file" env:"SERVER_TLS_KEY_FILE" validate:"required_with=TLSCertFile"`
+	ProblemTypeURL  string        `yaml:"problem_type_url" env:"SERVER_PROBLEM_TYPE_URL" validate:"omitempty,url"`
+	TrustedProxies  []string      `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES" validate:"dive,cidr"`
+}
+
+// TLS is true when the server should serve https
+func (server Server) TLS() bool {
+	return server.TLSCertFile != ""
+}
+
+type Database struct {
+	Host            string        `yaml:"host" env:"DB_POSTGRES_HOST_MASTER" default:"localhost" validate:"required"`
+	Port            int           `yaml:"port" env:"DB_POSTGRES_PORT" default:"5432" validate:"min=1,max=65535"`
+	Username        string        `yaml:"username" env:"DB_POSTGRES_USERNAME" validate:"required"`
+	Password        string        `yaml:"password" env:"DB_POSTGRES_PASSWORD" secret:"true"`
+	Name            string        `yaml:"name" env:"DB_POSTGRES_DATABASE" validate:"required"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_POSTGRES_MAX_OPEN_CONNS" default:"20" validate:"min=1"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_POSTGRES_MAX_IDLE_CONNS" default:"20" validate:"min=0,ltefield=MaxOpenConns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_POSTGRES_CONN_MAX_LIFETIME" default:"5m" validate:"min=0"`
+	QueryTimeout    time.Duration `yaml:"query_timeout" env:"DB_POSTGRES_QUERY_TIMEOUT" default:"10s" validate:"min=0"`
+}
+
+// DSN is the postgres connection string
+func (db Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s",
+		db.Host,
+		db.Port,
+		db.Username,
+		db.Name,
+		db.Password,
+	)
+}
+
+type JWT struct {
+	SecretKey string `yaml:"secret_key" env:"SECRET_KEY" secret:"true" validate:"required"`
+}
+
+// FieldEncryption keys are base64 encoded 32 byte keys. Keys lists every key
+// encryption key as comma separated version:key pairs, new values are encrypted with
+// the key version, the highest version when it is 0.
+type FieldEncryption struct {
+	Keys          string `yaml:"keys" env:"FIELD_ENCRYPTION_KEYS" secret:"true" validate:"required"`
+	KeyVersion    int    `yaml:"key_version" env:"FIELD_ENCRYPTION_KEY_VERSION" validate:"min=0"`
+	BlindIndexKey string `yaml:"blind_index_key" env:"FIELD_BLIND_INDEX_KEY" secret:"true" validate:"required,base64"`
+}
+
+type Swagger struct {
+	Host string `yaml:"host" env:"SWAGGER_HOST"`
+}
+
+// Tracing exports spans to stdout or to an OTLP/HTTP collector, the sample ratio
+// applies to traces that do not come with a sampling decision from the caller
+type Tracing struct {
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout otlp"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" default:"localhost:4318" validate:"required_if=Exporter otlp"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1" validate:"min=0,max=1"`
+}
+
+// Log lines are written as JSON to stderr, debug also logs the error responses
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+}
+
+// Health gives every dependency check of the readiness probe this long
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s" validate:"gt=0"`
+}
+
+// Storage keeps uploaded photos on the local disk or in an S3 compatible bucket. The
+// photos and placeholders are linked below the photo base url, relative when empty.
+type Storage struct {
+	Driver       string `yaml:"driver" env:"STORAGE_DRIVER" default:"local" validate:"oneof=local s3"`
+	LocalDir     string `yaml:"local_dir" env:"STORAGE_LOCAL_DIR" default:"storage" validate:"required_if=Driver local"`
+	S3Endpoint   string `yaml:"s3_endpoint" env:"STORAGE_S3_ENDPOINT" validate:"required_if=Driver s3,omitempty,url"`
+	S3Region     string `yaml:"s3_region" env:"STORAGE_S3_REGION"`
+	S3Bucket     string `yaml:"s3_bucket" env:"STORAGE_S3_BUCKET" validate:"required_if=Driver s3"`
+	S3AccessKey  string `yaml:"s3_access_key" env:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey  string `yaml:"s3_secret_key" env:"STORAGE_S3_SECRET_KEY" secret:"true"`
+	S3PathStyle  bool   `yaml:"s3_path_style" env:"STORAGE_S3_PATH_STYLE"`
+	PhotoBaseURL string `yaml:"photo_base_url" env:"PHOTO_BASE_URL" validate:"omitempty,url"`
+}
+
+// Mail is written to the log by the log driver, meant for development
+type Mail struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER" default:"log" validate:"oneof=log smtp"`
+	From         string `yaml:"from" env:"MAIL_FROM" validate:"required_if=Driver smtp"`
+	SMTPHost     string `yaml:"smtp_host" env:"MAIL_SMTP_HOST" validate:"required_if=Driver smtp"`
+	SMTPPort     int    `yaml:"smtp_port" env:"MAIL_SMTP_PORT" default:"587" validate:"min=1,max=65535"`
+	SMTPUsername string `yaml:"smtp_username" env:"MAIL_SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"MAIL_SMTP_PASSWORD" secret:"true"`
+}
+
+// Attendance events whose client time is further ahead than the clock skew tolerance
+// or older than the batch max age are rejected, the tolerance also applies to device
+// signatures. Check-ins older than the presence session max age are treated as
+// forgotten check-outs.
+type Attendance struct {
+	ClockSkewTolerance    time.Duration `yaml:"clock_skew_tolerance" env:"ATTENDANCE_CLOCK_SKEW_TOLERANCE" default:"5m" validate:"min=0"`
+	BatchMaxAge           time.Duration `yaml:"batch_max_age" env:"ATTENDANCE_BATCH_MAX_AGE" default:"72h" validate:"gt=0"`
+	PresenceSessionMaxAge time.Duration `yaml:"presence_session_max_age" env:"PRESENCE_SESSION_MAX_AGE" default:"16h" validate:"gt=0"`
+	MaxActiveDevices      int           `yaml:"max_active_devices" env:"MAXIMUM_ACTIVE_DEVICES" default:"2" validate:"min=1"`
+}
+
+// Registration is open to anyone only when open is set, meant for development,
+// otherwise an account is registered through an invitation. The invitation link is
+// the invitation url with the token appended.
+type Registration struct {
+	Open             bool          `yaml:"open" env:"OPEN_REGISTRATION"`
+	InvitationExpiry time.Duration `yaml:"invitation_expiry" env:"INVITATION_EXPIRY" default:"168h" validate:"gt=0"`
+	InvitationURL    string        `yaml:"invitation_url" env:"INVITATION_URL" validate:"omitempty,url"`
+}
+
+// Outbox events are relayed to the sinks, a failed event is retried with a doubling
+// delay up to the backoff max and is given up after max attempts. An event claimed by
+// a replica that stopped is relayed again after the claim timeout.
+type Outbox struct {
+	Sinks        []string      `yaml:"sinks" env:"OUTBOX_SINKS" default:"stream,webhook" validate:"min=1,dive,oneof=stream webhook nats"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" default:"20" validate:"min=1"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" default:"1s" validate:"gt=0"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"OUTBOX_BACKOFF_MAX" default:"5m" validate:"min=1s"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" env:"OUTBOX_CLAIM_TIMEOUT" default:"10m" validate:"min=1s"`
+	NATSURL      string        `yaml:"nats_url" env:"OUTBOX_NATS_URL" default:"nats://localhost:4222"`
+	NATSSubject  string        `yaml:"nats_subject" env:"OUTBOX_NATS_SUBJECT" default:"attendance"`
+}
+
+// Webhook deliveries are retried with a doubling delay from the backoff base up to
+// the backoff max, a delivery is given up after max attempts
+type Webhook struct {
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" default:"8" validate:"min=1"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"WEBHOOK_BACKOFF_BASE" default:"30s" validate:"gt=0"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"WEBHOOK_BACKOFF_MAX" default:"1h" validate:"gtefield=BackoffBase"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" default:"5s" validate:"gt=0"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" default:"10s" validate:"gt=0"`
+}
+
+// Load reads the config and checks it, a missing secret or an invalid value is an
+// error so the server stops before it starts serving
+func Load() (cfg Config, err error) {
+	_ = godotenv.Load()
+
+	if err = setDefaults(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("%w: read %s: %v", ErrInvalidConfig, path, err)
+		}
+		if err = yaml.UnmarshalStrict(file, &cfg); err != nil {
+			return cfg, fmt.Errorf("%w: parse %s: %v", ErrInvalidConfig, path, err)
+		}
+	}
+
+	if err = setFromEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return
+	}
+
+	err = cfg.Validate()
+	return
+}
+
+// Validate reports the invalid fields by their environment variable
+func (cfg Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+
+	err := validate.Struct(cfg)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := []string{}
+	for _, fieldError := range validationErrors {
+		fields = append(fields, fmt.Sprintf("%s failed on %s", fieldError.Field(), fieldError.Tag()))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(fields, ", "))
+}
+
+// Redacted lists every setting as env=value with the secrets left out, meant to be
+// logged at startup
+func (cfg Config) Redacted() string {
+	lines := []string{}
+	walk(reflect.ValueOf(cfg), func(field reflect.StructField, value reflect.Value) error {
+		shown := fmt.Sprint(value.Interface())
+		if list, ok := value.Interface().([]string); ok {
+			shown = strings.Join(list, ",")
+		}
+		if field.Tag.Get("secret") == "true" && shown != "" {
+			shown = redacted
+		}
+		lines = append(lines, field.Tag.Get("env")+"="+shown)
+		return nil
+	})
+	return strings.Join(lines, "\n")
+}
+
+func setDefaults(config reflect.Value) error {
+	return walk(config, func(field reflect.StructField, value reflect.Value) error {
+		fallback, ok := field.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		return set(value, fallback, field.Tag.Get("env"))
+	})
+}
+
+func setFromEnv(config reflect.Value) error {
+	return walk(config, func(field reflect.StructField, value reflect.Value) error {
+		raw, ok := os.LookupEnv(field.Tag.Get("env"))
+		if !ok || raw == "" {
+			return nil
+		}
+		return set(value, raw, field.Tag.Get("env"))
+	})
+}
+
+// walk calls fn for every setting, a setting is a field with an env tag
+func walk(config reflect.Value, fn func(field reflect.StructField, value reflect.Value) error) error {
+	for i := 0; i < config.NumField(); i++ {
+		field := config.Type().Field(i)
+		value := config.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := walk(value, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Tag.Get("env") == "" {
+			continue
+		}
+		if err := fn(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func set(value reflect.Value, raw, key string) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a duration", ErrInvalidConfig, key)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a number", ErrInvalidConfig, key)
+		}
+		value.SetInt(int64(number))
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a number", ErrInvalidConfig, key)
+		}
+		value.SetFloat(number)
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a boolean", ErrInvalidConfig, key)
+		}
+		value.SetBool(flag)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%w: %s has an unsupported type", ErrInvalidConfig, key)
+		}
+		// lists are comma separated in the environment
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%w: %s has an unsupported type", ErrInvalidConfig, key)
+	}
+	return nil
+}
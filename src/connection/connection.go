@@ -1,12 +1,10 @@
 package connection
 
 import (
-	"fmt"
-	log "github.com/forkyid/go-utils/v1/logger"
+	"go-rest-api/src/config"
+	"go-rest-api/src/pkg/logging"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"time"
 	"os"
 )
 
@@ -16,42 +14,54 @@ type DB struct {
 	Master *gorm.DB
 }
 
-func DBMaster() *gorm.DB {
+func DBMaster(cfg config.Database) *gorm.DB {
 	if db == nil {
-		log.Infof("creating database connection")
-		db = dbConnection(os.Getenv("DB_POSTGRES_HOST_MASTER"), os.Getenv("DB_POSTGRES_DATABASE"))
+		logging.Default().Info("creating database connection")
+		db = dbConnection(cfg)
 	}
 	return db
 }
 
-func dbConnection(hostType, dbName string) *gorm.DB {
-	port := os.Getenv("DB_POSTGRES_PORT")
-	postgresCon := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s",
-		hostType,
-		port,
-		os.Getenv("DB_POSTGRES_USERNAME"),
-		dbName,
-		os.Getenv("DB_POSTGRES_PASSWORD"),
-	)
-
-	db, err := gorm.Open(postgres.Open(postgresCon), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+func dbConnection(cfg config.Database) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(),
 		QueryFields: true,
 	})
 	if err != nil {
-		log.Fatalf(nil, fmt.Sprintf("failed to connect %s on %s:%s", dbName, hostType, port), err)
+		fatal("failed to connect", cfg, err)
 	}
-	log.Infof(fmt.Sprintf("successfully connected to %s on %s:%s", dbName, hostType, port))
+	logging.Default().Info("successfully connected", "database", cfg.Name, "host", cfg.Host, "port", cfg.Port)
 
 	connConfiguration, err := db.DB()
 	if err != nil {
-		log.Fatalf(nil, fmt.Sprintf("failed to connect %s on %s:%s", dbName, hostType, port), err)
+		fatal("failed to connect", cfg, err)
 	}
 
-	connConfiguration.SetConnMaxLifetime(5 * time.Minute)
-	connConfiguration.SetMaxIdleConns(20)
-	connConfiguration.SetMaxOpenConns(200)
-	connConfiguration.SetMaxOpenConns(20)
+	connConfiguration.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	connConfiguration.SetMaxIdleConns(cfg.MaxIdleConns)
+	connConfiguration.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	err = db.Use(queryTimeout{timeout: cfg.QueryTimeout})
+	if err != nil {
+		fatal("failed to register the query timeout", cfg, err)
+	}
 
 	return db
 }
+
+func fatal(msg string, cfg config.Database, err error) {
+	logging.Default().Error(msg, "database", cfg.Name, "host", cfg.Host, "port", cfg.Port, "error", err)
+	os.Exit(1)
+}
+
+// Close closes the pool, it is called once the server stopped serving
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	connConfiguration, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return connConfiguration.Close()
+}
@@ -0,0 +1,54 @@
+package connection
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/pkg/gormhook"
+
+	"gorm.io/gorm"
+)
+
+const (
+	parentContextKey = "connection:parent_context"
+	cancelKey        = "connection:cancel"
+)
+
+// queryTimeout bounds every statement by the context it was given and by timeout,
+// whichever ends first, so a request cannot hold a connection for longer. Row and
+// Scan are left alone, their rows are read after the callbacks ran.
+type queryTimeout struct {
+	timeout time.Duration
+}
+
+func (queryTimeout) Name() string {
+	return "query_timeout"
+}
+
+func (plugin queryTimeout) Initialize(db *gorm.DB) (err error) {
+	if plugin.timeout <= 0 {
+		return
+	}
+
+	return gormhook.Register(db, "query_timeout", plugin.start, plugin.end,
+		gormhook.Create, gormhook.Query, gormhook.Update, gormhook.Delete, gormhook.Raw)
+}
+
+func (plugin queryTimeout) start(db *gorm.DB, operation string) {
+	parent := db.Statement.Context
+	ctx, cancel := context.WithTimeout(parent, plugin.timeout)
+	db.InstanceSet(parentContextKey, parent)
+	db.InstanceSet(cancelKey, cancel)
+	db.Statement.Context = ctx
+}
+
+// end puts the context of the caller back, later statements of a transaction get
+// their own timeout
+func (plugin queryTimeout) end(db *gorm.DB, operation string) {
+	if parent, ok := db.InstanceGet(parentContextKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+	if cancel, ok := db.InstanceGet(cancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
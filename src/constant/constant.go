@@ -1,15 +1,17 @@
 package constant
 
 import (
-	"errors"
-	"os"
+	"net/http"
+	"time"
 
-	"github.com/joho/godotenv"
+	"go-rest-api/src/pkg/apperror"
 )
 
 const (
 	ContentTypeApplicationJson = "application/json"
+	ContentTypeTextCSV         = "text/csv"
 	DOBFormat                  = "2006-01-02"
+	DateFormat                 = "2006-01-02"
 	DBServerMaster             = "master"
 	FilterByDay                = "day"
 	FilterByWeek               = "week"
@@ -17,33 +19,178 @@ const (
 	FilterByYear               = "year"
 	StatusCheckIn              = "check-in"
 	StatusCheckOut             = "check-out"
+	RoleEmployee               = "employee"
+	RoleManager                = "manager"
+	RoleAdmin                  = "admin"
+	GenderMale                 = "male"
+	GenderFemale               = "female"
+	GenderNone                 = "none"
+	MaximumPhotoSize           = 5 << 20
+	MaximumAttendanceBatchSize = 100
+	MaximumSignedBodySize      = MaximumPhotoSize + 1<<20
+	BatchResultAccepted        = "accepted"
+	BatchResultDuplicate       = "duplicate"
+	BatchResultRejected        = "rejected"
+	HeaderDeviceID             = "X-Device-ID"
+	HeaderDeviceTimestamp      = "X-Device-Timestamp"
+	HeaderDeviceSignature      = "X-Device-Signature"
+	HeaderLastEventID          = "Last-Event-ID"
+	StreamReplaySize           = 256
+	StreamSubscriberBufferSize = 64
+	StreamKeepAliveInterval    = 15 * time.Second
+	StreamTicketTTL            = time.Minute
+	StreamChannel              = "attendance_stream"
+	EventAccountCreated        = "account.created"
+	EventAccountUpdated        = "account.updated"
+	EventAccountDeleted        = "account.deleted"
+	EventAccountErased         = "account.erased"
+	EventLocationCreated       = "location.created"
+	EventLocationUpdated       = "location.updated"
+	EventLocationDeleted       = "location.deleted"
+	EventAttendanceCheckIn     = "attendance.check-in"
+	EventAttendanceCheckOut    = "attendance.check-out"
+	EventWildcard              = "*"
+	DeliveryStatusPending      = "pending"
+	DeliveryStatusDelivered    = "delivered"
+	DeliveryStatusDead         = "dead"
+	OutboxStatusPending        = "pending"
+	OutboxStatusPublished      = "published"
+	OutboxStatusDead           = "dead"
+	HeaderWebhookID            = "X-Webhook-ID"
+	HeaderWebhookEvent         = "X-Webhook-Event"
+	HeaderWebhookSignature     = "X-Webhook-Signature"
+	AggregateAccount           = "account"
+	AggregateAttendance        = "attendance"
+	AggregateLocation          = "location"
+	OutboxBatchSize            = 100
+	OutboxLockKey              = 7207251
+	ReportingLineLockKey       = 7207252
+	SinkStream                 = "stream"
+	SinkWebhook                = "webhook"
+	SinkNATS                   = "nats"
+	DeletedExclude             = "exclude"
+	DeletedInclude             = "include"
+	DeletedOnly                = "only"
+	MaximumImportSize          = 5 << 20
+	MaximumImportRows          = 500
+	TemporaryPasswordLength    = 12
+	InvitationStatusPending    = "pending"
+	InvitationStatusAccepted   = "accepted"
+	InvitationStatusExpired    = "expired"
+	PhotoVariantSmall          = "small"
+	PhotoVariantMedium         = "medium"
+	PhotoVariantLarge          = "large"
+	FieldKTPNumber             = "ktp_number"
+	FieldPhoneNumber           = "phone_number"
+	ReencryptBatchSize         = 500
+	ErasureStatusPending       = "pending"
+	ErasureStatusCompleted     = "completed"
+	ErasureStatusRejected      = "rejected"
+	ErasedFullName             = "Erased account"
+	ErasedUsernameFormat       = "erased-%d"
+	ContentTypeApplicationZip  = "application/zip"
+	AuditActionCreate          = "create"
+	AuditActionUpdate          = "update"
+	AuditActionDelete          = "delete"
+	AuditActionErase           = "erase"
+	AuditActionErasePhotos     = "erase_photos"
+	LoginFailureUnknownAccount = "unknown_account"
+	LoginFailureWrongPassword  = "wrong_password"
 )
 
 var (
-	// db connection
-	_ = godotenv.Load()
-	ServiceName = os.Getenv("SERVICE_NAME")
-
-	// jwt
-	SampleSecretKey = []byte(os.Getenv("SECRET_KEY"))
-
-	// error
-	ErrInvalidAddress           = errors.New("invalid address")
-	ErrInvalidID                = errors.New("invalid id")
-	ErrInvalidFormat            = errors.New("invalid format")
-	ErrInvalidDOBFormat         = errors.New("invalid dob format, example : '2006-01-02'")
-	ErrInvalidLocationName      = errors.New("invalid location")
-	ErrInvalidPassword          = errors.New("invalid password")
-	ErrInvalidStatusAttendance  = errors.New("invalid status attendance")
-	ErrAccountExist             = errors.New("account already exist")
-	ErrAccountNotRegistered     = errors.New("account not registered")
-	ErrEmailAlreadyExist        = errors.New("email already exist")
-	ErrLocationAlreadyExist     = errors.New("location already exist")
-	ErrLocationNameAlreadyExist = errors.New("location name already exist")
-	ErrLocationNotExist         = errors.New("location is not exist")
-	ErrKTPNumberAlreadyExist    = errors.New("ktp number already exist")
-	ErrPasswordCannotBeEmpty    = errors.New("password cannot be empty")
-	ErrUsernameCannotBeEmpty    = errors.New("username cannot be empty")
-	ErrPhoneNumberAlreadyExist  = errors.New("phone number already exist")
-	ErrUsernameAlreadyExist     = errors.New("username already exist")
+	// error, the status and field are how the error is answered when a request fails
+	// with it, see apperror
+	ErrUnauthorized             = apperror.New("unauthorized", http.StatusUnauthorized, "", "invalid or missing bearer token")
+	ErrInvalidAddress           = apperror.New("invalid_address", http.StatusBadRequest, "address", "invalid address")
+	ErrInvalidID                = apperror.New("invalid_id", http.StatusBadRequest, "id", "invalid id")
+	ErrInvalidActorID           = apperror.New("invalid_actor_id", http.StatusBadRequest, "actor_id", "invalid actor id")
+	ErrInvalidEntityID          = apperror.New("invalid_entity_id", http.StatusBadRequest, "entity_id", "invalid entity id")
+	ErrInvalidFormat            = apperror.New("invalid_format", http.StatusBadRequest, "body", "invalid format")
+	ErrInvalidDOBFormat         = apperror.New("invalid_dob_format", http.StatusBadRequest, "date_of_birth", "invalid dob format, example : '2006-01-02'")
+	ErrInvalidLocationName      = apperror.New("invalid_location_name", http.StatusBadRequest, "name", "invalid location")
+	ErrInvalidPassword          = apperror.New("invalid_password", http.StatusBadRequest, "password", "invalid password")
+	ErrInvalidCredentials       = apperror.New("invalid_credentials", http.StatusUnauthorized, "", "invalid username or password")
+	ErrInvalidStatusAttendance  = apperror.New("invalid_status_attendance", http.StatusBadRequest, "status", "invalid status attendance")
+	ErrAccountExist             = apperror.New("account_exist", http.StatusConflict, "username", "account already exist")
+	ErrAccountNotRegistered     = apperror.New("account_not_registered", http.StatusNotFound, "account_id", "account not registered")
+	ErrAttendanceNotExist       = apperror.New("attendance_not_exist", http.StatusNotFound, "id", "attendance is not exist")
+	ErrClockSkewExceeded        = apperror.New("clock_skew_exceeded", http.StatusBadRequest, "client_time", "client time is ahead of server time beyond tolerance")
+	ErrDeviceAlreadyRegistered  = apperror.New("device_already_registered", http.StatusConflict, "device_id", "device is already registered, it must be revoked before registering it again")
+	ErrDeviceLimitReached       = apperror.New("device_limit_reached", http.StatusConflict, "device", "active device limit reached, revoke a device first")
+	ErrDeviceNotBound           = apperror.New("device_not_bound", http.StatusForbidden, "device", "attendance must be submitted from a bound device")
+	ErrDeviceNotExist           = apperror.New("device_not_exist", http.StatusNotFound, "id", "device is not exist")
+	ErrDeviceSignatureUsed      = apperror.New("device_signature_used", http.StatusForbidden, "device", "device signature has already been used")
+	ErrDepartmentNotEmpty       = apperror.New("department_not_empty", http.StatusConflict, "id", "department still has teams or accounts")
+	ErrDepartmentNotExist       = apperror.New("department_not_exist", http.StatusNotFound, "department_id", "department is not exist")
+	ErrDepartmentAlreadyExist   = apperror.New("department_already_exist", http.StatusConflict, "name", "department already exist")
+	ErrFieldRequired            = apperror.New("field_required", http.StatusBadRequest, "", "field is required")
+	ErrFieldTooLong             = apperror.New("field_too_long", http.StatusBadRequest, "", "field is too long")
+	ErrInvalidDeviceSignature   = apperror.New("invalid_device_signature", http.StatusForbidden, "device", "invalid device signature")
+	ErrInvalidPublicKey         = apperror.New("invalid_public_key", http.StatusBadRequest, "public_key", "invalid public key, expected base64 encoded ed25519 key")
+	ErrEmailAlreadyExist        = apperror.New("email_already_exist", http.StatusConflict, "email", "email already exist")
+	ErrErasureRequestExist      = apperror.New("erasure_request_exist", http.StatusConflict, "account", "an erasure request is already pending")
+	ErrErasureRequestNotExist   = apperror.New("erasure_request_not_exist", http.StatusNotFound, "id", "erasure request is not exist")
+	ErrErasureRequestProcessed  = apperror.New("erasure_request_processed", http.StatusConflict, "id", "erasure request has already been processed")
+	ErrEventIDAlreadyUsed       = apperror.New("event_id_already_used", http.StatusConflict, "event_id", "event id already used by another account")
+	ErrEventTooOld              = apperror.New("event_too_old", http.StatusBadRequest, "client_time", "client time is older than the allowed offline window")
+	ErrInvalidInvitationToken   = apperror.New("invalid_invitation_token", http.StatusBadRequest, "token", "invalid invitation token")
+	ErrInvitationAlreadyExist   = apperror.New("invitation_already_exist", http.StatusConflict, "email", "a pending invitation already exist for the email")
+	ErrInvitationAlreadyUsed    = apperror.New("invitation_already_used", http.StatusConflict, "token", "invitation has already been used")
+	ErrInvitationExpired        = apperror.New("invitation_expired", http.StatusGone, "token", "invitation has expired")
+	ErrInvitationNotExist       = apperror.New("invitation_not_exist", http.StatusNotFound, "token", "invitation is not exist")
+	ErrInvitationRequired       = apperror.New("invitation_required", http.StatusForbidden, "account", "registration needs an invitation")
+	ErrImportColumnMissing      = apperror.New("import_column_missing", http.StatusBadRequest, "file", "required column is missing")
+	ErrImportDuplicateValue     = apperror.New("import_duplicate_value", http.StatusBadRequest, "", "value is repeated in the file")
+	ErrImportEmpty              = apperror.New("import_empty", http.StatusBadRequest, "file", "file has no account rows")
+	ErrImportFileTooLarge       = apperror.New("import_file_too_large", http.StatusBadRequest, "file", "file is too large, maximum size is 5 MB")
+	ErrImportRowsInvalid        = apperror.New("import_rows_invalid", http.StatusUnprocessableEntity, "file", "some rows are invalid, nothing was imported")
+	ErrImportTooManyRows        = apperror.New("import_too_many_rows", http.StatusBadRequest, "file", "file has too many rows, maximum is 500")
+	ErrInvalidGender            = apperror.New("invalid_gender", http.StatusBadRequest, "gender", "invalid gender, only male, female and none are allowed")
+	ErrInvalidEmail             = apperror.New("invalid_email", http.StatusBadRequest, "email", "invalid email")
+	ErrInvalidImportFile        = apperror.New("invalid_import_file", http.StatusBadRequest, "file", "invalid file, only csv and xlsx are allowed")
+	ErrInvalidKTPNumber         = apperror.New("invalid_ktp_number", http.StatusBadRequest, "ktp_number", "invalid ktp number")
+	ErrInvalidPhoneNumber       = apperror.New("invalid_phone_number", http.StatusBadRequest, "phone_number", "invalid phone number, example : '081234567890' or '+6281234567890'")
+	ErrInvalidRole              = apperror.New("invalid_role", http.StatusBadRequest, "role", "invalid role")
+	ErrInvalidEventType         = apperror.New("invalid_event_type", http.StatusBadRequest, "event_types", "invalid event type")
+	ErrInvalidWebhookURL        = apperror.New("invalid_webhook_url", http.StatusBadRequest, "url", "invalid webhook url, expected an absolute http or https url")
+	ErrLocationAlreadyExist     = apperror.New("location_already_exist", http.StatusConflict, "name", "location already exist")
+	ErrLocationNameAlreadyExist = apperror.New("location_name_already_exist", http.StatusConflict, "name", "location name already exist")
+	ErrLocationNotExist         = apperror.New("location_not_exist", http.StatusNotFound, "location_id", "location is not exist")
+	ErrManagerNotExist          = apperror.New("manager_not_exist", http.StatusNotFound, "manager_id", "manager is not exist")
+	ErrReportingLineCycle       = apperror.New("reporting_line_cycle", http.StatusBadRequest, "manager_id", "manager cannot report to the account, directly or indirectly")
+	ErrKTPNumberAlreadyExist    = apperror.New("ktp_number_already_exist", http.StatusConflict, "ktp_number", "ktp number already exist")
+	ErrKTPNumberNotMatch        = apperror.New("ktp_number_not_match", http.StatusBadRequest, "ktp_number", "ktp number does not match the date of birth or gender")
+	ErrPasswordCannotBeEmpty    = apperror.New("password_cannot_be_empty", http.StatusBadRequest, "password", "password cannot be empty")
+	ErrPermissionDenied         = apperror.New("permission_denied", http.StatusForbidden, "", "permission denied")
+	ErrPhotoNotExist            = apperror.New("photo_not_exist", http.StatusNotFound, "photo", "photo is not exist")
+	ErrPhotoRequired            = apperror.New("photo_required", http.StatusBadRequest, "photo", "photo is required for this location")
+	ErrPhotoDimensionsTooLarge  = apperror.New("photo_dimensions_too_large", http.StatusBadRequest, "photo", "photo dimensions are too large")
+	ErrSignedBodyTooLarge       = apperror.New("signed_body_too_large", http.StatusRequestEntityTooLarge, "body", "signed request body is too large, maximum size is 6 MB")
+	ErrPhotoTooLarge            = apperror.New("photo_too_large", http.StatusBadRequest, "photo", "photo is too large, maximum size is 5 MB")
+	ErrInvalidPhotoType         = apperror.New("invalid_photo_type", http.StatusBadRequest, "photo", "invalid photo type, only jpeg and png are allowed")
+	ErrUsernameCannotBeEmpty    = apperror.New("username_cannot_be_empty", http.StatusBadRequest, "username", "username cannot be empty")
+	ErrWebhookDeliveryNotExist  = apperror.New("webhook_delivery_not_exist", http.StatusNotFound, "id", "webhook delivery is not exist")
+	ErrWebhookNotExist          = apperror.New("webhook_not_exist", http.StatusNotFound, "id", "webhook is not exist")
+	ErrWebhookURLNotAllowed     = apperror.New("webhook_url_not_allowed", http.StatusBadRequest, "url", "webhook url must resolve to a public address")
+	ErrOutboxEventNotExist      = apperror.New("outbox_event_not_exist", http.StatusNotFound, "id", "outbox event is not exist")
+	ErrPhoneNumberAlreadyExist  = apperror.New("phone_number_already_exist", http.StatusConflict, "phone_number", "phone number already exist")
+	ErrTeamNotEmpty             = apperror.New("team_not_empty", http.StatusConflict, "id", "team still has accounts")
+	ErrTeamNotExist             = apperror.New("team_not_exist", http.StatusNotFound, "team_id", "team is not exist")
+	ErrTeamNameAlreadyExist     = apperror.New("team_name_already_exist", http.StatusConflict, "name", "team name already exist in the department")
+	ErrTeamNotInDepartment      = apperror.New("team_not_in_department", http.StatusBadRequest, "team_id", "team does not belong to the department")
+	ErrUsernameAlreadyExist     = apperror.New("username_already_exist", http.StatusConflict, "username", "username already exist")
 )
+
+// EventTypes lists every event type a webhook can subscribe to
+var EventTypes = []string{
+	EventAccountCreated,
+	EventAccountUpdated,
+	EventAccountDeleted,
+	EventAccountErased,
+	EventLocationCreated,
+	EventLocationUpdated,
+	EventLocationDeleted,
+	EventAttendanceCheckIn,
+	EventAttendanceCheckOut,
+}
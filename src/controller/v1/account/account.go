@@ -1,17 +1,21 @@
 package account
 
 import (
-	"log"
 	"strings"
 	"net/http"
 
 	echo "github.com/labstack/echo/v4"
 	entity "go-rest-api/src/http"
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
 	"github.com/forkyid/go-utils/v1/validation"
 	"github.com/pkg/errors"
 	"go-rest-api/src/constant"
+	"go-rest-api/src/controller/v1/upload"
 	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
 	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/pkg/sheet"
 	"go-rest-api/src/service/v1/account"
 )
 
@@ -37,66 +41,87 @@ func NewController(
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/accounts [get]
-func (ctrl *Controller) Get(ctx echo.Context) {
+func (ctrl *Controller) Get(ctx echo.Context) error {
 	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
-	response, err := ctrl.svc.TakeAccountByID(accountID)
+	response, err := ctrl.svc.TakeAccountByID(ctx.Request().Context(), accountID)
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("get account by id:", err)
-		return
+		return errors.Wrap(err, "get account by id")
 	}
 
 	rest.ResponseData(ctx, http.StatusOK, response)
-	return
+	return nil
+}
+
+// @Summary Get Reports
+// @Description Get the accounts reporting to the logged in account, every level below it unless direct is set
+// @Tags Accounts
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param direct query bool false "only direct reports"
+// @Success 200 {object} []http.GetUser
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/accounts/reports [get]
+func (ctrl *Controller) GetReports(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindReports)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("direct")
+	}
+
+	response, err := ctrl.svc.FindReports(ctx.Request().Context(), accountID, req.Direct)
+	if err != nil {
+		return errors.Wrap(err, "get reports")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
 }
 
 // Register godoc
 // @Summary Register Account
-// @Description Register Account
+// @Description Register Account, only available when open registration is enabled, otherwise accounts are registered by accepting an invitation
 // @Tags Accounts
 // @Param Payload body http.RegisterUser true "Payload"
 // @Success 201 {object} string "Created"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 409 {string} string "Resource Conflict"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/accounts/register [post]
-func (ctrl *Controller) Register(ctx echo.Context) {
+func (ctrl *Controller) Register(ctx echo.Context) error {
 	req := new(entity.RegisterUser)
 	if err := ctx.Bind(req); err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		return constant.ErrInvalidFormat
 	}
 
 	// required tapi tidak diisi akan return bad request
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
 	request := *req
 	if request.Username == "" {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"username": constant.ErrInvalidFormat.Error()})
-		return
+		return constant.ErrInvalidFormat.WithField("username")
 	}
 	request.Username = strings.ToLower(request.Username)
-	err := ctrl.svc.Create(request)
-	if errors.Is(err, constant.ErrAccountExist) {
-		rest.ResponseError(ctx, http.StatusConflict, map[string]string{
-			"account": constant.ErrAccountExist.Error()})
-	} else if err != nil {
-		log.Println("register:", err.Error())
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-	} else {
-		rest.ResponseMessage(ctx, http.StatusCreated)
+	err := ctrl.svc.Create(ctx.Request().Context(), rest.Actor(ctx, 0), request)
+	if err != nil {
+		return errors.Wrap(err, "register")
 	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
 }
 
 // Update godoc
@@ -107,74 +132,70 @@ func (ctrl *Controller) Register(ctx echo.Context) {
 // @Param Payload body http.UpdateUser true "Payload"
 // @Success 200 {string} string "Success"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/accounts [patch]
-func (ctrl *Controller) Update(ctx echo.Context) {
+func (ctrl *Controller) Update(ctx echo.Context) error {
 	req := new(entity.UpdateUser)
 	err := ctx.Bind(req)
 	if err != nil {
-		log.Println("bind json:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Info("bind json", "error", err, "request", req)
+		return constant.ErrInvalidFormat
 	}
 
 	// required tapi tidak diisi akan return bad request
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
 	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
 	request := *req
 	*request.Username = strings.ToLower(*request.Username)
-	err = ctrl.svc.Update(accountID, request)
-	if err != nil {
-		if errors.Is(err, constant.ErrAccountNotRegistered) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrAccountNotRegistered.Error()})
-			return
-		} else if errors.Is(err, constant.ErrUsernameCannotBeEmpty) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrUsernameCannotBeEmpty.Error()})
-			return
-		} else if errors.Is(err, constant.ErrPasswordCannotBeEmpty) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrPasswordCannotBeEmpty.Error()})
-			return
-		} else if errors.Is(err, constant.ErrUsernameAlreadyExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrUsernameAlreadyExist.Error()})
-			return
-		} else if errors.Is(err, constant.ErrEmailAlreadyExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrEmailAlreadyExist.Error()})
-			return
-		} else if errors.Is(err, constant.ErrKTPNumberAlreadyExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrKTPNumberAlreadyExist.Error()})
-			return
-		} else if errors.Is(err, constant.ErrPhoneNumberAlreadyExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrPhoneNumberAlreadyExist.Error()})
-			return
-		} else if errors.Is(err, constant.ErrInvalidDOBFormat) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrInvalidDOBFormat.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("update account: ", err.Error())
-		return
+	err = ctrl.svc.Update(ctx.Request().Context(), rest.Actor(ctx, accountID), accountID, request)
+	if err != nil {
+		return errors.Wrap(err, "update account")
 	}
 
 	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Update Account Photo
+// @Description Upload the account photo, it is cropped to a square and stored in several sizes. The returned url points to the medium size, the small and large sizes sit next to it.
+// @Tags Accounts
+// @Accept multipart/form-data
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param photo formData file true "Photo (jpeg or png, max 5 MB)"
+// @Success 200 {object} http.UpdatedPhoto
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/accounts/photo [put]
+func (ctrl *Controller) UpdatePhoto(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	photo, file, err := upload.FormPhoto(ctx)
+	if err != nil {
+		return errors.Wrap(err, "open account photo")
+	}
+	defer file.Close()
+
+	photoURL, err := ctrl.svc.UpdatePhoto(ctx.Request().Context(), rest.Actor(ctx, accountID), accountID, photo)
+	if err != nil {
+		return errors.Wrap(err, "update account photo")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, entity.UpdatedPhoto{PhotoURL: photoURL})
+	return nil
 }
 
 // Delete godoc
@@ -184,27 +205,142 @@ func (ctrl *Controller) Update(ctx echo.Context) {
 // @Param Authorization header string true "Bearer Token"
 // @Success 200 {string} string "Success"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
 // @Failure 409 {string} string "Resource Conflict"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/accounts [delete]
-func (ctrl *Controller) Delete(ctx echo.Context) {
+func (ctrl *Controller) Delete(ctx echo.Context) error {
 	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
-	err = ctrl.svc.Delete(accountID)
+	err = ctrl.svc.Delete(ctx.Request().Context(), rest.Actor(ctx, accountID), accountID)
 	if err != nil {
-		if errors.Is(err, constant.ErrAccountNotRegistered) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrAccountNotRegistered.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("delete account: ", err.Error())
-		return
+		return errors.Wrap(err, "delete account")
 	}
 		
 	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Get Account Directory
+// @Description Search and page through every account, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param search query string false "words matched against full name, username and employee number"
+// @Param department_id query int false "department id"
+// @Param is_verified query bool false "verified accounts only, or unverified when false"
+// @Param job_position query string false "job position, case insensitive"
+// @Param deleted query string false "deleted accounts" Enums(exclude, include, only)
+// @Param sort query string false "sort field, prefix with - for descending" Enums(full_name, -full_name, username, -username, employee_number, -employee_number, created_at, -created_at)
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} rest.ResponsePaginationResult{data=[]http.GetAccount}
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/accounts [get]
+func (ctrl *Controller) GetDirectory(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindAccounts)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.FindDirectory(ctx.Request().Context(), adminID, *req, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get account directory")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
+
+// @Summary Import Accounts
+// @Description Register accounts from a csv or xlsx file, admin only. The first row names the columns: username, fullname and email are required, phone_number, ktp_number, employee_number, job_position, address, gender, date_of_birth and role are optional. Every row is checked first and nothing is imported when a row is invalid, use dry_run to only check the file. Imported accounts get a temporary password by email.
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param dry_run query bool false "only check the file"
+// @Param file formData file true "csv or xlsx file, max 5 MB"
+// @Success 200 {object} http.ImportAccountsResult
+// @Success 201 {object} http.ImportAccountsResult
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {object} http.ImportAccountsResult
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/accounts/import [post]
+func (ctrl *Controller) Import(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.ImportAccounts)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("dry_run")
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return constant.ErrInvalidImportFile
+	}
+	if fileHeader.Size > constant.MaximumImportSize {
+		return constant.ErrImportFileTooLarge
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return constant.ErrInvalidImportFile
+	}
+	defer file.Close()
+
+	rows, err := sheet.Read(file, fileHeader.Size, fileHeader.Filename)
+	if err != nil {
+		logging.FromContext(ctx.Request().Context()).Error("read import file", "error", err)
+		return constant.ErrInvalidImportFile
+	}
+
+	response, err := ctrl.svc.Import(ctx.Request().Context(), rest.Actor(ctx, adminID), rows, req.DryRun)
+	// the row errors are the answer, not only a detail of it
+	if errors.Is(err, constant.ErrImportRowsInvalid) {
+		rest.ResponseData(ctx, http.StatusUnprocessableEntity, response, constant.ErrImportRowsInvalid.Error())
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "import accounts")
+	}
+
+	if req.DryRun {
+		rest.ResponseData(ctx, http.StatusOK, response)
+		return nil
+	}
+	rest.ResponseData(ctx, http.StatusCreated, response)
+	return nil
 }
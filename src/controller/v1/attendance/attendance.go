@@ -1,19 +1,29 @@
 package attendance
 
 import (
-	"log"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"go-rest-api/src/constant"
+	"go-rest-api/src/controller/v1/upload"
 	entity "go-rest-api/src/http"
 	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
 	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/pubsub"
+	"go-rest-api/src/pkg/rest"
 	"go-rest-api/src/service/v1/attendance"
 
-	"github.com/forkyid/go-utils/v1/rest"
+	"github.com/forkyid/go-utils/v1/aes"
 	"github.com/forkyid/go-utils/v1/validation"
-	"github.com/gin-gonic/gin"
+	echo "github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 )
 
@@ -42,68 +52,65 @@ func NewController(
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/attendance/history [get]
-func (ctrl *Controller) Get(ctx *gin.Context) {
-	accountID, err := jwt.ExtractID(ctx.GetHeader("Authorization"))
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
-	limitString := ctx.Query("Limit")
-	limit, err := strconv.Atoi(limitString)
+	pgn, filter, err := historyParams(ctx)
 	if err != nil {
-		log.Print(err)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"limit": constant.ErrInvalidFormat.Error()})
-		return
+		return err
 	}
-	limit = int(limit)
-	pageString := ctx.Query("Page")
-	page, err := strconv.Atoi(pageString)
+
+	response, err := ctrl.svc.FindAttendanceHistory(ctx.Request().Context(), accountID, pgn, filter)
 	if err != nil {
-		log.Print(err)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"page": constant.ErrInvalidFormat.Error()})
-		return
+		return errors.Wrap(err, "get attendance by account id")
 	}
-	page = int(page)
-	if limit < 0 || limit == 0 {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"limit": constant.ErrInvalidFormat.Error()})
-		return
-	}
-	if page < 0 || page == 0 {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"page": constant.ErrInvalidFormat.Error()})
-		return
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Get Report Attendance History
+// @Description Get the attendance history of an account reporting to the logged in manager, directly or indirectly
+// @Tags Attendance
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path string true "encrypted account id"
+// @Param Page query string true "page"
+// @Param Limit query string true "limit"
+// @Param Filter query string true "string enums" Enums(day, week, month, year)
+// @Success 200 {object} http.GetAttendance
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/attendance/reports/{id} [get]
+func (ctrl *Controller) GetReport(ctx echo.Context) error {
+	managerID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
 	}
-	pgn := pagination.Pagination{
-		Limit:  limit,
-		Page:   page,
+
+	accountID := aes.Decrypt(ctx.Param("id"))
+	if accountID < 0 {
+		return constant.ErrInvalidID
 	}
-	pgn.Paginate()
 
-	filter := ctx.Query("Filter")
-	log.Print(filter)
-	if filter != constant.FilterByDay && filter != constant.FilterByWeek && filter != constant.FilterByMonth && filter != constant.FilterByYear {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"filter": constant.ErrInvalidFormat.Error()})
-		return
+	pgn, filter, err := historyParams(ctx)
+	if err != nil {
+		return err
 	}
 
-	response, err := ctrl.svc.FindAttendanceHistory(accountID, pgn, filter)
+	response, err := ctrl.svc.FindReportAttendanceHistory(ctx.Request().Context(), managerID, accountID, pgn, filter)
 	if err != nil {
-		if errors.Is(err, constant.ErrAccountNotRegistered) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"account_id": constant.ErrAccountNotRegistered.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("get attendance by account id:", err)
-		return
+		return errors.Wrap(err, "get report attendance history")
 	}
 
 	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
 }
 
 // @Summary Get Attendance Status By Locations
@@ -118,40 +125,31 @@ func (ctrl *Controller) Get(ctx *gin.Context) {
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/attendance/locations [get]
-func (ctrl *Controller) GetByLocation(ctx *gin.Context) {
-	accountID, err := jwt.ExtractID(ctx.GetHeader("Authorization"))
+func (ctrl *Controller) GetByLocation(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
-	limitString := ctx.Query("Limit")
+	limitString := ctx.QueryParam("Limit")
 	limit, err := strconv.Atoi(limitString)
 	if err != nil {
-		log.Print(err)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"limit": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Debug("invalid query", "error", err)
+		return constant.ErrInvalidFormat.WithField("limit")
 	}
 	limit = int(limit)
-	pageString := ctx.Query("Page")
+	pageString := ctx.QueryParam("Page")
 	page, err := strconv.Atoi(pageString)
 	if err != nil {
-		log.Print(err)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"page": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Debug("invalid query", "error", err)
+		return constant.ErrInvalidFormat.WithField("page")
 	}
 	page = int(page)
 	if limit < 0 || limit == 0 {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"limit": constant.ErrInvalidFormat.Error()})
-		return
+		return constant.ErrInvalidFormat.WithField("limit")
 	}
 	if page < 0 || page == 0 {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"page": constant.ErrInvalidFormat.Error()})
-		return
+		return constant.ErrInvalidFormat.WithField("page")
 	}
 	pgn := pagination.Pagination{
 		Limit:  limit,
@@ -159,67 +157,440 @@ func (ctrl *Controller) GetByLocation(ctx *gin.Context) {
 	}
 	pgn.Paginate()
 
-	response, err := ctrl.svc.FindByLocation(accountID, pgn)
+	response, err := ctrl.svc.FindByLocation(ctx.Request().Context(), accountID, pgn)
 	if err != nil {
-		if errors.Is(err, constant.ErrAccountNotRegistered) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"account_id": constant.ErrAccountNotRegistered.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("get attendance by account id and by location:", err)
-		return
+		return errors.Wrap(err, "get attendance by account id and by location")
 	}
 
 	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
 }
 
 // Create godoc
 // @Summary Add Attendance
-// @Description Add Attendance
+// @Description Add Attendance, send as multipart/form-data with a photo file to attach a selfie
 // @Tags Attendance
+// @Accept application/json,multipart/form-data
 // @Param Authorization header string true "Bearer Token"
+// @Param X-Device-ID header string false "Registered device id"
+// @Param X-Device-Timestamp header string false "Unix timestamp the request was signed at"
+// @Param X-Device-Signature header string false "Base64 ed25519 signature of device id, timestamp, method, path and body sha256"
 // @Param Payload body http.AddAttendance true "Payload"
+// @Param photo formData file false "Selfie photo (jpeg or png, max 5 MB)"
 // @Success 201 {object} string "Created"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
 // @Failure 409 {string} string "Resource Conflict"
+// @Failure 413 {string} string "Request Entity Too Large"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/attendance [post]
-func (ctrl *Controller) Add(ctx *gin.Context) {
-	accountID, err := jwt.ExtractID(ctx.GetHeader("Authorization"))
+func (ctrl *Controller) Add(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
+	}
+
+	proof, err := deviceProof(ctx)
+	if err != nil {
+		return err
 	}
 
 	req := entity.AddAttendance{}
-	if err := rest.BindJSON(ctx, &req); err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+	if err := ctx.Bind(&req); err != nil {
+		return constant.ErrInvalidFormat
 	}
 
 	// required tapi tidak diisi akan return bad request
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
-	err = ctrl.svc.Add(accountID, req)
-	if errors.Is(err, constant.ErrAccountNotRegistered) {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"account_id": constant.ErrAccountNotRegistered.Error()})
-	} else if errors.Is(err, constant.ErrLocationNotExist) {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location": constant.ErrLocationNotExist.Error()})
-	} else if errors.Is(err, constant.ErrInvalidStatusAttendance) {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"status": constant.ErrInvalidStatusAttendance.Error()})
-	} else if err != nil {
-		log.Println("attendance name:", err.Error())
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
+	if strings.HasPrefix(ctx.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		photo, file, err := upload.FormPhoto(ctx)
+		if err == nil {
+			defer file.Close()
+			req.Photo = &photo
+		} else if !errors.Is(err, constant.ErrPhotoRequired) {
+			return err
+		}
+	}
+
+	req.Device = proof
+	err = ctrl.svc.Add(ctx.Request().Context(), rest.Actor(ctx, accountID), accountID, req)
+	if err != nil {
+		return errors.Wrap(err, "attendance name")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
+}
+
+// @Summary Add Attendance Batch
+// @Description Sync attendance events queued offline, events are deduplicated on their id so retries are safe
+// @Tags Attendance
+// @Param Authorization header string true "Bearer Token"
+// @Param X-Device-ID header string false "Registered device id"
+// @Param X-Device-Timestamp header string false "Unix timestamp the request was signed at"
+// @Param X-Device-Signature header string false "Base64 ed25519 signature of device id, timestamp, method, path and body sha256"
+// @Param Payload body http.AddAttendanceBatch true "Payload"
+// @Success 200 {object} []http.AttendanceEventResult
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 413 {string} string "Request Entity Too Large"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/attendance/batch [post]
+func (ctrl *Controller) AddBatch(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	proof, err := deviceProof(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := entity.AddAttendanceBatch{}
+	if err := ctx.Bind(&req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	req.Device = proof
+	response, err := ctrl.svc.AddBatch(ctx.Request().Context(), rest.Actor(ctx, accountID), accountID, req)
+	if err != nil {
+		return errors.Wrap(err, "add attendance batch")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Get Attendance Photo
+// @Description Get the selfie photo attached to an attendance, managers and admins only
+// @Tags Attendance
+// @Produce image/jpeg,image/png
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "attendance id"
+// @Success 200 {file} file "Photo"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/attendance/{id}/photo [get]
+func (ctrl *Controller) GetPhoto(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	attendanceID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	photo, contentType, err := ctrl.svc.TakePhoto(ctx.Request().Context(), accountID, attendanceID)
+	if err != nil {
+		return errors.Wrap(err, "get attendance photo")
+	}
+	defer photo.Close()
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=3600")
+	ctx.Stream(http.StatusOK, contentType, photo)
+	return nil
+}
+
+// @Summary Get Location Presence
+// @Description Get accounts currently checked in at a location, managers and admins only
+// @Tags Locations
+// @Produce application/json,text/csv
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "location id"
+// @Param format query string false "response format" Enums(json, csv)
+// @Success 200 {object} http.GetPresence
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/locations/{id}/presence [get]
+func (ctrl *Controller) GetPresence(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	locationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	response, err := ctrl.svc.FindPresence(ctx.Request().Context(), accountID, locationID)
+	if err != nil {
+		return errors.Wrap(err, "get location presence")
+	}
+
+	if wantsCSV(ctx) {
+		rest.ResponseCSV(ctx, fmt.Sprintf("presence-%d.csv", locationID), presenceCSVHeader, presenceCSVRows(response))
+		return nil
+	}
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Get Presence Summary
+// @Description Get accounts currently checked in, grouped per location, managers and admins only
+// @Tags Locations
+// @Produce application/json,text/csv
+// @Param Authorization header string true "Bearer Token"
+// @Param format query string false "response format" Enums(json, csv)
+// @Success 200 {object} http.GetPresenceSummary
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/locations/presence [get]
+func (ctrl *Controller) GetPresenceSummary(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	response, err := ctrl.svc.FindPresenceSummary(ctx.Request().Context(), accountID)
+	if err != nil {
+		return errors.Wrap(err, "get presence summary")
+	}
+
+	if wantsCSV(ctx) {
+		var rows [][]string
+		for i := range response.Locations {
+			rows = append(rows, presenceCSVRows(response.Locations[i])...)
+		}
+		rest.ResponseCSV(ctx, "presence.csv", presenceCSVHeader, rows)
+		return nil
+	}
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Get Stream Ticket
+// @Description Ticket to open the attendance stream with, for clients that cannot set the Authorization header.
+// @Description It is put in the url instead of the bearer token and expires after a minute.
+// @Tags Attendance
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} http.StreamTicket
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/attendance/stream/ticket [post]
+func (ctrl *Controller) StreamTicket(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	expiresAt := time.Now().Add(constant.StreamTicketTTL).UTC()
+	ticket, err := jwt.GenerateStreamTicket(aes.Encrypt(accountID), constant.StreamTicketTTL)
+	if err != nil {
+		return errors.Wrap(err, "generate stream ticket")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, entity.StreamTicket{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt,
+	})
+	return nil
+}
+
+// @Summary Stream Attendance Events
+// @Description Server-Sent Events of check-ins and check-outs as they are recorded, managers and admins only.
+// @Description Reconnect with the Last-Event-ID header to resume from the short replay buffer.
+// @Tags Attendance
+// @Produce text/event-stream
+// @Param Authorization header string false "Bearer Token"
+// @Param ticket query string false "ticket from /v1/attendance/stream/ticket for clients that cannot set headers, e.g. EventSource"
+// @Param Last-Event-ID header string false "id of the last received event"
+// @Param location_id query int false "only stream events of this location"
+// @Param team_id query int false "only stream events of accounts in this team"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/attendance/stream [get]
+func (ctrl *Controller) Stream(ctx echo.Context) error {
+	// a bearer token is never read from the url, where proxies and browser history keep it
+	var accountID int
+	var err error
+	if ticket := ctx.QueryParam("ticket"); ticket != "" && ctx.Request().Header.Get("Authorization") == "" {
+		accountID, err = jwt.ExtractStreamTicketID(ticket)
 	} else {
-		rest.ResponseMessage(ctx, http.StatusCreated)
+		accountID, err = jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	}
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := entity.StreamAttendance{}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(ctx, &req); err != nil {
+		return constant.ErrInvalidID.WithField("location_id")
+	}
+
+	var lastEventID uint64
+	if header := ctx.Request().Header.Get(constant.HeaderLastEventID); header != "" {
+		lastEventID, err = strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			return constant.ErrInvalidFormat.WithField("last_event_id")
+		}
+	}
+
+	subscription, missed, err := ctrl.svc.Subscribe(ctx.Request().Context(), accountID, lastEventID, req)
+	if err != nil {
+		return errors.Wrap(err, "subscribe attendance stream")
+	}
+	defer ctrl.svc.Unsubscribe(ctx.Request().Context(), subscription)
+
+	response := ctx.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		if err := writeEvent(response, event); err != nil {
+			return nil
+		}
+	}
+	response.Flush()
+
+	keepAlive := time.NewTicker(constant.StreamKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case event, ok := <-subscription.C:
+			// the hub drops subscribers that fall behind, the client resumes with Last-Event-ID
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(response, event); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+		response.Flush()
+	}
+}
+
+func writeEvent(response *echo.Response, event pubsub.Event) error {
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(response, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+var presenceCSVHeader = []string{
+	"location_id", "location_name", "account_id", "username", "fullname",
+	"employee_number", "job_position", "checked_in_at",
+}
+
+func presenceCSVRows(presence entity.GetPresence) (rows [][]string) {
+	for _, account := range presence.Accounts {
+		rows = append(rows, []string{
+			strconv.Itoa(presence.LocationID),
+			presence.LocationName,
+			account.ID,
+			account.Username,
+			account.FullName,
+			account.EmployeeNumber,
+			account.JobPosition,
+			account.CheckedInAt.Format(time.RFC3339),
+		})
+	}
+	return
+}
+
+func wantsCSV(ctx echo.Context) bool {
+	return ctx.QueryParam("format") == "csv" ||
+		strings.Contains(ctx.Request().Header.Get(echo.HeaderAccept), constant.ContentTypeTextCSV)
+}
+
+// deviceProof reads the device signature headers together with the raw body, the body
+// is put back so it can still be bound afterwards. Read failures are answered as an
+// invalid format
+func deviceProof(ctx echo.Context) (proof *entity.DeviceProof, err error) {
+	deviceID := ctx.Request().Header.Get(constant.HeaderDeviceID)
+	if deviceID == "" {
+		return
+	}
+
+	// the whole body is held in memory to be hashed, so it is read up to the limit only
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request().Body, constant.MaximumSignedBodySize+1))
+	if err != nil {
+		return nil, constant.ErrInvalidFormat
 	}
-}
\ No newline at end of file
+	if len(body) > constant.MaximumSignedBodySize {
+		return nil, constant.ErrSignedBodyTooLarge
+	}
+	ctx.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	proof = &entity.DeviceProof{
+		DeviceID:  deviceID,
+		Timestamp: ctx.Request().Header.Get(constant.HeaderDeviceTimestamp),
+		Signature: ctx.Request().Header.Get(constant.HeaderDeviceSignature),
+		Method:    ctx.Request().Method,
+		Path:      ctx.Request().URL.Path,
+		Body:      body,
+	}
+	return
+}
+
+// historyParams reads the Page, Limit and Filter query of the history endpoints
+func historyParams(ctx echo.Context) (pgn pagination.Pagination, filter string, err error) {
+	limitString := ctx.QueryParam("Limit")
+	limit, err := strconv.Atoi(limitString)
+	if err != nil {
+		logging.FromContext(ctx.Request().Context()).Debug("invalid query", "error", err)
+		return pgn, filter, constant.ErrInvalidFormat.WithField("limit")
+	}
+	limit = int(limit)
+	pageString := ctx.QueryParam("Page")
+	page, err := strconv.Atoi(pageString)
+	if err != nil {
+		logging.FromContext(ctx.Request().Context()).Debug("invalid query", "error", err)
+		return pgn, filter, constant.ErrInvalidFormat.WithField("page")
+	}
+	page = int(page)
+	if limit < 0 || limit == 0 {
+		return pgn, filter, constant.ErrInvalidFormat.WithField("limit")
+	}
+	if page < 0 || page == 0 {
+		return pgn, filter, constant.ErrInvalidFormat.WithField("page")
+	}
+	pgn = pagination.Pagination{
+		Limit:  limit,
+		Page:   page,
+	}
+	pgn.Paginate()
+
+	filter = ctx.QueryParam("Filter")
+	if filter != constant.FilterByDay && filter != constant.FilterByWeek && filter != constant.FilterByMonth && filter != constant.FilterByYear {
+		return pgn, filter, constant.ErrInvalidFormat.WithField("filter")
+	}
+	return pgn, filter, nil
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"net/http"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/audit"
+
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc audit.Servicer
+}
+
+func NewController(
+	servicer audit.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Audit Log
+// @Description Get who changed accounts, locations and attendances newest first, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param actor_id query string false "actor id"
+// @Param entity_type query string false "entity type" Enums(account, location, attendance)
+// @Param entity_id query string false "entity id, encrypted for accounts"
+// @Param action query string false "action"
+// @Param from query string false "from date" example(yyyy-mm-dd)
+// @Param to query string false "to date, inclusive" example(yyyy-mm-dd)
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} rest.ResponsePaginationResult{data=[]http.GetAuditLog}
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/audit [get]
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindAuditLogs)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.Find(ctx.Request().Context(), adminID, *req, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get audit log")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
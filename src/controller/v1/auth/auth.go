@@ -2,7 +2,6 @@ package auth
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 
 	echo "github.com/labstack/echo/v4"
@@ -11,8 +10,8 @@ import (
 	"github.com/forkyid/go-utils/v1/validation"
 	"github.com/pkg/errors"
 	"go-rest-api/src/constant"
-	"go-rest-api/src/pkg/bcrypt"
 	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
 	"go-rest-api/src/pkg/rest"
 	"go-rest-api/src/service/v1/account"
 )
@@ -39,51 +38,33 @@ func NewController(
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/auth [post]
-func (ctrl *Controller) Login(ctx echo.Context) {
+func (ctrl *Controller) Login(ctx echo.Context) error {
 	req := new(entity.Auth)
 	err := ctx.Bind(req)
 	if err != nil {
-		log.Println("bind json:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Info("bind json", "error", err, "request", req)
+		return constant.ErrInvalidFormat
 	}
 
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
-	exist, _ := ctrl.svc.CheckAccountByUsername(req.Username)
-	if !exist {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"accounts": constant.ErrAccountNotRegistered.Error()})
-		return
-	}
-
-	account, err:= ctrl.svc.TakeAccountByUsername(req.Username)
-	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		return
-	}
-
-	err = bcrypt.ComparePassword(account.Password, req.Password)
+	account, err := ctrl.svc.Login(ctx.Request().Context(), *req)
 	if err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"accounts": constant.ErrInvalidPassword.Error()})
-		return
+		return errors.Wrap(err, "login")
 	}
 
 	token, err := jwt.GenerateJWT(aes.Encrypt(int(account.ID)))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		return
+		return errors.Wrap(err, "generate jwt")
 	}
 
 	rest.ResponseData(ctx, http.StatusOK, entity.Token{
 		Token: fmt.Sprintf("Bearer %v", token),
 	})
+	return nil
 }
 
 // @Summary Update User Password
@@ -94,36 +75,28 @@ func (ctrl *Controller) Login(ctx echo.Context) {
 // @Success 200 {string} string "Success"
 // @Failure 400 {string} string "Bad Request"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/auth/forgot [patch]
-func (ctrl *Controller) ForgotPassword(ctx echo.Context) {
+func (ctrl *Controller) ForgotPassword(ctx echo.Context) error {
 	req := new(entity.ForgotPassword)
 	err := ctx.Bind(req)
 	if err != nil {
-		log.Println("bind json:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Info("bind json", "error", err, "request", req)
+		return constant.ErrInvalidFormat
 	}
 
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
 	request := *req
-	err = ctrl.svc.UpdatePassword(request)
+	err = ctrl.svc.UpdatePassword(ctx.Request().Context(), rest.Actor(ctx, 0), request)
 	if err != nil {
-		if errors.Is(err, constant.ErrPasswordCannotBeEmpty) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"accounts": constant.ErrPasswordCannotBeEmpty.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("update account password:", err)
-		return
+		return errors.Wrap(err, "update account password")
 	}
 
 	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,196 @@
+package device
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/device"
+
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc device.Servicer
+}
+
+func NewController(
+	servicer device.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Own Devices
+// @Description Get devices registered to the logged in account
+// @Tags Devices
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} []http.GetDevice
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/devices [get]
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	response, err := ctrl.svc.Find(ctx.Request().Context(), accountID)
+	if err != nil {
+		return errors.Wrap(err, "get devices")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// Register godoc
+// @Summary Register Device
+// @Description Bind a device to the logged in account, attendance can then be signed with the device key. An active device must be revoked before it is registered again
+// @Tags Devices
+// @Param Authorization header string true "Bearer Token"
+// @Param Payload body http.RegisterDevice true "Payload"
+// @Success 201 {object} string "Created"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Resource Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/devices [post]
+func (ctrl *Controller) Register(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.RegisterDevice)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.Register(ctx.Request().Context(), accountID, *req)
+	if err != nil {
+		return errors.Wrap(err, "register device")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
+}
+
+// @Summary Get Account Devices
+// @Description Get devices registered to an account, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "account id"
+// @Success 200 {object} []http.GetDevice
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/accounts/{id}/devices [get]
+func (ctrl *Controller) GetByAccount(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	accountID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	response, err := ctrl.svc.FindByAdmin(ctx.Request().Context(), adminID, accountID)
+	if err != nil {
+		return errors.Wrap(err, "get account devices")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Update Account Device Binding
+// @Description Restrict or allow attendance submission from unbound devices, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "account id"
+// @Param Payload body http.UpdateDeviceBinding true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/accounts/{id}/device-binding [patch]
+func (ctrl *Controller) UpdateBinding(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	accountID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.UpdateDeviceBinding)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.UpdateBinding(ctx.Request().Context(), rest.Actor(ctx, adminID), accountID, *req)
+	if err != nil {
+		return errors.Wrap(err, "update device binding")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Revoke Device
+// @Description Revoke a registered device, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "device id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/devices/{id} [delete]
+func (ctrl *Controller) Revoke(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	deviceID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.Revoke(ctx.Request().Context(), adminID, deviceID)
+	if err != nil {
+		return errors.Wrap(err, "revoke device")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"net/http"
+
+	"go-rest-api/src/pkg/health"
+	"go-rest-api/src/pkg/rest"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+type Controller struct {
+	checker *health.Checker
+}
+
+func NewController(
+	checker *health.Checker,
+) *Controller {
+	return &Controller{
+		checker: checker,
+	}
+}
+
+// @Summary Liveness
+// @Description Tell the process is running, it does not check any dependency
+// @Tags Health
+// @Produce application/json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func (ctrl *Controller) Live(ctx echo.Context) error {
+	rest.ResponseData(ctx, http.StatusOK, ctrl.checker.Live())
+	return nil
+}
+
+// @Summary Readiness
+// @Description Check the database and the other dependencies with their latency, not ready while shutting down
+// @Tags Health
+// @Produce application/json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (ctrl *Controller) Ready(ctx echo.Context) error {
+	report, ready := ctrl.checker.Ready(ctx.Request().Context())
+	if !ready {
+		rest.ResponseData(ctx, http.StatusServiceUnavailable, report)
+		return nil
+	}
+	rest.ResponseData(ctx, http.StatusOK, report)
+	return nil
+}
@@ -0,0 +1,251 @@
+package invitation
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/invitation"
+
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc invitation.Servicer
+}
+
+func NewController(
+	servicer invitation.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Invitations
+// @Description Get invitations newest first, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param status query string false "invitation status" Enums(pending, accepted, expired)
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} rest.ResponsePaginationResult{data=[]http.GetInvitation}
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/invitations [get]
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindInvitations)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.Find(ctx.Request().Context(), adminID, req.Status, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get invitations")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
+
+// @Summary Create Invitation
+// @Description Invite an email to register, the registration link is mailed to it and returned here, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param Payload body http.CreateInvitation true "Payload"
+// @Success 201 {object} http.CreatedInvitation
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/invitations [post]
+func (ctrl *Controller) Create(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.CreateInvitation)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	response, err := ctrl.svc.Create(ctx.Request().Context(), adminID, *req)
+	if err != nil {
+		return errors.Wrap(err, "create invitation")
+	}
+
+	rest.ResponseData(ctx, http.StatusCreated, response)
+	return nil
+}
+
+// @Summary Resend Invitation
+// @Description Extend an invitation that was not accepted yet and mail a new link, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "invitation id"
+// @Success 200 {object} http.CreatedInvitation
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/invitations/{id}/resend [post]
+func (ctrl *Controller) Resend(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	invitationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	response, err := ctrl.svc.Resend(ctx.Request().Context(), adminID, invitationID)
+	if err != nil {
+		return errors.Wrap(err, "resend invitation")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Revoke Invitation
+// @Description Revoke an invitation that was not accepted yet, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "invitation id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/invitations/{id} [delete]
+func (ctrl *Controller) Revoke(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	invitationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.Revoke(ctx.Request().Context(), adminID, invitationID)
+	if err != nil {
+		return errors.Wrap(err, "revoke invitation")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Verify Invitation
+// @Description Check an invitation link before showing the registration form
+// @Tags Invitations
+// @Produce application/json
+// @Param token query string true "invitation token"
+// @Success 200 {object} http.GetInvitation
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 410 {string} string "Gone"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/invitations/verify [get]
+func (ctrl *Controller) Verify(ctx echo.Context) error {
+	req := new(entity.VerifyInvitation)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("token")
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	response, err := ctrl.svc.Verify(ctx.Request().Context(), req.Token)
+	if err != nil {
+		return errors.Wrap(err, "verify invitation")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Accept Invitation
+// @Description Register the invited account, the email, role and team come from the invitation
+// @Tags Invitations
+// @Param Payload body http.AcceptInvitation true "Payload"
+// @Success 201 {string} string "Created"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 410 {string} string "Gone"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/invitations/accept [post]
+func (ctrl *Controller) Accept(ctx echo.Context) error {
+	req := new(entity.AcceptInvitation)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err := ctrl.svc.Accept(ctx.Request().Context(), rest.Actor(ctx, 0), *req)
+	if err != nil {
+		return errors.Wrap(err, "accept invitation")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
+}
@@ -1,8 +1,6 @@
 package location
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"strconv"
@@ -12,7 +10,9 @@ import (
 	"github.com/forkyid/go-utils/v1/validation"
 	"github.com/pkg/errors"
 	"go-rest-api/src/constant"
+	"go-rest-api/src/controller/v1/upload"
 	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
 	"go-rest-api/src/pkg/rest"
 	"go-rest-api/src/service/v1/location"
 )
@@ -40,40 +40,34 @@ func NewController(
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/locations [get]
-func (ctrl *Controller) Get(ctx echo.Context) {
+func (ctrl *Controller) Get(ctx echo.Context) error {
 	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
 	locationIDsStr := ctx.QueryParam("location_ids")
 	if locationIDsStr == "" {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location_id": constant.ErrInvalidID.Error()})
-		return
+		return constant.ErrInvalidID.WithField("location_ids")
 	}
 
 	locationIDsString := strings.Split(locationIDsStr, ",")
 	var locationIDs []int
 	for i := range locationIDsString {
-	    locationID, err := strconv.Atoi(locationIDsString[i])
-	    if err != nil {
-		    rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			    "location_id": fmt.Sprintf(constant.ErrInvalidID.Error() + " : " + locationIDsString[i])})
-		    return
-	    }
+		locationID, err := strconv.Atoi(locationIDsString[i])
+		if err != nil {
+			return constant.ErrInvalidID.WithField("location_ids")
+		}
 		locationIDs = append(locationIDs, locationID)
 	}
 
-	response, err := ctrl.svc.Find(locationIDs)
+	response, err := ctrl.svc.Find(ctx.Request().Context(), locationIDs)
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("get location by id:", err)
-		return
+		return errors.Wrap(err, "get location by id")
 	}
 
 	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
 }
 
 // Create godoc
@@ -84,47 +78,35 @@ func (ctrl *Controller) Get(ctx echo.Context) {
 // @Param Payload body http.CreateLocation true "Payload"
 // @Success 201 {object} string "Created"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
 // @Failure 409 {string} string "Resource Conflict"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/locations [post]
-func (ctrl *Controller) Create(ctx echo.Context) {
-	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+func (ctrl *Controller) Create(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
 	req := new(entity.CreateLocation)
 	if err := ctx.Bind(req); err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		return constant.ErrInvalidFormat
 	}
 
 	// required tapi tidak diisi akan return bad request
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
 	request := *req
-	err = ctrl.svc.Create(request)
-	if errors.Is(err, constant.ErrInvalidLocationName) {
-		rest.ResponseError(ctx, http.StatusConflict, map[string]string{
-			"location": constant.ErrInvalidLocationName.Error()})
-	} else if errors.Is(err, constant.ErrInvalidAddress) {
-		rest.ResponseError(ctx, http.StatusConflict, map[string]string{
-			"location": constant.ErrInvalidAddress.Error()})
-	} else if errors.Is(err, constant.ErrLocationAlreadyExist) {
-		rest.ResponseError(ctx, http.StatusConflict, map[string]string{
-			"location": constant.ErrLocationAlreadyExist.Error()})
-	} else if err != nil {
-		log.Println("location:", err.Error())
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-	} else {
-		rest.ResponseMessage(ctx, http.StatusCreated)
+	err = ctrl.svc.Create(ctx.Request().Context(), rest.Actor(ctx, accountID), request)
+	if err != nil {
+		return errors.Wrap(err, "create location")
 	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
 }
 
 // Update godoc
@@ -136,64 +118,84 @@ func (ctrl *Controller) Create(ctx echo.Context) {
 // @Param Payload body http.UpdateLocation true "Payload"
 // @Success 200 {string} string "Success"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Resource Conflict"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/locations [patch]
-func (ctrl *Controller) Update(ctx echo.Context) {
-	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+func (ctrl *Controller) Update(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
+		return constant.ErrUnauthorized
 	}
 
 	req := new(entity.UpdateLocation)
 	// int di isi dengan string maka akan return invalid format
 	err = ctx.Bind(req)
 	if err != nil {
-		log.Println("bind json:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"body": constant.ErrInvalidFormat.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Info("bind json", "error", err, "request", req)
+		return constant.ErrInvalidFormat
 	}
 
 	// required tapi tidak diisi akan return bad request
 	if err := validation.Validator.Struct(req); err != nil {
-		log.Println("validate struct:", err, "request:", req)
-		rest.ResponseError(ctx, http.StatusBadRequest, err)
-		return
-	}
-
-	locationIDStr := ctx.QueryParam("location_id")
-	if locationIDStr == "" {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location_id": constant.ErrInvalidID.Error()})
-		return
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
 	}
 
-	locationID, err := strconv.Atoi(locationIDStr)
+	locationID, err := strconv.Atoi(ctx.QueryParam("location_id"))
 	if err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location_id": constant.ErrInvalidID.Error()})
-		return
+		return constant.ErrInvalidID.WithField("location_id")
 	}
 
 	request := *req
-	err = ctrl.svc.Update(locationID, request)
+	err = ctrl.svc.Update(ctx.Request().Context(), rest.Actor(ctx, accountID), locationID, request)
 	if err != nil {
-		if errors.Is(err, constant.ErrLocationNotExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"locations": constant.ErrLocationNotExist.Error()})
-			return
-		} else if errors.Is(err, constant.ErrLocationNameAlreadyExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"locations": constant.ErrLocationNameAlreadyExist.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("update location: ", err.Error())
-		return
+		return errors.Wrap(err, "update location")
 	}
 
 	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Update Location Photo
+// @Description Upload the location photo, it is stored in several sizes. The returned url points to the medium size, the small and large sizes sit next to it.
+// @Tags Locations
+// @Accept multipart/form-data
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param location_id query int true "location id"
+// @Param photo formData file true "Photo (jpeg or png, max 5 MB)"
+// @Success 200 {object} http.UpdatedPhoto
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/locations/photo [put]
+func (ctrl *Controller) UpdatePhoto(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	locationID, err := strconv.Atoi(ctx.QueryParam("location_id"))
+	if err != nil {
+		return constant.ErrInvalidID.WithField("location_id")
+	}
+
+	photo, file, err := upload.FormPhoto(ctx)
+	if err != nil {
+		return errors.Wrap(err, "open location photo")
+	}
+	defer file.Close()
+
+	photoURL, err := ctrl.svc.UpdatePhoto(ctx.Request().Context(), rest.Actor(ctx, accountID), locationID, photo)
+	if err != nil {
+		return errors.Wrap(err, "update location photo")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, entity.UpdatedPhoto{PhotoURL: photoURL})
+	return nil
 }
 
 // Delete godoc
@@ -204,41 +206,27 @@ func (ctrl *Controller) Update(ctx echo.Context) {
 // @Param location_id query string true "location_id"
 // @Success 200 {string} string "Success"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
 // @Failure 409 {string} string "Resource Conflict"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /v1/locations [delete]
-func (ctrl *Controller) Delete(ctx echo.Context) {
-	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+func (ctrl *Controller) Delete(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
 	if err != nil {
-		rest.ResponseMessage(ctx, http.StatusUnauthorized)
-		return
-	}
-	
-	locationIDStr := ctx.QueryParam("location_id")
-	if locationIDStr == "" {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location_id": constant.ErrInvalidID.Error()})
-		return
+		return constant.ErrUnauthorized
 	}
 
-	locationID, err := strconv.Atoi(locationIDStr)
+	locationID, err := strconv.Atoi(ctx.QueryParam("location_id"))
 	if err != nil {
-		rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-			"location_id": constant.ErrInvalidID.Error()})
-		return
+		return constant.ErrInvalidID.WithField("location_id")
 	}
 
-	err = ctrl.svc.Delete(locationID)
+	err = ctrl.svc.Delete(ctx.Request().Context(), rest.Actor(ctx, accountID), locationID)
 	if err != nil {
-		if errors.Is(err, constant.ErrLocationNotExist) {
-			rest.ResponseError(ctx, http.StatusBadRequest, map[string]string{
-				"locations": constant.ErrLocationNotExist.Error()})
-			return
-		}
-		rest.ResponseMessage(ctx, http.StatusInternalServerError)
-		log.Println("delete location: ", err.Error())
-		return
+		return errors.Wrap(err, "delete location")
 	}
-		
+
 	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
 }
@@ -0,0 +1,354 @@
+package organization
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/organization"
+
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc organization.Servicer
+}
+
+func NewController(
+	servicer organization.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Departments
+// @Description Get every department
+// @Tags Organization
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} []http.GetDepartment
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/departments [get]
+func (ctrl *Controller) GetDepartments(ctx echo.Context) error {
+	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	response, err := ctrl.svc.FindDepartments(ctx.Request().Context())
+	if err != nil {
+		return errors.Wrap(err, "get departments")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Create Department
+// @Description Create a department, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param Payload body http.CreateDepartment true "Payload"
+// @Success 201 {string} string "Created"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/departments [post]
+func (ctrl *Controller) CreateDepartment(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.CreateDepartment)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.CreateDepartment(ctx.Request().Context(), adminID, *req)
+	if err != nil {
+		return errors.Wrap(err, "create department")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
+}
+
+// @Summary Update Department
+// @Description Rename a department or change its description, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "department id"
+// @Param Payload body http.UpdateDepartment true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/departments/{id} [patch]
+func (ctrl *Controller) UpdateDepartment(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	departmentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.UpdateDepartment)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.UpdateDepartment(ctx.Request().Context(), adminID, departmentID, *req)
+	if err != nil {
+		return errors.Wrap(err, "update department")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Delete Department
+// @Description Delete a department without teams or accounts, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "department id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/departments/{id} [delete]
+func (ctrl *Controller) DeleteDepartment(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	departmentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.DeleteDepartment(ctx.Request().Context(), adminID, departmentID)
+	if err != nil {
+		return errors.Wrap(err, "delete department")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Get Teams
+// @Description Get every team, or the teams of one department
+// @Tags Organization
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param department_id query int false "department id"
+// @Success 200 {object} []http.GetTeam
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/teams [get]
+func (ctrl *Controller) GetTeams(ctx echo.Context) error {
+	_, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindTeams)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("department_id")
+	}
+
+	response, err := ctrl.svc.FindTeams(ctx.Request().Context(), req.DepartmentID)
+	if err != nil {
+		return errors.Wrap(err, "get teams")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// @Summary Create Team
+// @Description Create a team in a department, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param Payload body http.CreateTeam true "Payload"
+// @Success 201 {string} string "Created"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/teams [post]
+func (ctrl *Controller) CreateTeam(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.CreateTeam)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.CreateTeam(ctx.Request().Context(), adminID, *req)
+	if err != nil {
+		return errors.Wrap(err, "create team")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusCreated)
+	return nil
+}
+
+// @Summary Update Team
+// @Description Rename a team or move it to another department, only teams without accounts can be moved, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "team id"
+// @Param Payload body http.UpdateTeam true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/teams/{id} [patch]
+func (ctrl *Controller) UpdateTeam(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	teamID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.UpdateTeam)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.UpdateTeam(ctx.Request().Context(), adminID, teamID, *req)
+	if err != nil {
+		return errors.Wrap(err, "update team")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Delete Team
+// @Description Delete a team without accounts, admin only
+// @Tags Organization
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "team id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/teams/{id} [delete]
+func (ctrl *Controller) DeleteTeam(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	teamID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.DeleteTeam(ctx.Request().Context(), adminID, teamID)
+	if err != nil {
+		return errors.Wrap(err, "delete team")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Update Account Reporting Line
+// @Description Place an account in a department and team and under a manager, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "account id"
+// @Param Payload body http.UpdateReportingLine true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/accounts/{id}/reporting-line [patch]
+func (ctrl *Controller) UpdateReportingLine(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	accountID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.UpdateReportingLine)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.UpdateReportingLine(ctx.Request().Context(), rest.Actor(ctx, adminID), accountID, *req)
+	if err != nil {
+		return errors.Wrap(err, "update reporting line")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/outbox"
+
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc outbox.Servicer
+}
+
+func NewController(
+	servicer outbox.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Outbox Dead Letters
+// @Description Get the domain events that ran out of relay attempts, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} []http.GetOutboxEvent
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/outbox/dead-letters [get]
+func (ctrl *Controller) GetDeadLetters(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindOutboxEvents)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.FindDeadLetters(ctx.Request().Context(), adminID, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get outbox dead letters")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
+
+// @Summary Retry Outbox Event
+// @Description Queue a dead domain event again with a fresh set of attempts, it is relayed after the later events of its aggregate, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "outbox event id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/outbox/events/{id}/retry [post]
+func (ctrl *Controller) Retry(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	eventID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.Retry(ctx.Request().Context(), adminID, eventID)
+	if err != nil {
+		return errors.Wrap(err, "retry outbox event")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
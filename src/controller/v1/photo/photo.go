@@ -0,0 +1,62 @@
+package photo
+
+import (
+	"net/http"
+
+	"go-rest-api/src/service/v1/photo"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc photo.Servicer
+}
+
+func NewController(
+	servicer photo.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Photo
+// @Description Get an uploaded account or location photo, the path comes from the photo url. A stored photo never changes, a new upload gets a new url.
+// @Tags Photos
+// @Produce image/jpeg
+// @Param path path string true "photo path, for example accounts/{id}/medium.jpg"
+// @Success 200 {file} file "Photo"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/photos/{path} [get]
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	photo, contentType, err := ctrl.svc.Take(ctx.Request().Context(), ctx.Param("*"))
+	if err != nil {
+		return errors.Wrap(err, "get photo")
+	}
+	defer photo.Close()
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+	ctx.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+	ctx.Stream(http.StatusOK, contentType, photo)
+	return nil
+}
+
+// @Summary Get Photo Placeholder
+// @Description Get the generated placeholder shown for accounts and locations without a photo, the initials of the name on a colored background
+// @Tags Photos
+// @Produce image/svg+xml
+// @Param name query string false "name to take the initials from"
+// @Success 200 {file} file "Placeholder"
+// @Router /v1/photos/placeholder.svg [get]
+func (ctrl *Controller) GetPlaceholder(ctx echo.Context) error {
+	placeholder := ctrl.svc.Placeholder(ctx.Request().Context(), ctx.QueryParam("name"))
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=86400")
+	ctx.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+	// the svg is only an image, nothing inside it may run or load
+	ctx.Response().Header().Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'")
+	ctx.Blob(http.StatusOK, "image/svg+xml", placeholder)
+	return nil
+}
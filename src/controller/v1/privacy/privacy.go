@@ -0,0 +1,221 @@
+package privacy
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/privacy"
+
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc privacy.Servicer
+}
+
+func NewController(
+	servicer privacy.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Export Account Data
+// @Description Download everything held about the account as a zip of json and csv files with the attendance photos
+// @Tags Accounts
+// @Produce application/zip
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {file} file "Zip archive"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/accounts/me/export [get]
+func (ctrl *Controller) Export(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	export, err := ctrl.svc.Export(ctx.Request().Context(), accountID)
+	if err != nil {
+		return errors.Wrap(err, "export account")
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, constant.ContentTypeApplicationZip)
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="account-export.zip"`)
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	// the status is already sent once the archive is streamed, a failure can only be logged
+	err = ctrl.svc.WriteExport(ctx.Request().Context(), export, ctx.Response())
+	if err != nil {
+		logging.FromContext(ctx.Request().Context()).Error("write account export", "error", err)
+	}
+	return nil
+}
+
+// @Summary Request Account Erasure
+// @Description Request the personal data of the account to be erased, an admin reviews the request
+// @Tags Accounts
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Success 202 {object} http.GetErasureRequest
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/accounts/me/erasure [post]
+func (ctrl *Controller) RequestErasure(ctx echo.Context) error {
+	accountID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	response, err := ctrl.svc.RequestErasure(ctx.Request().Context(), accountID)
+	if err != nil {
+		return errors.Wrap(err, "request erasure")
+	}
+
+	rest.ResponseData(ctx, http.StatusAccepted, response)
+	return nil
+}
+
+// @Summary Get Erasure Requests
+// @Description Get erasure requests oldest first, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param status query string false "erasure request status" Enums(pending, completed, rejected)
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} rest.ResponsePaginationResult{data=[]http.GetErasureRequest}
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/erasure-requests [get]
+func (ctrl *Controller) GetErasureRequests(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindErasureRequests)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.FindErasureRequests(ctx.Request().Context(), adminID, req.Status, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get erasure requests")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
+
+// @Summary Approve Erasure Request
+// @Description Erase the personal data of the account of a pending request, attendances are kept without their photos, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "erasure request id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/erasure-requests/{id}/approve [post]
+func (ctrl *Controller) ApproveErasureRequest(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	requestID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.ApproveErasure(ctx.Request().Context(), rest.Actor(ctx, adminID), requestID)
+	if err != nil {
+		return errors.Wrap(err, "approve erasure request")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Reject Erasure Request
+// @Description Reject a pending erasure request with a reason, admin only
+// @Tags Admin
+// @Accept application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "erasure request id"
+// @Param Payload body http.RejectErasureRequest true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/erasure-requests/{id}/reject [post]
+func (ctrl *Controller) RejectErasureRequest(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	requestID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.RejectErasureRequest)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.RejectErasure(ctx.Request().Context(), adminID, requestID, req.Reason)
+	if err != nil {
+		return errors.Wrap(err, "reject erasure request")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
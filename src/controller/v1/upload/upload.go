@@ -0,0 +1,59 @@
+// Package upload reads the files of multipart requests the controllers share
+package upload
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// FormPhoto opens the photo field of a multipart form, the content type is sniffed
+// instead of trusting the client supplied header. A missing photo is
+// constant.ErrPhotoRequired, the caller closes file
+func FormPhoto(ctx echo.Context) (photo entity.Photo, file multipart.File, err error) {
+	fileHeader, err := ctx.FormFile("photo")
+	if err == http.ErrMissingFile {
+		err = constant.ErrPhotoRequired
+		return
+	} else if err != nil {
+		err = constant.ErrInvalidFormat.WithField("photo")
+		return
+	}
+	if fileHeader.Size > constant.MaximumPhotoSize {
+		err = constant.ErrPhotoTooLarge
+		return
+	}
+
+	file, err = fileHeader.Open()
+	if err != nil {
+		err = constant.ErrInvalidFormat.WithField("photo")
+		return
+	}
+
+	sniff := make([]byte, 512)
+	n, _ := file.Read(sniff)
+	contentType := http.DetectContentType(sniff[:n])
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		file.Close()
+		err = constant.ErrInvalidPhotoType
+		return
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		err = errors.Wrap(err, "rewind photo")
+		return
+	}
+
+	photo = entity.Photo{
+		Body:        file,
+		Size:        fileHeader.Size,
+		ContentType: contentType,
+	}
+	return
+}
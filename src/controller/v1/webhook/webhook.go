@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/service/v1/webhook"
+
+	restPagination "github.com/forkyid/go-utils/v1/pagination"
+	"github.com/forkyid/go-utils/v1/validation"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type Controller struct {
+	svc webhook.Servicer
+}
+
+func NewController(
+	servicer webhook.Servicer,
+) *Controller {
+	return &Controller{
+		svc: servicer,
+	}
+}
+
+// @Summary Get Webhooks
+// @Description Get every webhook subscription, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} []http.GetWebhook
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks [get]
+func (ctrl *Controller) Get(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	response, err := ctrl.svc.Find(ctx.Request().Context(), adminID)
+	if err != nil {
+		return errors.Wrap(err, "get webhooks")
+	}
+
+	rest.ResponseData(ctx, http.StatusOK, response)
+	return nil
+}
+
+// Create godoc
+// @Summary Create Webhook
+// @Description Subscribe an URL to domain events, the signing secret is generated when left empty and only returned here
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param Payload body http.CreateWebhook true "Payload"
+// @Success 201 {object} http.CreatedWebhook
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks [post]
+func (ctrl *Controller) Create(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.CreateWebhook)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	response, err := ctrl.svc.Create(ctx.Request().Context(), adminID, *req)
+	if err != nil {
+		return errors.Wrap(err, "create webhook")
+	}
+
+	rest.ResponseData(ctx, http.StatusCreated, response)
+	return nil
+}
+
+// Update godoc
+// @Summary Update Webhook
+// @Description Change the URL, event types or active state of a webhook, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "webhook id"
+// @Param Payload body http.UpdateWebhook true "Payload"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks/{id} [patch]
+func (ctrl *Controller) Update(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	subscriptionID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	req := new(entity.UpdateWebhook)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat
+	}
+
+	if err := validation.Validator.Struct(req); err != nil {
+		logging.FromContext(ctx.Request().Context()).Info("validate struct", "error", err, "request", req)
+		return err
+	}
+
+	err = ctrl.svc.Update(ctx.Request().Context(), adminID, subscriptionID, *req)
+	if err != nil {
+		return errors.Wrap(err, "update webhook")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Delete Webhook
+// @Description Delete a webhook subscription, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "webhook id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks/{id} [delete]
+func (ctrl *Controller) Delete(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	subscriptionID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.Delete(ctx.Request().Context(), adminID, subscriptionID)
+	if err != nil {
+		return errors.Wrap(err, "delete webhook")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+// @Summary Get Webhook Deliveries
+// @Description Get the delivery log of a webhook subscription, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "webhook id"
+// @Param status query string false "pending, delivered or dead"
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} []http.GetWebhookDelivery
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks/{id}/deliveries [get]
+func (ctrl *Controller) GetDeliveries(ctx echo.Context) error {
+	subscriptionID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	return ctrl.findDeliveries(ctx, subscriptionID, "")
+}
+
+// @Summary Get Webhook Dead Letters
+// @Description Get deliveries of every webhook that ran out of attempts, admin only
+// @Tags Admin
+// @Produce application/json
+// @Param Authorization header string true "Bearer Token"
+// @Param page query int false "page"
+// @Param limit query int false "limit"
+// @Success 200 {object} []http.GetWebhookDelivery
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks/dead-letters [get]
+func (ctrl *Controller) GetDeadLetters(ctx echo.Context) error {
+	return ctrl.findDeliveries(ctx, 0, constant.DeliveryStatusDead)
+}
+
+// @Summary Retry Webhook Delivery
+// @Description Queue a dead delivery again with a fresh set of attempts, admin only
+// @Tags Admin
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "delivery id"
+// @Success 200 {string} string "Success"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/admin/webhooks/deliveries/{id}/retry [post]
+func (ctrl *Controller) Retry(ctx echo.Context) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	deliveryID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return constant.ErrInvalidID
+	}
+
+	err = ctrl.svc.Retry(ctx.Request().Context(), adminID, deliveryID)
+	if err != nil {
+		return errors.Wrap(err, "retry webhook delivery")
+	}
+
+	rest.ResponseMessage(ctx, http.StatusOK)
+	return nil
+}
+
+func (ctrl *Controller) findDeliveries(ctx echo.Context, subscriptionID int, status string) error {
+	adminID, err := jwt.ExtractID(ctx.Request().Header.Get("Authorization"))
+	if err != nil {
+		return constant.ErrUnauthorized
+	}
+
+	req := new(entity.FindWebhookDeliveries)
+	if err := ctx.Bind(req); err != nil {
+		return constant.ErrInvalidFormat.WithField("query")
+	}
+	if status == "" {
+		status = req.Status
+	}
+
+	pgn := pagination.Pagination{
+		Limit: req.Limit,
+		Page:  req.Page,
+	}
+	pgn.Paginate()
+
+	response, total, err := ctrl.svc.FindDeliveries(ctx.Request().Context(), adminID, subscriptionID, status, pgn)
+	if err != nil {
+		return errors.Wrap(err, "get webhook deliveries")
+	}
+
+	rest.ResponsePagination(ctx, http.StatusOK, rest.ResponsePaginationParams{
+		Data:      response,
+		TotalData: total,
+		Pagination: &restPagination.Pagination{
+			Limit: pgn.Limit,
+			Page:  pgn.Page,
+		},
+	})
+	return nil
+}
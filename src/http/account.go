@@ -1,20 +1,81 @@
 package http
 
+import "time"
+
 type GetUser struct {
-	ID             string `json:"id"`
-	Username       string `json:"username"`
-	FullName       string `json:"fullname"`
-	Email          string `json:"email"`
-	EmployeeNumber string `json:"employee_number"`
-	Address        string `json:"address"`
-	JobPosition    string `json:"job_position"`
-	PhotoURL       string `json:"photo_url"`
+	ID                 string `json:"id"`
+	Username           string `json:"username"`
+	FullName           string `json:"fullname"`
+	Email              string `json:"email"`
+	EmployeeNumber     string `json:"employee_number"`
+	Address            string `json:"address"`
+	JobPosition        string `json:"job_position"`
+	PhotoURL           string `json:"photo_url"`
+	Role               string `json:"role"`
+	RequireBoundDevice bool   `json:"require_bound_device"`
+	DepartmentID       *int   `json:"department_id"`
+	TeamID             *int   `json:"team_id"`
+	ManagerID          string `json:"manager_id" copier:"-"`
+}
+
+// GetAccount is an account as listed in the admin directory
+type GetAccount struct {
+	GetUser
+	IsVerified bool       `json:"is_verified"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+type FindAccounts struct {
+	Search       string `query:"search"`
+	DepartmentID int    `query:"department_id"`
+	IsVerified   *bool  `query:"is_verified"`
+	JobPosition  string `query:"job_position"`
+	Deleted      string `query:"deleted" validate:"omitempty,oneof=exclude include only"`
+	Sort         string `query:"sort" validate:"omitempty,oneof=full_name -full_name username -username employee_number -employee_number created_at -created_at"`
+	Page         int    `query:"page"`
+	Limit        int    `query:"limit"`
+}
+
+type ImportAccounts struct {
+	DryRun bool `query:"dry_run"`
+}
+
+// ImportAccountRow is a row of an account import file, columns are matched by the
+// header row
+type ImportAccountRow struct {
+	Username       string
+	FullName       string
+	Email          string
+	PhoneNumber    string
+	KTPNumber      string
+	EmployeeNumber string
+	JobPosition    string
+	Address        string
+	Gender         string
+	DateOfBirth    string
+	Role           string
+}
+
+type ImportAccountsResult struct {
+	DryRun   bool                     `json:"dry_run"`
+	Total    int                      `json:"total"`
+	Valid    int                      `json:"valid"`
+	Imported int                      `json:"imported"`
+	Notified int                      `json:"notified"`
+	Rows     []ImportAccountRowResult `json:"rows"`
+}
+
+type ImportAccountRowResult struct {
+	Row      int               `json:"row"`
+	Username string            `json:"username"`
+	Errors   map[string]string `json:"errors,omitempty"`
 }
 
 type RegisterUser struct {
-	Username  string `json:"username" validate:"required"`
-	FullName  string `json:"fullname" validate:"required"`
-	Password  string `json:"password" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	FullName string `json:"fullname" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 type UpdateUser struct {
@@ -25,8 +86,8 @@ type UpdateUser struct {
 	Address        *string `json:"address"`
 	EmployeeNumber *string `json:"employee_number"`
 	JobPosition    *string `json:"job_position"`
-	KTPNumber      *int    `json:"ktp_number"`
-	PhoneNumber    *string `json:"phone_number"`
+	KTPNumber      *string `json:"ktp_number" validate:"omitempty,nik,nik_birth_date=DOBString,nik_gender=Gender" example:"3171014508900001"`
+	PhoneNumber    *string `json:"phone_number" validate:"omitempty,phone" example:"081234567890"`
 	Gender         *string `json:"gender"`
 	DOBString      *string `json:"date_of_birth" example:"yyyy-mm-dd"`
 }
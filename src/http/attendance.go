@@ -1,11 +1,15 @@
 package http
 
+import "time"
+
 type GetAttendance struct {
+	ID           int     `json:"id"`
 	LocationID   int     `json:"location_id"`
 	LocationName string  `json:"location_name"`
 	Status       string  `json:"status"`
 	Time         string  `json:"time"`
 	Description  string  `json:"description"`
+	HasPhoto     bool    `json:"has_photo"`
 }
 
 type GetAttendanceByLocation struct {
@@ -16,6 +20,69 @@ type GetAttendanceByLocation struct {
 }
 
 type AddAttendance struct {
-	LocationID int     `json:"location_id" validate:"required"`
-	Status     string  `json:"status" validate:"required"`
+	LocationID int              `json:"location_id" form:"location_id" validate:"required"`
+	Status     string           `json:"status" form:"status" validate:"required"`
+	Photo      *Photo `json:"-" form:"-"`
+	Device     *DeviceProof     `json:"-" form:"-"`
+}
+
+type AddAttendanceBatch struct {
+	Events []AttendanceEvent `json:"events" validate:"required,min=1,max=100"`
+	Device *DeviceProof      `json:"-"`
+}
+
+type AttendanceEvent struct {
+	ID         string    `json:"id" validate:"required,uuid" example:"6f1c2a4e-3b1d-4c8e-9a57-0d2b7f4e1a90"`
+	LocationID int       `json:"location_id" validate:"required"`
+	Status     string    `json:"status" validate:"required"`
+	ClientTime time.Time `json:"client_time" validate:"required" example:"2006-01-02T15:04:05+07:00"`
+}
+
+type AttendanceEventResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result" example:"accepted"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type GetPresenceSummary struct {
+	Total     int           `json:"total"`
+	Locations []GetPresence `json:"locations"`
+}
+
+type GetPresence struct {
+	LocationID   int               `json:"location_id"`
+	LocationName string            `json:"location_name"`
+	Total        int               `json:"total"`
+	Accounts     []PresenceAccount `json:"accounts"`
+}
+
+type PresenceAccount struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	FullName       string    `json:"fullname"`
+	EmployeeNumber string    `json:"employee_number"`
+	JobPosition    string    `json:"job_position"`
+	PhotoURL       string    `json:"photo_url"`
+	CheckedInAt    time.Time `json:"checked_in_at"`
+}
+
+type StreamAttendance struct {
+	LocationID int `query:"location_id"`
+	TeamID     int `query:"team_id"`
+}
+
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type AttendanceStreamEvent struct {
+	ID           int       `json:"id"`
+	AccountID    string    `json:"account_id"`
+	FullName     string    `json:"fullname"`
+	LocationID   int       `json:"location_id"`
+	LocationName string    `json:"location_name"`
+	TeamID       *int      `json:"team_id"`
+	Status       string    `json:"status"`
+	Time         time.Time `json:"time"`
 }
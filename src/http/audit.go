@@ -0,0 +1,31 @@
+package http
+
+import "time"
+
+type GetAuditLog struct {
+	ID         int64                  `json:"id"`
+	ActorID    string                 `json:"actor_id"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Changes    map[string]AuditChange `json:"changes"`
+	RequestID  string                 `json:"request_id"`
+	ClientIP   string                 `json:"client_ip"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type FindAuditLogs struct {
+	ActorID    string `query:"actor_id"`
+	EntityType string `query:"entity_type" validate:"omitempty,oneof=account location attendance"`
+	EntityID   string `query:"entity_id"`
+	Action     string `query:"action"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02" example:"yyyy-mm-dd"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02" example:"yyyy-mm-dd"`
+	Page       int    `query:"page"`
+	Limit      int    `query:"limit"`
+}
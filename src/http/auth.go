@@ -10,6 +10,6 @@ type Token struct {
 }
 
 type ForgotPassword struct {
-	KTPNumber int    `json:"ktp_number" validate:"required"`
+	KTPNumber string `json:"ktp_number" validate:"required,nik"`
 	Password  string `json:"new_password" validate:"required"`
 }
@@ -0,0 +1,33 @@
+package http
+
+import "time"
+
+type GetDevice struct {
+	ID         int        `json:"id"`
+	DeviceID   string     `json:"device_id"`
+	Platform   string     `json:"platform"`
+	PublicKey  string     `json:"public_key"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type RegisterDevice struct {
+	DeviceID  string `json:"device_id" validate:"required,max=100"`
+	Platform  string `json:"platform" validate:"required,oneof=android ios web"`
+	PublicKey string `json:"public_key" validate:"required" example:"base64 encoded ed25519 public key"`
+}
+
+type UpdateDeviceBinding struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// DeviceProof is read from the X-Device-* headers of a request signed by a registered device
+type DeviceProof struct {
+	DeviceID  string
+	Timestamp string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
@@ -0,0 +1,46 @@
+package http
+
+import "time"
+
+type GetInvitation struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	DepartmentID *int       `json:"department_id"`
+	TeamID       *int       `json:"team_id"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CreatedInvitation carries the link mailed to the invitee, it is only returned when
+// the invitation is created or resent
+type CreatedInvitation struct {
+	GetInvitation
+	Link string `json:"link"`
+}
+
+type CreateInvitation struct {
+	Email          string `json:"email" validate:"required,email,max=150"`
+	Role           string `json:"role" validate:"omitempty,oneof=employee manager admin"`
+	TeamID         *int   `json:"team_id" validate:"omitempty,min=1"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1,max=720"`
+}
+
+type FindInvitations struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending accepted expired"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+type VerifyInvitation struct {
+	Token string `query:"token" validate:"required"`
+}
+
+type AcceptInvitation struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	FullName string `json:"fullname" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
@@ -5,15 +5,18 @@ type GetLocation struct {
 	LocationName string `json:"location_name"`
 	Address      string `json:"address"`
 	PhotoURL     string `json:"photo_url"`
+	RequirePhoto bool   `json:"require_photo"`
 }
 
 type CreateLocation struct {
     LocationName string `json:"location_name" validate:"required"`
 	Address      string `json:"address" validate:"required"`
 	PhotoURL     string `json:"photo_url"`
+	RequirePhoto bool   `json:"require_photo"`
 }
 
 type UpdateLocation struct {
     LocationName string `json:"location_name"`
 	Address      string `json:"address"`
+	RequirePhoto *bool  `json:"require_photo"`
 }
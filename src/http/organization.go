@@ -0,0 +1,50 @@
+package http
+
+type GetDepartment struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreateDepartment struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type UpdateDepartment struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type GetTeam struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	DepartmentID   int    `json:"department_id"`
+	DepartmentName string `json:"department_name"`
+}
+
+type CreateTeam struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	DepartmentID int    `json:"department_id" validate:"required"`
+}
+
+type UpdateTeam struct {
+	Name         *string `json:"name" validate:"omitempty,min=1,max=100"`
+	DepartmentID *int    `json:"department_id" validate:"omitempty,min=1"`
+}
+
+type FindTeams struct {
+	DepartmentID int `query:"department_id"`
+}
+
+// UpdateReportingLine changes only the fields that are sent, 0 or an empty manager id
+// removes the account from its department, team or manager
+type UpdateReportingLine struct {
+	DepartmentID *int    `json:"department_id" validate:"omitempty,min=0"`
+	TeamID       *int    `json:"team_id" validate:"omitempty,min=0"`
+	ManagerID    *string `json:"manager_id" example:"encrypted account id"`
+}
+
+type FindReports struct {
+	Direct bool `query:"direct"`
+}
@@ -0,0 +1,21 @@
+package http
+
+import "time"
+
+type GetOutboxEvent struct {
+	ID            int64     `json:"id"`
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   int       `json:"aggregate_id"`
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type FindOutboxEvents struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
@@ -0,0 +1,14 @@
+package http
+
+import "io"
+
+// Photo is an uploaded image, ContentType is sniffed from the content
+type Photo struct {
+	Body        io.Reader
+	Size        int64
+	ContentType string
+}
+
+type UpdatedPhoto struct {
+	PhotoURL string `json:"photo_url"`
+}
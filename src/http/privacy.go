@@ -0,0 +1,52 @@
+package http
+
+import "time"
+
+// AccountExport is everything held about an account, it is written as files of a zip
+type AccountExport struct {
+	Profile         ExportProfile       `json:"profile"`
+	Attendances     []ExportAttendance  `json:"attendances"`
+	Devices         []GetDevice         `json:"devices"`
+	ErasureRequests []GetErasureRequest `json:"erasure_requests"`
+	AuditEntries    []GetAuditLog       `json:"audit_entries"`
+}
+
+type ExportProfile struct {
+	GetUser
+	PhoneNumber string    `json:"phone_number"`
+	KTPNumber   string    `json:"ktp_number"`
+	Gender      string    `json:"gender"`
+	DateOfBirth string    `json:"date_of_birth"`
+	IsVerified  bool      `json:"is_verified"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportAttendance struct {
+	ID           int       `json:"id"`
+	LocationID   int       `json:"location_id"`
+	LocationName string    `json:"location_name"`
+	Status       string    `json:"status"`
+	DeviceID     *int      `json:"device_id"`
+	Photo        string    `json:"photo,omitempty"`
+	PhotoKey     string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type GetErasureRequest struct {
+	ID          int        `json:"id"`
+	AccountID   string     `json:"account_id"`
+	Status      string     `json:"status"`
+	Reason      *string    `json:"reason"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type FindErasureRequests struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending completed rejected"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+type RejectErasureRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
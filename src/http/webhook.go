@@ -0,0 +1,49 @@
+package http
+
+import "time"
+
+type GetWebhook struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateWebhook struct {
+	URL        string   `json:"url" validate:"required,url"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=100"`
+	EventTypes []string `json:"event_types" validate:"required,min=1" example:"attendance.check-in,account.created"`
+}
+
+// CreatedWebhook is only returned once, it is the single place the signing secret is shown
+type CreatedWebhook struct {
+	ID     int    `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type UpdateWebhook struct {
+	URL        *string  `json:"url" validate:"omitempty,url"`
+	EventTypes []string `json:"event_types"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+type GetWebhookDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error"`
+	ResponseStatus int        `json:"response_status"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type FindWebhookDeliveries struct {
+	Status string `query:"status"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
@@ -1,9 +1,22 @@
 package main
 
 import (
+	"os"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/pkg/logging"
 	"go-rest-api/src/routes"
 )
 
 func main() {
-	routes.Run()
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Default().Error("load config", "error", err)
+		os.Exit(1)
+	}
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logging.SetDefault(logging.New(os.Stderr, level).With("service", cfg.ServiceName))
+	logging.Default().Info("config loaded", "config", cfg.Redacted())
+
+	routes.Run(cfg)
 }
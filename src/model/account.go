@@ -1,27 +1,56 @@
 package model
 
 import (
+	"go-rest-api/src/pkg/envelope"
 	"gorm.io/gorm"
-	"time"
 )
 
 type Account struct {
 	gorm.Model
-	Username          string    `gorm:"column:username;type:varchar(50)"`
-	FullName          string    `gorm:"column:full_name;type:varchar(150)"`
-	Email             *string   `gorm:"column:email;type:varchar(150)"`
-	Password          string    `gorm:"column:password;type:varchar(64)"`
-	Address           *string   `gorm:"column:address;type:varchar(50)"`
-	EmployeeNumber    *string   `gorm:"column:employee_number;type:varchar(50)"`
-	JobPosition       *string   `gorm:"column:job_position;type:varchar(50)"`
-	KTPNumber         *string   `gorm:"column:ktp_number;type:varchar(50)"`
-	PhoneNumber       *string   `gorm:"column:phone_number;type:varchar(20)"`
-	PhotoURL          string    `gorm:"column:photo_url;type:varchar(200)"`
-	Gender            string    `gorm:"column:gender"`
-	DateOfBirth       time.Time `gorm:"column:date_of_birth;type:date"`
-	IsVerified        bool      `gorm:"column:is_verified;type:bool"`
+	Username           string           `gorm:"column:username;type:varchar(50)"`
+	FullName           string           `gorm:"column:full_name;type:varchar(150)"`
+	Email              *string          `gorm:"column:email;type:varchar(150)"`
+	Password           string           `gorm:"column:password;type:varchar(64)"`
+	Address            *envelope.String `gorm:"column:address;type:text"`
+	EmployeeNumber     *string          `gorm:"column:employee_number;type:varchar(50)"`
+	JobPosition        *string          `gorm:"column:job_position;type:varchar(50)"`
+	KTPNumber          *envelope.String `gorm:"column:ktp_number;type:text"`
+	KTPNumberIndex     *string          `gorm:"column:ktp_number_index;type:varchar(64)"`
+	PhoneNumber        *envelope.String `gorm:"column:phone_number;type:text"`
+	PhoneNumberIndex   *string          `gorm:"column:phone_number_index;type:varchar(64)"`
+	PhotoURL           string           `gorm:"column:photo_url;type:varchar(500)"`
+	Gender             string           `gorm:"column:gender"`
+	DateOfBirth        *envelope.String `gorm:"column:date_of_birth;type:text"`
+	IsVerified         bool             `gorm:"column:is_verified;type:bool"`
+	Role               string           `gorm:"column:role;type:varchar(20)"`
+	RequireBoundDevice bool             `gorm:"column:require_bound_device;type:bool"`
+	DepartmentID       *int             `gorm:"column:department_id"`
+	TeamID             *int             `gorm:"column:team_id"`
+	ManagerID          *int             `gorm:"column:manager_id"`
 }
 
 func (Account) TableName() string {
 	return "accounts"
 }
+
+// AccountCiphertext is the stored form of the encrypted columns of an account, it is
+// read without decrypting so the values can be re-encrypted when the key is rotated
+type AccountCiphertext struct {
+	ID               int
+	Address          *string
+	KTPNumber        *string
+	KTPNumberIndex   *string
+	PhoneNumber      *string
+	PhoneNumberIndex *string
+	DateOfBirth      *string
+}
+
+// AccountFilter narrows the admin account directory, zero values do not filter
+type AccountFilter struct {
+	Search       string
+	DepartmentID int
+	IsVerified   *bool
+	JobPosition  string
+	Deleted      string
+	Sort         string
+}
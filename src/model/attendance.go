@@ -7,9 +7,13 @@ import (
 )
 
 type Attendance struct {
+	ID         int            `gorm:"column:id;primaryKey"`
 	AccountID  int            `gorm:"column:account_id"`
 	LocationID int            `gorm:"column:location_id"`
 	Status     string         `gorm:"column:status"`
+	PhotoKey   *string        `gorm:"column:photo_key;type:varchar(200)"`
+	EventID    *string        `gorm:"column:event_id;type:uuid"`
+	DeviceID   *int           `gorm:"column:device_id"`
 	CreatedAt  time.Time      `gorm:"column:created_at"`
     UpdatedAt  time.Time      `gorm:"column:updated_at"`
     DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at"`
@@ -18,3 +22,16 @@ type Attendance struct {
 func (Attendance) TableName() string {
 	return "attendances"
 }
+
+// Presence is an open check-in session, derived from the latest attendance of an account
+type Presence struct {
+	LocationID     int       `gorm:"column:location_id"`
+	LocationName   string    `gorm:"column:location_name"`
+	AccountID      int       `gorm:"column:account_id"`
+	Username       string    `gorm:"column:username"`
+	FullName       string    `gorm:"column:full_name"`
+	EmployeeNumber *string   `gorm:"column:employee_number"`
+	JobPosition    *string   `gorm:"column:job_position"`
+	PhotoURL       string    `gorm:"column:photo_url"`
+	CheckedInAt    time.Time `gorm:"column:checked_in_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// AuditLog is a change made to an account, location or attendance, written in the
+// same transaction as the change
+type AuditLog struct {
+	ID         int64     `gorm:"column:id;primaryKey"`
+	ActorID    *int      `gorm:"column:actor_id"`
+	Action     string    `gorm:"column:action;type:varchar(50)"`
+	EntityType string    `gorm:"column:entity_type;type:varchar(50)"`
+	EntityID   int       `gorm:"column:entity_id"`
+	Changes    string    `gorm:"column:changes;type:jsonb"`
+	RequestID  *string   `gorm:"column:request_id;type:varchar(100)"`
+	ClientIP   *string   `gorm:"column:client_ip;type:varchar(45)"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// Actor is who makes a change and where the request came from, an anonymous request
+// has no account id
+type Actor struct {
+	AccountID int
+	RequestID string
+	ClientIP  string
+}
+
+// AuditLogFilter narrows the audit log, zero values do not filter
+type AuditLogFilter struct {
+	ActorID    int
+	EntityType string
+	EntityID   int
+	Action     string
+	From       time.Time
+	To         time.Time
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Device struct {
+	gorm.Model
+	AccountID  int        `gorm:"column:account_id"`
+	DeviceID   string     `gorm:"column:device_id;type:varchar(100)"`
+	Platform   string     `gorm:"column:platform;type:varchar(20)"`
+	PublicKey  string     `gorm:"column:public_key;type:varchar(100)"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (Device) TableName() string {
+	return "devices"
+}
+
+// DeviceSignature is a signature a device has already been authorized with, it is
+// kept until its timestamp falls out of the clock skew tolerance so it cannot be
+// replayed
+type DeviceSignature struct {
+	DeviceID  int       `gorm:"column:device_id;primaryKey"`
+	Signature string    `gorm:"column:signature;type:varchar(100);primaryKey"`
+	SignedAt  time.Time `gorm:"column:signed_at"`
+}
+
+func (DeviceSignature) TableName() string {
+	return "device_signatures"
+}
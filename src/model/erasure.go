@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErasureRequest is an account asking for its personal data to be erased, an admin
+// approves or rejects it
+type ErasureRequest struct {
+	gorm.Model
+	AccountID   int        `gorm:"column:account_id"`
+	Status      string     `gorm:"column:status;type:varchar(20)"`
+	Reason      *string    `gorm:"column:reason;type:varchar(500)"`
+	ProcessedBy *int       `gorm:"column:processed_by"`
+	ProcessedAt *time.Time `gorm:"column:processed_at"`
+}
+
+func (ErasureRequest) TableName() string {
+	return "erasure_requests"
+}
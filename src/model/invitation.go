@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Invitation struct {
+	gorm.Model
+	Email        string     `gorm:"column:email;type:varchar(150)"`
+	Role         string     `gorm:"column:role;type:varchar(20)"`
+	DepartmentID *int       `gorm:"column:department_id"`
+	TeamID       *int       `gorm:"column:team_id"`
+	InvitedBy    int        `gorm:"column:invited_by"`
+	ExpiresAt    time.Time  `gorm:"column:expires_at"`
+	AcceptedAt   *time.Time `gorm:"column:accepted_at"`
+	AccountID    *int       `gorm:"column:account_id"`
+	// Version is bumped on every resend, only a token of the current version is accepted
+	Version int `gorm:"column:version"`
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
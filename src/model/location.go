@@ -9,6 +9,7 @@ type Location struct {
 	LocationName string `gorm:"column:name;type:varchar(60)"`
 	Address      string `gorm:"column:address;type:varchar(500)"`
 	PhotoURL     string `gorm:"column:photo_url;type:varchar(500)"`
+	RequirePhoto bool   `gorm:"column:require_photo;type:bool"`
 }
 
 func (Location) TableName() string {
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type Department struct {
+	gorm.Model
+	Name        string  `gorm:"column:name;type:varchar(100)"`
+	Description *string `gorm:"column:description;type:varchar(500)"`
+}
+
+func (Department) TableName() string {
+	return "departments"
+}
+
+type Team struct {
+	gorm.Model
+	Name         string `gorm:"column:name;type:varchar(100)"`
+	DepartmentID int    `gorm:"column:department_id"`
+}
+
+func (Team) TableName() string {
+	return "teams"
+}
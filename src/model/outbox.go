@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// OutboxEvent is a domain event written in the same transaction as the change it
+// describes, the relay publishes it afterwards. A relay claims the events it delivers
+// until claimed until, the other relays leave them and the later events of their
+// aggregates alone meanwhile. An event that ran out of attempts is dead and no longer
+// holds back its aggregate
+type OutboxEvent struct {
+	ID            int64      `gorm:"column:id;primaryKey"`
+	AggregateType string     `gorm:"column:aggregate_type;type:varchar(50)"`
+	AggregateID   int        `gorm:"column:aggregate_id"`
+	EventID       string     `gorm:"column:event_id;type:uuid"`
+	EventType     string     `gorm:"column:event_type;type:varchar(50)"`
+	Payload       string     `gorm:"column:payload;type:jsonb"`
+	Status        string     `gorm:"column:status;type:varchar(20)"`
+	Attempts      int        `gorm:"column:attempts"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at"`
+	LastError     *string    `gorm:"column:last_error;type:varchar(500)"`
+	PublishedAt   *time.Time `gorm:"column:published_at"`
+	ClaimedUntil  *time.Time `gorm:"column:claimed_until"`
+	CreatedAt     time.Time  `gorm:"column:created_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
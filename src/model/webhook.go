@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookSubscription struct {
+	gorm.Model
+	URL        string `gorm:"column:url;type:varchar(500)"`
+	Secret     string `gorm:"column:secret;type:varchar(100)"`
+	EventTypes string `gorm:"column:event_types;type:varchar(500)"`
+	IsActive   bool   `gorm:"column:is_active;type:bool"`
+	CreatedBy  int    `gorm:"column:created_by"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Events splits the comma separated event types
+func (subscription WebhookSubscription) Events() []string {
+	if subscription.EventTypes == "" {
+		return []string{}
+	}
+	return strings.Split(subscription.EventTypes, ",")
+}
+
+type WebhookDelivery struct {
+	ID             int        `gorm:"column:id;primaryKey"`
+	SubscriptionID int        `gorm:"column:subscription_id"`
+	EventID        string     `gorm:"column:event_id;type:uuid"`
+	EventType      string     `gorm:"column:event_type;type:varchar(50)"`
+	Payload        string     `gorm:"column:payload;type:jsonb"`
+	Status         string     `gorm:"column:status;type:varchar(20)"`
+	Attempts       int        `gorm:"column:attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at"`
+	LastError      *string    `gorm:"column:last_error;type:varchar(500)"`
+	ResponseStatus *int       `gorm:"column:response_status"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,57 @@
+// Package apperror defines the errors a request can fail with. An Error knows how it
+// is answered: the HTTP status, a code clients can switch on, the request field it
+// is about and a message for people.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Error struct {
+	Code    string
+	Status  int
+	Field   string
+	Message string
+}
+
+func New(code string, status int, field string, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Field:   field,
+		Message: message,
+	}
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// Is matches errors with the same code, so a copy from WithField is still the
+// error it was made from
+func (err *Error) Is(target error) bool {
+	targetErr, ok := target.(*Error)
+	return ok && targetErr.Code == err.Code
+}
+
+// WithField returns a copy about another request field, for errors like an invalid
+// id that can be about any of them
+func (err *Error) WithField(field string) *Error {
+	copied := *err
+	copied.Field = field
+	return &copied
+}
+
+// Internal is the error anything that is not an *Error is answered with, the cause
+// is logged but never shown to the client
+var Internal = New("internal", http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+
+// From returns the *Error in the chain of err, or Internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal
+}
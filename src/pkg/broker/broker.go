@@ -0,0 +1,40 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+
+	"go-rest-api/src/pkg/event"
+)
+
+// Publisher is implemented by message broker clients
+type Publisher interface {
+	Publish(subject string, data []byte) (err error)
+}
+
+// Sink publishes domain events to a broker, the subject is the prefix followed by the
+// event type, e.g. attendance.events.account.created
+type Sink struct {
+	publisher Publisher
+	prefix    string
+}
+
+func NewSink(publisher Publisher, prefix string) *Sink {
+	return &Sink{
+		publisher: publisher,
+		prefix:    prefix,
+	}
+}
+
+func (sink *Sink) Deliver(ctx context.Context, domainEvent event.Event) (err error) {
+	data, err := json.Marshal(domainEvent)
+	if err != nil {
+		return
+	}
+
+	subject := domainEvent.Type
+	if sink.prefix != "" {
+		subject = sink.prefix + "." + subject
+	}
+	return sink.publisher.Publish(subject, data)
+}
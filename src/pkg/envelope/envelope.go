@@ -0,0 +1,254 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go-rest-api/src/config"
+)
+
+// prefix marks an encrypted value, a stored value has the form
+// enc:<key version>:<wrapped data key>:<sealed value>
+const prefix = "enc:"
+
+const keySize = 32
+
+var (
+	ErrKeyringNotLoaded   = errors.New("field encryption keys are not loaded")
+	ErrMalformedValue     = errors.New("malformed encrypted value")
+	ErrUnknownKeyVersion  = errors.New("unknown field encryption key version")
+	ErrInvalidKeyringKeys = errors.New("invalid field encryption keys")
+)
+
+// Keyring encrypts column values with envelope encryption, every value is sealed with
+// its own random data key which is wrapped with a versioned key encryption key. New
+// values use the active version, older versions are kept to read existing values until
+// they are rewrapped.
+type Keyring struct {
+	keys     map[int][]byte
+	active   int
+	indexKey []byte
+}
+
+// NewKeyring checks the keys, every key encryption key and the blind index key must be
+// 32 bytes
+func NewKeyring(keys map[int][]byte, active int, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active version %d has no key", ErrInvalidKeyringKeys, active)
+	}
+	for version, key := range keys {
+		if version < 1 || len(key) != keySize {
+			return nil, fmt.Errorf("%w: key version %d must be positive and %d bytes", ErrInvalidKeyringKeys, version, keySize)
+		}
+	}
+	if len(indexKey) != keySize {
+		return nil, fmt.Errorf("%w: blind index key must be %d bytes", ErrInvalidKeyringKeys, keySize)
+	}
+	return &Keyring{
+		keys:     keys,
+		active:   active,
+		indexKey: indexKey,
+	}, nil
+}
+
+// New reads the keys as comma separated version:base64 pairs, the key version picks
+// the active version and defaults to the highest
+func New(cfg config.FieldEncryption) (*Keyring, error) {
+	keys := map[int][]byte{}
+	active := 0
+	for _, pair := range strings.Split(cfg.Keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: expected version:key pairs", ErrInvalidKeyringKeys)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid key version %q", ErrInvalidKeyringKeys, parts[0])
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: key version %d is not base64", ErrInvalidKeyringKeys, version)
+		}
+		keys[version] = key
+		if version > active {
+			active = version
+		}
+	}
+	if cfg.KeyVersion != 0 {
+		active = cfg.KeyVersion
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(cfg.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: blind index key is not base64", ErrInvalidKeyringKeys)
+	}
+	return NewKeyring(keys, active, indexKey)
+}
+
+func (keyring *Keyring) ActiveVersion() int {
+	return keyring.active
+}
+
+// Encrypt seals the value with a new data key wrapped with the active key
+func (keyring *Keyring) Encrypt(plaintext string) (value string, err error) {
+	dataKey := make([]byte, keySize)
+	if _, err = io.ReadFull(rand.Reader, dataKey); err != nil {
+		return
+	}
+	wrapped, err := seal(keyring.keys[keyring.active], dataKey)
+	if err != nil {
+		return
+	}
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return
+	}
+	return format(keyring.active, wrapped, sealed), nil
+}
+
+func (keyring *Keyring) Decrypt(value string) (plaintext string, err error) {
+	version, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return
+	}
+	dataKey, err := keyring.unwrap(version, wrapped)
+	if err != nil {
+		return
+	}
+	opened, err := open(dataKey, sealed)
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	return string(opened), nil
+}
+
+// Rewrap wraps the data key of the value with the active key, the sealed value itself
+// is left as it is
+func (keyring *Keyring) Rewrap(value string) (rewrapped string, err error) {
+	version, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return
+	}
+	if version == keyring.active {
+		return value, nil
+	}
+	dataKey, err := keyring.unwrap(version, wrapped)
+	if err != nil {
+		return
+	}
+	wrapped, err = seal(keyring.keys[keyring.active], dataKey)
+	if err != nil {
+		return
+	}
+	return format(keyring.active, wrapped, sealed), nil
+}
+
+// BlindIndex is a keyed hash of the value, equal values give equal indexes so an
+// encrypted column can still be looked up. The field name is part of the hash so the
+// same value in two columns cannot be matched.
+func (keyring *Keyring) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, keyring.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strings.TrimSpace(value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (keyring *Keyring) unwrap(version int, wrapped []byte) (dataKey []byte, err error) {
+	key, ok := keyring.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+	dataKey, err = open(key, wrapped)
+	if err != nil {
+		return nil, ErrMalformedValue
+	}
+	return
+}
+
+// IsEncrypted tells an encrypted value from one stored before the column was encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Version returns the version of the key the data key of the value is wrapped with
+func Version(value string) (version int, err error) {
+	version, _, _, err = parse(value)
+	return
+}
+
+func format(version int, wrapped, sealed []byte) string {
+	return prefix + strconv.Itoa(version) + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+func parse(value string) (version int, wrapped, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if !IsEncrypted(value) || len(parts) != 3 {
+		err = ErrMalformedValue
+		return
+	}
+	version, err = strconv.Atoi(parts[0])
+	if err != nil {
+		err = ErrMalformedValue
+		return
+	}
+	wrapped, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = ErrMalformedValue
+		return
+	}
+	sealed, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		err = ErrMalformedValue
+		return
+	}
+	return
+}
+
+// seal encrypts with AES-256-GCM, the random nonce is put in front of the ciphertext
+func seal(key, plaintext []byte) (sealed []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) (plaintext []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
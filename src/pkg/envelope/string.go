@@ -0,0 +1,68 @@
+package envelope
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+var defaultKeyring *Keyring
+
+// SetDefault sets the keyring String columns are encrypted with
+func SetDefault(keyring *Keyring) {
+	defaultKeyring = keyring
+}
+
+// BlindIndex hashes the value with the default keyring, see Keyring.BlindIndex
+func BlindIndex(field, value string) (index string, err error) {
+	if defaultKeyring == nil {
+		return "", ErrKeyringNotLoaded
+	}
+	return defaultKeyring.BlindIndex(field, value), nil
+}
+
+// String is a column that is encrypted with the default keyring when written and
+// decrypted when read. Values stored before the column was encrypted are read as they
+// are until they are re-encrypted.
+type String string
+
+func (s String) Value() (driver.Value, error) {
+	if defaultKeyring == nil {
+		return nil, ErrKeyringNotLoaded
+	}
+	return defaultKeyring.Encrypt(string(s))
+}
+
+func (s *String) Scan(src interface{}) (err error) {
+	value := ""
+	switch src := src.(type) {
+	case string:
+		value = src
+	case []byte:
+		value = string(src)
+	default:
+		return fmt.Errorf("envelope: cannot scan %T into String", src)
+	}
+
+	if !IsEncrypted(value) {
+		*s = String(value)
+		return
+	}
+	if defaultKeyring == nil {
+		return ErrKeyringNotLoaded
+	}
+	plaintext, err := defaultKeyring.Decrypt(value)
+	if err != nil {
+		return
+	}
+	*s = String(plaintext)
+	return
+}
+
+// Ptr returns a pointer to the value as a String, or nil when the value is empty
+func Ptr(value string) *String {
+	if value == "" {
+		return nil
+	}
+	s := String(value)
+	return &s
+}
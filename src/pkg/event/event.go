@@ -0,0 +1,39 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/forkyid/go-utils/v1/uuid"
+)
+
+// Event is the envelope every domain event is delivered in. Sequence is the outbox id
+// the relay delivers the event with, it increases with the order events are recorded
+// in and is only known in process
+type Event struct {
+	ID         string      `json:"id"`
+	Sequence   int64       `json:"-"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Sink is a destination the outbox relay publishes domain events to, delivery is at
+// least once so sinks should tolerate an event id they have already seen
+type Sink interface {
+	Deliver(ctx context.Context, domainEvent Event) (err error)
+}
+
+// Notifier is told that new events were committed to the outbox
+type Notifier interface {
+	Notify()
+}
+
+func New(eventType string, data interface{}) Event {
+	return Event{
+		ID:         uuid.GetUUID(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
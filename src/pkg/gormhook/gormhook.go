@@ -0,0 +1,62 @@
+// Package gormhook registers a pair of callbacks around every statement gorm runs,
+// the plugins for tracing, metrics and query timeouts are built on it.
+package gormhook
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// The gorm callback chains a statement runs through
+const (
+	Create = "create"
+	Query  = "query"
+	Update = "update"
+	Delete = "delete"
+	Row    = "row"
+	Raw    = "raw"
+)
+
+// Operations lists every chain, Register wraps all of them when given none
+var Operations = []string{Create, Query, Update, Delete, Row, Raw}
+
+// Hook is called with the statement and the operation it runs as
+type Hook func(db *gorm.DB, operation string)
+
+// Register runs before ahead of every callback of the operations and after once they
+// ran, named <name>:before_<operation> and <name>:after_<operation>
+func Register(db *gorm.DB, name string, before, after Hook, operations ...string) (err error) {
+	if len(operations) == 0 {
+		operations = Operations
+	}
+
+	callback := db.Callback()
+	registers := map[string][2]func(name string, fn func(*gorm.DB)) error{
+		Create: {callback.Create().Before("*").Register, callback.Create().After("*").Register},
+		Query:  {callback.Query().Before("*").Register, callback.Query().After("*").Register},
+		Update: {callback.Update().Before("*").Register, callback.Update().After("*").Register},
+		Delete: {callback.Delete().Before("*").Register, callback.Delete().After("*").Register},
+		Row:    {callback.Row().Before("*").Register, callback.Row().After("*").Register},
+		Raw:    {callback.Raw().Before("*").Register, callback.Raw().After("*").Register},
+	}
+	for _, operation := range operations {
+		register, ok := registers[operation]
+		if !ok {
+			return fmt.Errorf("unknown gorm operation %q", operation)
+		}
+		if err = register[0](name+":before_"+operation, bind(before, operation)); err != nil {
+			return
+		}
+		if err = register[1](name+":after_"+operation, bind(after, operation)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func bind(hook Hook, operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		hook(db, operation)
+	}
+}
@@ -0,0 +1,44 @@
+package gormhook
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type account struct {
+	ID   uint
+	Name string
+}
+
+func TestRegister(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []string{}
+	before := func(db *gorm.DB, operation string) { calls = append(calls, "before "+operation) }
+	after := func(db *gorm.DB, operation string) { calls = append(calls, "after "+operation) }
+	if err := Register(db, "test", before, after, Create, Query); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Create(&account{Name: "a"})
+	db.Find(&[]account{})
+	db.Where("id = ?", 1).Delete(&account{})
+
+	want := []string{"before create", "after create", "before query", "after query"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	if err := Register(db, "unknown", before, after, "upsert"); err == nil {
+		t.Error("an unknown operation was registered")
+	}
+}
@@ -0,0 +1,134 @@
+// Package health runs the dependency checks behind the liveness and readiness
+// endpoints. Every check gets its own timeout and runs concurrently.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check reports whether a dependency can be used, it must return once ctx is done
+type Check func(ctx context.Context) error
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+type Component struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Checker struct {
+	timeout  time.Duration
+	started  time.Time
+	draining int32
+
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		started: time.Now(),
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register adds a dependency the service cannot serve without
+func (checker *Checker) Register(name string, check Check) {
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	checker.checks[name] = check
+}
+
+// Drain makes the service report not ready from now on, it is called when the
+// shutdown starts
+func (checker *Checker) Drain() {
+	atomic.StoreInt32(&checker.draining, 1)
+}
+
+// Live only tells the process is running and serving requests
+func (checker *Checker) Live() Report {
+	return Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"process": {Status: StatusOK},
+		},
+	}
+}
+
+// Ready runs every check, the service is ready when all of them pass and it is
+// not shutting down
+func (checker *Checker) Ready(ctx context.Context) (report Report, ready bool) {
+	checker.mu.Lock()
+	checks := make(map[string]Check, len(checker.checks))
+	for name, check := range checker.checks {
+		checks[name] = check
+	}
+	checker.mu.Unlock()
+
+	report = Report{
+		Status:     StatusOK,
+		Components: make(map[string]Component, len(checks)+1),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			component := checker.run(ctx, check)
+			mu.Lock()
+			report.Components[name] = component
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	ready = true
+	for _, component := range report.Components {
+		if component.Status != StatusOK {
+			ready = false
+		}
+	}
+	if atomic.LoadInt32(&checker.draining) == 1 {
+		report.Components["lifecycle"] = Component{Status: StatusDraining}
+		ready = false
+	}
+	if !ready {
+		report.Status = StatusUnavailable
+	}
+	return
+}
+
+func (checker *Checker) run(ctx context.Context, check Check) (component Component) {
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	component.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		component.Status = StatusUnavailable
+		component.Error = err.Error()
+		return
+	}
+	component.Status = StatusOK
+	return
+}
@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidNIK         = errors.New("invalid nik")
+	ErrInvalidNIKRegion   = errors.New("invalid nik region code")
+	ErrInvalidNIKBirth    = errors.New("invalid nik birth date")
+	ErrInvalidNIKSerial   = errors.New("invalid nik serial number")
+	ErrInvalidPhoneNumber = errors.New("invalid phone number")
+)
+
+// provinceCodes are the province codes of the Dukcapil region coding, a nik starts with
+// the code of the province it was issued in
+var provinceCodes = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true,
+	"51": true, "52": true, "53": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "72": true, "73": true, "74": true, "75": true, "76": true,
+	"81": true, "82": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true,
+}
+
+// NIK is a parsed Nomor Induk Kependudukan, the 16 digit number on the KTP
+type NIK struct {
+	Province string
+	Regency  string
+	District string
+	// BirthYear only holds the last two digits of the year
+	BirthDay   int
+	BirthMonth int
+	BirthYear  int
+	Female     bool
+	Serial     string
+}
+
+// ParseNIK checks the structure of a nik: the province, regency and district code, the
+// birth date where women have 40 added to the day, and the registration serial. A nik
+// has no mathematical check digit, the serial is the trailing four digits counting up
+// from 0001 for everyone born on the same day in the same district.
+func ParseNIK(value string) (nik NIK, err error) {
+	if len(value) != 16 {
+		err = ErrInvalidNIK
+		return
+	}
+	for _, digit := range value {
+		if digit < '0' || digit > '9' {
+			err = ErrInvalidNIK
+			return
+		}
+	}
+
+	nik.Province = value[0:2]
+	nik.Regency = value[2:4]
+	nik.District = value[4:6]
+	if !provinceCodes[nik.Province] || nik.Regency == "00" || nik.District == "00" {
+		err = ErrInvalidNIKRegion
+		return
+	}
+
+	nik.BirthDay, _ = strconv.Atoi(value[6:8])
+	nik.BirthMonth, _ = strconv.Atoi(value[8:10])
+	nik.BirthYear, _ = strconv.Atoi(value[10:12])
+	if nik.BirthDay > 40 {
+		nik.BirthDay -= 40
+		nik.Female = true
+	}
+	// the century is unknown, 2000 is a leap year so 29 february is accepted
+	date := time.Date(2000, time.Month(nik.BirthMonth), nik.BirthDay, 0, 0, 0, 0, time.UTC)
+	if nik.BirthDay < 1 || nik.BirthMonth < 1 || nik.BirthMonth > 12 || date.Day() != nik.BirthDay {
+		err = ErrInvalidNIKBirth
+		return
+	}
+
+	nik.Serial = value[12:16]
+	if nik.Serial == "0000" {
+		err = ErrInvalidNIKSerial
+		return
+	}
+	return
+}
+
+// MatchesDateOfBirth reports whether the birth date segment of the nik is the date
+func (nik NIK) MatchesDateOfBirth(date time.Time) bool {
+	return nik.BirthDay == date.Day() &&
+		nik.BirthMonth == int(date.Month()) &&
+		nik.BirthYear == date.Year()%100
+}
+
+// MatchesGender reports whether the nik was issued for the gender, an unknown gender
+// matches any nik
+func (nik NIK) MatchesGender(gender string) bool {
+	switch gender {
+	case "male":
+		return !nik.Female
+	case "female":
+		return nik.Female
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package identity
+
+import (
+	"regexp"
+	"strings"
+)
+
+const countryCodeIndonesia = "62"
+
+var (
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	// nationalNumber is an indonesian number without the trunk prefix, 8 to 12 digits
+	// for both mobile numbers and landlines with their area code
+	nationalNumber = regexp.MustCompile(`^[2-9][0-9]{7,11}$`)
+	e164Number     = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// NormalizePhoneNumber returns the phone number in E.164. Indonesian numbers are
+// accepted as 0812..., 62812... or +62812..., separators are dropped, and numbers of
+// other countries have to be written with their + country code already.
+func NormalizePhoneNumber(value string) (phoneNumber string, err error) {
+	value = phoneSeparators.Replace(strings.TrimSpace(value))
+
+	var national string
+	switch {
+	case strings.HasPrefix(value, "+"+countryCodeIndonesia):
+		national = strings.TrimPrefix(value, "+"+countryCodeIndonesia)
+	case strings.HasPrefix(value, countryCodeIndonesia):
+		national = strings.TrimPrefix(value, countryCodeIndonesia)
+	case strings.HasPrefix(value, "0"):
+		national = value
+	case strings.HasPrefix(value, "+"):
+		if !e164Number.MatchString(value) {
+			err = ErrInvalidPhoneNumber
+			return
+		}
+		phoneNumber = value
+		return
+	default:
+		err = ErrInvalidPhoneNumber
+		return
+	}
+
+	// +62 (0)812... is a common way to write a number
+	national = strings.TrimPrefix(national, "0")
+	if !nationalNumber.MatchString(national) {
+		err = ErrInvalidPhoneNumber
+		return
+	}
+	phoneNumber = "+" + countryCodeIndonesia + national
+	return
+}
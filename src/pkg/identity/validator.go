@@ -0,0 +1,82 @@
+package identity
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const dateOfBirthFormat = "2006-01-02"
+
+// RegisterValidations adds the validator tags:
+//
+//	nik             a structurally valid nik
+//	nik_birth_date  the nik matches the yyyy-mm-dd date of birth in the field named by the param
+//	nik_gender      the nik matches the male or female gender in the field named by the param
+//	phone           a phone number NormalizePhoneNumber accepts
+//
+// The sibling checks pass when the sibling is not set, so partial updates validate.
+func RegisterValidations(validate *validator.Validate) (err error) {
+	validations := map[string]validator.Func{
+		"nik":            validateNIK,
+		"nik_birth_date": validateNIKBirthDate,
+		"nik_gender":     validateNIKGender,
+		"phone":          validatePhoneNumber,
+	}
+	for tag, validation := range validations {
+		if err = validate.RegisterValidation(tag, validation); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func validateNIK(fl validator.FieldLevel) bool {
+	_, err := ParseNIK(fl.Field().String())
+	return err == nil
+}
+
+func validateNIKBirthDate(fl validator.FieldLevel) bool {
+	nik, err := ParseNIK(fl.Field().String())
+	if err != nil {
+		// reported by the nik tag
+		return true
+	}
+	value, ok := siblingString(fl)
+	if !ok {
+		return true
+	}
+	date, err := time.Parse(dateOfBirthFormat, value)
+	if err != nil {
+		// reported by the date of birth field
+		return true
+	}
+	return nik.MatchesDateOfBirth(date)
+}
+
+func validateNIKGender(fl validator.FieldLevel) bool {
+	nik, err := ParseNIK(fl.Field().String())
+	if err != nil {
+		return true
+	}
+	value, ok := siblingString(fl)
+	if !ok {
+		return true
+	}
+	return nik.MatchesGender(value)
+}
+
+func validatePhoneNumber(fl validator.FieldLevel) bool {
+	_, err := NormalizePhoneNumber(fl.Field().String())
+	return err == nil
+}
+
+// siblingString returns the value of the string or *string field named by the param
+func siblingString(fl validator.FieldLevel) (value string, ok bool) {
+	field, kind, _, found := fl.GetStructFieldOK2()
+	if !found || kind != reflect.String {
+		return
+	}
+	return field.String(), field.String() != ""
+}
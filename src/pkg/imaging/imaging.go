@@ -0,0 +1,181 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maximumPixels guards against small files that decode into huge images
+	maximumPixels = 40_000_000
+	jpegQuality   = 85
+)
+
+var (
+	ErrInvalidImage  = errors.New("invalid image")
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// Variant is a resized copy of an image. The image is scaled down to fit inside the
+// box, or to fill it when Crop is set, images smaller than the box are not scaled up.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+type Image struct {
+	Name string
+	Body []byte
+}
+
+// Process decodes a jpeg or png image and re-encodes every variant as jpeg, which also
+// drops any metadata the original carried. Transparent pixels are flattened on white.
+func Process(data []byte, variants []Variant) (images []Image, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > maximumPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	bounds := decoded.Bounds()
+	source := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(source, source.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(source, source.Bounds(), decoded, bounds.Min, draw.Over)
+
+	for _, variant := range variants {
+		buffer := bytes.Buffer{}
+		err = jpeg.Encode(&buffer, scale(source, variant), &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, fmt.Errorf("encode %s variant: %w", variant.Name, err)
+		}
+		images = append(images, Image{
+			Name: variant.Name,
+			Body: buffer.Bytes(),
+		})
+	}
+	return
+}
+
+func scale(source *image.RGBA, variant Variant) *image.RGBA {
+	width, height := source.Bounds().Dx(), source.Bounds().Dy()
+	if !variant.Crop {
+		ratio := min(min(float64(variant.Width)/float64(width), float64(variant.Height)/float64(height)), 1)
+		return resize(source, source.Bounds(), round(float64(width)*ratio), round(float64(height)*ratio))
+	}
+
+	// cut the largest center part of the source that has the aspect ratio of the box
+	aspect := float64(variant.Width) / float64(variant.Height)
+	cropWidth, cropHeight := width, round(float64(width)/aspect)
+	if cropHeight > height {
+		cropWidth, cropHeight = round(float64(height)*aspect), height
+	}
+	x, y := (width-cropWidth)/2, (height-cropHeight)/2
+	crop := image.Rect(x, y, x+cropWidth, y+cropHeight)
+
+	targetWidth, targetHeight := variant.Width, variant.Height
+	if cropWidth < targetWidth {
+		targetWidth, targetHeight = cropWidth, cropHeight
+	}
+	return resize(source, crop, targetWidth, targetHeight)
+}
+
+// resize averages every source pixel that falls into a target pixel, a box filter is
+// enough for scaling down
+func resize(source *image.RGBA, crop image.Rectangle, width, height int) *image.RGBA {
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+	cropWidth, cropHeight := crop.Dx(), crop.Dy()
+	for y := 0; y < height; y++ {
+		y0 := crop.Min.Y + y*cropHeight/height
+		y1 := crop.Min.Y + (y+1)*cropHeight/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0 := crop.Min.X + x*cropWidth/width
+			x1 := crop.Min.X + (x+1)*cropWidth/width
+			if x1 == x0 {
+				x1++
+			}
+
+			var r, g, b, count int
+			for sy := y0; sy < y1; sy++ {
+				offset := source.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(source.Pix[offset])
+					g += int(source.Pix[offset+1])
+					b += int(source.Pix[offset+2])
+					offset += 4
+					count++
+				}
+			}
+			offset := target.PixOffset(x, y)
+			target.Pix[offset] = uint8(r / count)
+			target.Pix[offset+1] = uint8(g / count)
+			target.Pix[offset+2] = uint8(b / count)
+			target.Pix[offset+3] = 0xff
+		}
+	}
+	return target
+}
+
+// Placeholder draws the initials of a name on a background color picked from the
+// name, so the same name always gets the same placeholder
+func Placeholder(name string) []byte {
+	initials := []rune{}
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(initials) == 2 {
+			break
+		}
+	}
+	if len(initials) == 0 {
+		initials = []rune{'?'}
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	hue := hash.Sum32() % 360
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 256 256">`+
+		`<rect width="256" height="256" fill="hsl(%d, 45%%, 50%%)"/>`+
+		`<text x="128" y="128" dy="0.35em" text-anchor="middle" font-family="sans-serif" font-size="104" fill="#ffffff">%s</text>`+
+		`</svg>`, hue, html.EscapeString(string(initials))))
+}
+
+func round(value float64) int {
+	rounded := int(value + 0.5)
+	if rounded < 1 {
+		return 1
+	}
+	return rounded
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"strings"
@@ -8,9 +10,16 @@ import (
 
 	"github.com/forkyid/go-utils/v1/aes"
 	"github.com/golang-jwt/jwt"
-	"go-rest-api/src/constant"
 )
 
+// secretKey signs bearer tokens and is the base of the invitation and stream keys, it is set
+// from the config at startup
+var secretKey []byte
+
+func SetSecretKey(key string) {
+	secretKey = []byte(key)
+}
+
 func GenerateJWT(accountID string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
@@ -18,9 +27,9 @@ func GenerateJWT(accountID string) (string, error) {
 	claims["accountID"] = accountID
 	claims["exp"] = time.Now().Add(time.Minute * 30).Unix()
 
-	tokenString, err := token.SignedString(constant.SampleSecretKey)
+	tokenString, err := token.SignedString(secretKey)
 	if err != nil {
-		fmt.Errorf("Something Went Wrong: %s", err.Error())
+		err = fmt.Errorf("Something Went Wrong: %s", err.Error())
 		return "", err
 	}
 	return tokenString, nil
@@ -33,7 +42,7 @@ func ValidateToken(bearerToken string) (claims jwt.MapClaims, err error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("there was an error in parsing")
 		}
-		return constant.SampleSecretKey, nil
+		return secretKey, nil
 	})
 	if err != nil {
 		return nil, err
@@ -68,4 +77,103 @@ func ExtractID(bearerToken string) (int, error) {
 		return -1, fmt.Errorf("invalid ID")
 	}
 	return id, nil
-}
\ No newline at end of file
+}
+// invitationKey signs invitation tokens, it is derived from the secret key so an
+// invitation token is never accepted as a bearer token and the other way around
+func invitationKey() []byte {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte("invitation"))
+	return mac.Sum(nil)
+}
+
+func GenerateInvitationToken(invitationID string, version int, expiresAt time.Time) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	claims["invitationID"] = invitationID
+	claims["version"] = version
+	claims["exp"] = expiresAt.Unix()
+
+	return token.SignedString(invitationKey())
+}
+
+// ExtractInvitationID checks the signature of an invitation token and returns the
+// invitation id and version, an expired token still returns them so callers can tell
+// it apart. Tokens issued before versions existed are version 1
+func ExtractInvitationID(tokenString string) (int, int, error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("there was an error in parsing")
+		}
+		return invitationKey(), nil
+	})
+	if err != nil {
+		return -1, 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return -1, 0, errors.New("invalid invitation token")
+	}
+	invitationID, ok := claims["invitationID"].(string)
+	if !ok {
+		return -1, 0, errors.New("invalid invitation token")
+	}
+	id := aes.Decrypt(invitationID)
+	if id == -1 {
+		return -1, 0, fmt.Errorf("invalid ID")
+	}
+	version := 1
+	if claim, ok := claims["version"].(float64); ok {
+		version = int(claim)
+	}
+	return id, version, nil
+}
+
+// streamKey signs stream tickets, derived like the invitation key so a ticket is only
+// accepted by the attendance stream
+func streamKey() []byte {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte("stream"))
+	return mac.Sum(nil)
+}
+
+// GenerateStreamTicket returns a ticket for clients that cannot set the Authorization
+// header on the attendance stream, e.g. EventSource. The ticket goes in the url, so it
+// only opens the stream and expires after the ttl
+func GenerateStreamTicket(accountID string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	claims["accountID"] = accountID
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	return token.SignedString(streamKey())
+}
+
+// ExtractStreamTicketID checks the signature and expiry of a stream ticket and returns
+// the account id
+func ExtractStreamTicketID(ticket string) (int, error) {
+	token, err := jwt.Parse(ticket, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("there was an error in parsing")
+		}
+		return streamKey(), nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return -1, errors.New("invalid stream ticket")
+	}
+	accountID, ok := claims["accountID"].(string)
+	if !ok {
+		return -1, errors.New("invalid stream ticket")
+	}
+	id := aes.Decrypt(accountID)
+	if id == -1 {
+		return -1, fmt.Errorf("invalid ID")
+	}
+	return id, nil
+}
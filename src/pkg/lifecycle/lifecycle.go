@@ -0,0 +1,134 @@
+// Package lifecycle runs the server until SIGINT or SIGTERM and then stops it in
+// order: the shutdown callbacks run, the server drains its connections, the workers are cancelled and waited
+// for, then the stop hooks run in reverse registration order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-rest-api/src/pkg/logging"
+)
+
+type hook struct {
+	name string
+	stop func() error
+}
+
+type Lifecycle struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mu         sync.Mutex
+	hooks      []hook
+	onShutdown []func()
+}
+
+func New() *Lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifecycle{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go starts a background worker, its context is cancelled once the server drained
+func (l *Lifecycle) Go(name string, worker func(ctx context.Context)) {
+	l.workers.Add(1)
+	go func() {
+		defer l.workers.Done()
+		worker(l.ctx)
+		logger().Info("worker stopped", "worker", name)
+	}()
+}
+
+// OnStop registers a hook that runs after the workers stopped, hooks run in reverse
+// order so a dependency registered first is closed last
+func (l *Lifecycle) OnStop(name string, stop func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook{name: name, stop: stop})
+}
+
+// OnShutdown registers a callback that runs as soon as the shutdown starts, before
+// the server drains
+func (l *Lifecycle) OnShutdown(callback func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onShutdown = append(l.onShutdown, callback)
+}
+
+// Run calls serve and blocks until it fails or a signal arrives, shutdown gets a
+// context that expires after timeout and should stop accepting and drain requests.
+// Workers and hooks share what is left of that timeout.
+func (l *Lifecycle) Run(serve func() error, shutdown func(ctx context.Context) error, timeout time.Duration) (err error) {
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+
+	select {
+	case err = <-served:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case <-signals.Done():
+		logger().Info("shutting down")
+	}
+
+	l.mu.Lock()
+	callbacks := l.onShutdown
+	l.mu.Unlock()
+	for _, callback := range callbacks {
+		callback()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if shutdownErr := shutdown(ctx); shutdownErr != nil {
+		logger().Error("drain connections", "error", shutdownErr)
+		if err == nil {
+			err = shutdownErr
+		}
+	}
+
+	l.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		l.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger().Warn("workers did not stop in time")
+	}
+
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hookErr := hooks[i].stop(); hookErr != nil {
+			logger().Error("stop hook failed", "hook", hooks[i].name, "error", hookErr)
+			if err == nil {
+				err = hookErr
+			}
+		}
+	}
+	return
+}
+
+func logger() *logging.Logger {
+	return logging.Default().With("component", "lifecycle")
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQuery is logged as a warning
+const slowQuery = 200 * time.Millisecond
+
+// GormLogger writes gorm messages with the logger of the statement context. Failed
+// and slow statements are logged without their values, every statement with its
+// values only at debug level
+type GormLogger struct {
+	level gormlogger.LogLevel
+}
+
+func NewGormLogger() GormLogger {
+	return GormLogger{level: gormlogger.Info}
+}
+
+func (l GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	l.level = level
+	return l
+}
+
+func (l GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	logger := FromContext(ctx)
+	elapsed := time.Since(begin)
+	latency := float64(elapsed.Microseconds()) / 1000
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		_, rows := fc()
+		logger.Error("query failed", "error", err, "rows", rows, "latency_ms", latency)
+	case elapsed > slowQuery && l.level >= gormlogger.Warn:
+		_, rows := fc()
+		logger.Warn("slow query", "rows", rows, "latency_ms", latency)
+	case logger.Enabled(LevelDebug) && l.level >= gormlogger.Info:
+		sql, rows := fc()
+		logger.Debug("query", "sql", sql, "rows", rows, "latency_ms", latency)
+	}
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// Middleware puts a logger carrying the request id in the request context and
+// writes one access line per request. It runs after middleware.RequestID, which
+// takes the id from X-Request-ID or generates one
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			request := c.Request()
+
+			logger := Default()
+			if requestID := RequestID(c); requestID != "" {
+				logger = logger.With("request_id", requestID)
+			}
+			c.SetRequest(request.WithContext(WithContext(request.Context(), logger)))
+
+			// the error is answered here so the line carries the status it was
+			// answered with, the error handler skips a committed response after
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+
+			args := []interface{}{
+				"method", request.Method,
+				"route", c.Path(),
+				"path", request.URL.Path,
+				"status", status,
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"ip", c.RealIP(),
+				"bytes_out", c.Response().Size,
+			}
+			if err != nil {
+				args = append(args, "error", err)
+			}
+			switch {
+			case status >= http.StatusInternalServerError:
+				logger.Error("request", args...)
+			case status >= http.StatusBadRequest:
+				logger.Warn("request", args...)
+			default:
+				logger.Info("request", args...)
+			}
+			return err
+		}
+	}
+}
+
+// RequestID returns the id middleware.RequestID gave the request
+func RequestID(c echo.Context) string {
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		return requestID
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
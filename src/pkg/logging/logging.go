@@ -0,0 +1,179 @@
+// Package logging writes leveled JSON lines. Attributes are passed as key value
+// pairs like log/slog, values of keys that look like credentials are redacted and
+// the logger of a request carries its request id.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "info"
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(value string) (level Level, err error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", value)
+}
+
+type Logger struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level Level
+	attrs []interface{}
+}
+
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+	}
+}
+
+var defaultLogger = New(os.Stderr, LevelInfo)
+
+// SetDefault replaces the logger used outside of requests and by FromContext when
+// the context has none
+func SetDefault(logger *Logger) {
+	defaultLogger = logger
+}
+
+func Default() *Logger {
+	return defaultLogger
+}
+
+// With returns a logger that adds the attributes to every line
+func (logger *Logger) With(args ...interface{}) *Logger {
+	attrs := make([]interface{}, 0, len(logger.attrs)+len(args))
+	attrs = append(attrs, logger.attrs...)
+	attrs = append(attrs, args...)
+	return &Logger{
+		mu:    logger.mu,
+		out:   logger.out,
+		level: logger.level,
+		attrs: attrs,
+	}
+}
+
+func (logger *Logger) Enabled(level Level) bool {
+	return level >= logger.level
+}
+
+func (logger *Logger) Debug(msg string, args ...interface{}) {
+	logger.log(LevelDebug, msg, args)
+}
+
+func (logger *Logger) Info(msg string, args ...interface{}) {
+	logger.log(LevelInfo, msg, args)
+}
+
+func (logger *Logger) Warn(msg string, args ...interface{}) {
+	logger.log(LevelWarn, msg, args)
+}
+
+func (logger *Logger) Error(msg string, args ...interface{}) {
+	logger.log(LevelError, msg, args)
+}
+
+func (logger *Logger) log(level Level, msg string, args []interface{}) {
+	if !logger.Enabled(level) {
+		return
+	}
+
+	line := &bytes.Buffer{}
+	line.WriteString(`{"time":`)
+	writeJSON(line, time.Now().UTC().Format(time.RFC3339Nano))
+	line.WriteString(`,"level":`)
+	writeJSON(line, level.String())
+	line.WriteString(`,"msg":`)
+	writeJSON(line, msg)
+	writeAttrs(line, logger.attrs)
+	writeAttrs(line, args)
+	line.WriteString("}\n")
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.out.Write(line.Bytes())
+}
+
+// writeAttrs writes key value pairs in the order they were passed, a key without a
+// value is written under !BADKEY like slog does
+func writeAttrs(line *bytes.Buffer, args []interface{}) {
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			writeAttr(line, "!BADKEY", redactValue("", args[i]))
+			i--
+			continue
+		}
+		writeAttr(line, key, redactValue(key, args[i+1]))
+	}
+}
+
+func writeAttr(line *bytes.Buffer, key string, value interface{}) {
+	line.WriteByte(',')
+	writeJSON(line, key)
+	line.WriteByte(':')
+	writeJSON(line, value)
+}
+
+func writeJSON(line *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("!ERROR %v", err))
+	}
+	line.Write(data)
+}
+
+type contextKey struct{}
+
+// WithContext stores the logger in ctx
+func WithContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the default one
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return logger
+		}
+	}
+	return defaultLogger
+}
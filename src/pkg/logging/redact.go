@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const redacted = "[redacted]"
+
+// sensitiveKeys are matched against attribute and field names, case insensitive
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"signature",
+	"cookie",
+	"api_key",
+}
+
+var bearerToken = regexp.MustCompile(`(?i)bearer\s+[a-z0-9\-_.~+/=]+`)
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue turns value into something json can write with credentials removed,
+// structs and maps are redacted by their json field names
+func redactValue(key string, value interface{}) interface{} {
+	if sensitive(key) {
+		return redacted
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return redactString(value.Error())
+	case string:
+		return redactString(value)
+	case fmt.Stringer:
+		if _, ok := value.(time.Time); !ok {
+			return redactString(value.String())
+		}
+		return value
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Duration, time.Time:
+		return value
+	}
+
+	kind := reflect.Indirect(reflect.ValueOf(value)).Kind()
+	if kind != reflect.Struct && kind != reflect.Map && kind != reflect.Slice && kind != reflect.Array {
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return redactDecoded(decoded)
+}
+
+func redactDecoded(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if sensitive(key) {
+				value[key] = redacted
+				continue
+			}
+			value[key] = redactDecoded(nested)
+		}
+		return value
+	case []interface{}:
+		for i := range value {
+			value[i] = redactDecoded(value[i])
+		}
+		return value
+	case string:
+		return redactString(value)
+	}
+	return value
+}
+
+func redactString(value string) string {
+	return bearerToken.ReplaceAllString(value, "Bearer "+redacted)
+}
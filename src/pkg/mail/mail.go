@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/pkg/logging"
+)
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(to string, subject string, body string) (err error)
+}
+
+// New builds the mailer selected by the mail driver, defaults to log
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return Log{}, nil
+	case DriverSMTP:
+		return NewSMTP(SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     strconv.Itoa(cfg.SMTPPort),
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// Log writes emails to the log instead of sending them, meant for development. The
+// body is logged as is, temporary passwords included
+type Log struct{}
+
+func (Log) Send(to string, subject string, body string) (err error) {
+	logging.Default().Info("mail", "to", to, "subject", subject, "body", body)
+	return
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTP struct {
+	config SMTPConfig
+}
+
+func NewSMTP(config SMTPConfig) (*SMTP, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, fmt.Errorf("smtp mailer needs a host and a from address")
+	}
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &SMTP{
+		config: config,
+	}, nil
+}
+
+func (s *SMTP) Send(to string, subject string, body string) (err error) {
+	// headers are built by hand, refuse anything that could inject another one
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	message := "From: " + s.config.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(net.JoinHostPort(s.config.Host, s.config.Port), auth, s.config.From, []string{to}, []byte(message))
+}
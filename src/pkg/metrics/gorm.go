@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"go-rest-api/src/pkg/gormhook"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "metrics:started_at"
+
+// RegisterDB observes every statement run through db and exports the stats of its
+// connection pool
+func RegisterDB(db *gorm.DB, name string) error {
+	pool, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err = db.Use(gormPlugin{}); err != nil {
+		return err
+	}
+	return Register(collectors.NewDBStatsCollector(pool, name))
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) (err error) {
+	return gormhook.Register(db, "metrics", started, finished)
+}
+
+func started(db *gorm.DB, operation string) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func finished(db *gorm.DB, operation string) {
+	value, ok := db.InstanceGet(startedAtKey)
+	if !ok {
+		return
+	}
+	startedAt, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+
+	table := db.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+	DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(startedAt).Seconds())
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		DBQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests without a route so unknown paths do not each get
+// their own series
+const unmatchedRoute = "unmatched"
+
+// Middleware counts requests and observes their latency by the route template, not
+// the path, so ids in the path are not labels
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			route := c.Path()
+			if err != nil {
+				var httpError *echo.HTTPError
+				if errors.As(err, &httpError) {
+					status = httpError.Code
+				} else if status == 0 || status == http.StatusOK {
+					status = http.StatusInternalServerError
+				}
+				if errors.Is(err, echo.ErrNotFound) {
+					route = unmatchedRoute
+				}
+			}
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			HTTPRequests.WithLabelValues(labels...).Inc()
+			HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
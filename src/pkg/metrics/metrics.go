@@ -0,0 +1,76 @@
+// Package metrics holds the Prometheus collectors of the service, they are exposed
+// on /metrics by Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// http
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// database
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database statement latency by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Database statements that failed by operation and table, record not found excluded.",
+	}, []string{"operation", "table"})
+
+	// domain
+	CheckIns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "attendance_check_ins_total",
+		Help: "Recorded check-ins by location.",
+	}, []string{"location_id"})
+	AttendanceRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "attendance_rejections_total",
+		Help: "Attendance that was refused by reason.",
+	}, []string{"reason"})
+	FailedLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_failed_logins_total",
+		Help: "Login attempts that failed by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		DBQueryErrors,
+		CheckIns,
+		AttendanceRejections,
+		FailedLogins,
+	)
+}
+
+// Register adds a collector that is not part of this package, such as the pool
+// stats of a database
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
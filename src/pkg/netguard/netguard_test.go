@@ -0,0 +1,99 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "203.0.113.7", want: true},
+		{ip: "8.8.8.8", want: true},
+		{ip: "2001:4860:4860::8888", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "172.31.255.255"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "255.255.255.255"},
+		{ip: "::1"},
+		{ip: "::"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "::ffff:169.254.169.254"},
+		{ip: "64:ff9b::a9fe:a9fe"},
+	}
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			if got := Public(net.ParseIP(test.ip)); got != test.want {
+				t.Errorf("Public(%s) = %v, want %v", test.ip, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr error
+	}{
+		{host: "203.0.113.7"},
+		{host: "169.254.169.254", wantErr: ErrNotPublic},
+		{host: "::1", wantErr: ErrNotPublic},
+		{host: "localhost", wantErr: ErrNotPublic},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			err := CheckHost(context.Background(), test.host)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("CheckHost(%s) = %v, want %v", test.host, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Client(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrNotPublic) {
+		t.Errorf("Get(%s) = %v, want %v", server.URL, err, ErrNotPublic)
+	}
+}
+
+func TestClientRefusesRedirectsToInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("http://127.0.0.1:1/", http.StatusFound))
+	defer server.Close()
+
+	// the test server is internal too, so the redirect is followed by a client whose
+	// first hop is allowed
+	client := Client(time.Second)
+	first := true
+	transport := client.Transport.(*http.Transport)
+	guarded := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if first {
+			first = false
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		}
+		return guarded(ctx, network, address)
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrNotPublic) {
+		t.Errorf("Get(%s) = %v, want %v", server.URL, err, ErrNotPublic)
+	}
+}
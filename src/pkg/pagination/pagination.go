@@ -1,8 +1,8 @@
 package pagination
 
 type Pagination struct {
-	Limit     int `form:"limit"`
-	Page      int `form:"page"`
+	Limit     int `form:"limit" query:"limit"`
+	Page      int `form:"page" query:"page"`
 	Offset    int
 }
 
@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/pkg/logging"
+
+	"github.com/jackc/pgx/v4"
+	"gorm.io/gorm"
+)
+
+const listenRetryDelay = 5 * time.Second
+
+// PostgresChannel carries payloads to every replica through LISTEN/NOTIFY, so an
+// event relayed by one replica reaches the hubs of all of them. A payload must stay
+// under the 8000 bytes NOTIFY allows.
+type PostgresChannel struct {
+	db   *gorm.DB
+	dsn  string
+	name string
+}
+
+func NewPostgresChannel(db *gorm.DB, dsn string, name string) *PostgresChannel {
+	return &PostgresChannel{
+		db:   db,
+		dsn:  dsn,
+		name: name,
+	}
+}
+
+// Notify sends payload to the listeners of every replica, including this one
+func (channel *PostgresChannel) Notify(ctx context.Context, payload []byte) error {
+	return channel.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel.name, string(payload)).Error
+}
+
+// Listen calls handle with every payload until ctx is cancelled. It holds its own
+// connection outside the pool and reconnects after an error, payloads sent while it
+// is disconnected are lost.
+func (channel *PostgresChannel) Listen(ctx context.Context, handle func(payload []byte)) {
+	for {
+		err := channel.listen(ctx, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).Error("listen to postgres channel", "channel", channel.name, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (channel *PostgresChannel) listen(ctx context.Context, handle func(payload []byte)) (err error) {
+	conn, err := pgx.Connect(ctx, channel.dsn)
+	if err != nil {
+		return
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel.name}.Sanitize())
+	if err != nil {
+		return
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle([]byte(notification.Payload))
+	}
+}
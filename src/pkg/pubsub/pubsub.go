@@ -0,0 +1,138 @@
+package pubsub
+
+import (
+	"sync"
+)
+
+// Event is published with the id its source gave it, the same event has the same id
+// on every hub
+type Event struct {
+	ID      uint64
+	Type    string
+	Payload interface{}
+}
+
+// Publisher is the side of the hub services depend on
+type Publisher interface {
+	Publish(id uint64, eventType string, payload interface{}) Event
+}
+
+// Hub is an in-process pub/sub. Every subscriber has its own buffered channel, a
+// subscriber that falls behind is dropped and can resume from the replay buffer.
+// Events are identified by the id they are published with, so a subscriber can resume
+// on another hub that received the same events in the same order.
+type Hub struct {
+	mu          sync.Mutex
+	replay      []Event
+	replaySize  int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C      chan Event
+	filter func(Event) bool
+}
+
+func NewHub(replaySize, bufferSize int) *Hub {
+	return &Hub{
+		replaySize:  replaySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends the event to the subscribers, an id still in the replay buffer was
+// already published and is skipped, so a source delivering at least once does not
+// repeat events
+func (h *Hub) Publish(id uint64, eventType string, payload interface{}) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := Event{
+		ID:      id,
+		Type:    eventType,
+		Payload: payload,
+	}
+	if h.index(id) >= 0 {
+		return event
+	}
+
+	h.replay = append(h.replay, event)
+	if len(h.replay) > h.replaySize {
+		h.replay = h.replay[len(h.replay)-h.replaySize:]
+	}
+
+	for subscription := range h.subscribers {
+		if subscription.filter != nil && !subscription.filter(event) {
+			continue
+		}
+		select {
+		case subscription.C <- event:
+		default:
+			delete(h.subscribers, subscription)
+			close(subscription.C)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber and returns the buffered events published after
+// lastEventID, pass 0 to skip the replay. Ids do not have to be published in
+// increasing order, the events after lastEventID are the ones published after it. When
+// lastEventID is no longer buffered the events with a greater id are returned.
+func (h *Hub) Subscribe(lastEventID uint64, filter func(Event) bool) (subscription *Subscription, missed []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID != 0 {
+		replay := h.replay
+		last := h.index(lastEventID)
+		if last >= 0 {
+			replay = replay[last+1:]
+		}
+		for _, event := range replay {
+			if (last >= 0 || event.ID > lastEventID) && (filter == nil || filter(event)) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	subscription = &Subscription{
+		C:      make(chan Event, h.bufferSize),
+		filter: filter,
+	}
+	h.subscribers[subscription] = struct{}{}
+	return
+}
+
+// index is the position of id in the replay buffer, -1 when it is not buffered
+func (h *Hub) index(id uint64) int {
+	for i := len(h.replay) - 1; i >= 0; i-- {
+		if h.replay[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[subscription]; ok {
+		delete(h.subscribers, subscription)
+		close(subscription.C)
+	}
+}
+
+// Close ends every subscription, streams return once their channel is closed
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscription := range h.subscribers {
+		delete(h.subscribers, subscription)
+		close(subscription.C)
+	}
+}
@@ -0,0 +1,83 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ids(events []Event) (ids []uint64) {
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return
+}
+
+func TestSubscribeResumesOnAnotherHub(t *testing.T) {
+	// two replicas receive the same events in the same order, ids are not increasing
+	// when an event was retried after a later one
+	first := NewHub(10, 10)
+	second := NewHub(10, 10)
+	for _, hub := range []*Hub{first, second} {
+		for _, id := range []uint64{11, 12, 10, 13} {
+			hub.Publish(id, "check_in", nil)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		want        []uint64
+	}{
+		{name: "no replay", lastEventID: 0},
+		{name: "after the middle", lastEventID: 12, want: []uint64{10, 13}},
+		{name: "after a retried event", lastEventID: 10, want: []uint64{13}},
+		{name: "after the last", lastEventID: 13},
+		{name: "not buffered", lastEventID: 9, want: []uint64{11, 12, 10, 13}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subscription, missed := second.Subscribe(test.lastEventID, nil)
+			defer second.Unsubscribe(subscription)
+
+			if got := ids(missed); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("missed = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPublishSkipsRepeatedIDs(t *testing.T) {
+	hub := NewHub(10, 10)
+	subscription, _ := hub.Subscribe(0, nil)
+	defer hub.Unsubscribe(subscription)
+
+	hub.Publish(1, "check_in", nil)
+	hub.Publish(2, "check_out", nil)
+	hub.Publish(1, "check_in", nil)
+
+	received := []Event{<-subscription.C, <-subscription.C}
+	select {
+	case event := <-subscription.C:
+		t.Errorf("received repeated event %d", event.ID)
+	default:
+	}
+	if got := ids(received); !reflect.DeepEqual(got, []uint64{1, 2}) {
+		t.Errorf("received = %v, want [1 2]", got)
+	}
+}
+
+func TestSubscribeFilters(t *testing.T) {
+	hub := NewHub(10, 10)
+	hub.Publish(1, "check_in", nil)
+	hub.Publish(2, "check_out", nil)
+	hub.Publish(3, "check_in", nil)
+
+	subscription, missed := hub.Subscribe(1, func(event Event) bool {
+		return event.Type == "check_in"
+	})
+	defer hub.Unsubscribe(subscription)
+
+	if got := ids(missed); !reflect.DeepEqual(got, []uint64{3}) {
+		t.Errorf("missed = %v, want [3]", got)
+	}
+}
@@ -0,0 +1,17 @@
+package rest
+
+import (
+	"go-rest-api/src/model"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// Actor describes who makes the request for the audit log, accountID is 0 for an
+// anonymous request
+func Actor(context echo.Context, accountID int) model.Actor {
+	return model.Actor{
+		AccountID: accountID,
+		RequestID: context.Response().Header().Get(echo.HeaderXRequestID),
+		ClientIP:  context.RealIP(),
+	}
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-rest-api/src/pkg/apperror"
+	"go-rest-api/src/pkg/logging"
+
+	"github.com/go-playground/validator/v10"
+	echo "github.com/labstack/echo/v4"
+)
+
+// ErrInvalidRequest answers a request body that failed validation, the detail has the
+// failed tag of every field
+var ErrInvalidRequest = apperror.New("invalid_request", http.StatusBadRequest, "", "request is invalid")
+
+// HTTPErrorHandler answers the errors handlers return, set it as the echo
+// HTTPErrorHandler. An *apperror.Error is answered with its status and a detail for
+// its field, validation errors with the failed tag of every field and echo errors,
+// such as an unknown route, with their status. Anything else is logged and answered
+// with 500 without showing the cause. Clients that accept application/problem+json
+// get problem details instead, see ResponseProblem.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	appErr, detail := Error(err)
+	logger := logging.FromContext(ctx.Request().Context())
+	if appErr.Status >= http.StatusInternalServerError {
+		logger.Error("request failed", "error", err)
+	} else {
+		logger.Debug("error response", "status", appErr.Status, "code", appErr.Code, "error", err)
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		ctx.NoContent(appErr.Status)
+		return
+	}
+	ctx.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if WantsProblem(ctx) {
+		ResponseProblem(ctx, err)
+		return
+	}
+	ctx.JSON(appErr.Status, Response{
+		Error:   errorID(ctx),
+		Code:    appErr.Code,
+		Message: http.StatusText(appErr.Status),
+		Detail:  detail,
+	})
+}
+
+// Error returns how err is answered, the detail maps request fields to what is wrong
+// with them
+func Error(err error) (appErr *apperror.Error, detail map[string]string) {
+	var validationErrs validator.ValidationErrors
+	var httpErr *echo.HTTPError
+	if errors.As(err, &appErr) {
+		if appErr.Field != "" {
+			detail = map[string]string{appErr.Field: appErr.Message}
+		}
+		return
+	} else if errors.As(err, &validationErrs) {
+		detail = map[string]string{}
+		for _, fieldErr := range validationErrs {
+			detail[strings.ToLower(fieldErr.Field())] = fieldErr.Tag()
+		}
+		return ErrInvalidRequest, detail
+	} else if errors.As(err, &httpErr) {
+		code := strings.ReplaceAll(strings.ToLower(http.StatusText(httpErr.Code)), " ", "_")
+		return apperror.New(code, httpErr.Code, "", fmt.Sprint(httpErr.Message)), nil
+	}
+	return apperror.Internal, nil
+}
@@ -0,0 +1,312 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-rest-api/src/constant"
+	"go-rest-api/src/pkg/apperror"
+
+	"github.com/go-playground/validator/v10"
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+type validated struct {
+	Name  string `validate:"required"`
+	Email string `validate:"max=5"`
+}
+
+func validationError(t *testing.T) error {
+	err := validator.New().Struct(validated{Email: "someone@example.com"})
+	if err == nil {
+		t.Fatal("validation passed")
+	}
+	return err
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		detail  map[string]string
+	}{
+		{
+			name:    "bad request",
+			err:     constant.ErrInvalidPassword,
+			status:  http.StatusBadRequest,
+			code:    "invalid_password",
+			message: "invalid password",
+			detail:  map[string]string{"password": "invalid password"},
+		},
+		{
+			name:    "unauthorized without a field",
+			err:     constant.ErrUnauthorized,
+			status:  http.StatusUnauthorized,
+			code:    "unauthorized",
+			message: "invalid or missing bearer token",
+		},
+		{
+			name:    "forbidden",
+			err:     constant.ErrDeviceNotBound,
+			status:  http.StatusForbidden,
+			code:    "device_not_bound",
+			message: "attendance must be submitted from a bound device",
+			detail:  map[string]string{"device": "attendance must be submitted from a bound device"},
+		},
+		{
+			name:    "not found",
+			err:     constant.ErrLocationNotExist,
+			status:  http.StatusNotFound,
+			code:    "location_not_exist",
+			message: "location is not exist",
+			detail:  map[string]string{"location_id": "location is not exist"},
+		},
+		{
+			name:    "conflict",
+			err:     constant.ErrEmailAlreadyExist,
+			status:  http.StatusConflict,
+			code:    "email_already_exist",
+			message: "email already exist",
+			detail:  map[string]string{"email": "email already exist"},
+		},
+		{
+			name:    "gone",
+			err:     constant.ErrInvitationExpired,
+			status:  http.StatusGone,
+			code:    "invitation_expired",
+			message: "invitation has expired",
+			detail:  map[string]string{"token": "invitation has expired"},
+		},
+		{
+			name:    "too large",
+			err:     constant.ErrSignedBodyTooLarge,
+			status:  http.StatusRequestEntityTooLarge,
+			code:    "signed_body_too_large",
+			message: "signed request body is too large, maximum size is 6 MB",
+			detail:  map[string]string{"body": "signed request body is too large, maximum size is 6 MB"},
+		},
+		{
+			name:    "unprocessable",
+			err:     constant.ErrImportRowsInvalid,
+			status:  http.StatusUnprocessableEntity,
+			code:    "import_rows_invalid",
+			message: "some rows are invalid, nothing was imported",
+			detail:  map[string]string{"file": "some rows are invalid, nothing was imported"},
+		},
+		{
+			name:    "copy about another field",
+			err:     constant.ErrAccountNotRegistered.WithField("username"),
+			status:  http.StatusNotFound,
+			code:    "account_not_registered",
+			message: "account not registered",
+			detail:  map[string]string{"username": "account not registered"},
+		},
+		{
+			name:    "wrapped",
+			err:     errors.Wrap(errors.Wrap(constant.ErrDeviceAlreadyRegistered, "register device"), "handler"),
+			status:  http.StatusConflict,
+			code:    "device_already_registered",
+			message: "device is already registered, it must be revoked before registering it again",
+			detail:  map[string]string{"device_id": "device is already registered, it must be revoked before registering it again"},
+		},
+		{
+			name:    "wrapped with fmt",
+			err:     fmt.Errorf("take location: %w", constant.ErrLocationNotExist),
+			status:  http.StatusNotFound,
+			code:    "location_not_exist",
+			message: "location is not exist",
+			detail:  map[string]string{"location_id": "location is not exist"},
+		},
+		{
+			name:    "echo error",
+			err:     echo.ErrNotFound,
+			status:  http.StatusNotFound,
+			code:    "not_found",
+			message: "Not Found",
+		},
+		{
+			name:    "echo error with a message",
+			err:     echo.NewHTTPError(http.StatusMethodNotAllowed, "method not allowed here"),
+			status:  http.StatusMethodNotAllowed,
+			code:    "method_not_allowed",
+			message: "method not allowed here",
+		},
+		{
+			name:    "validation",
+			err:     validationError(t),
+			status:  http.StatusBadRequest,
+			code:    "invalid_request",
+			message: "request is invalid",
+			detail:  map[string]string{"name": "required", "email": "max"},
+		},
+		{
+			name:    "wrapped validation",
+			err:     errors.Wrap(validationError(t), "validate struct"),
+			status:  http.StatusBadRequest,
+			code:    "invalid_request",
+			message: "request is invalid",
+			detail:  map[string]string{"name": "required", "email": "max"},
+		},
+		{
+			name:    "unknown",
+			err:     errors.New("pq: password authentication failed for user \"postgres\""),
+			status:  http.StatusInternalServerError,
+			code:    "internal",
+			message: "Internal Server Error",
+		},
+		{
+			name:    "wrapped unknown",
+			err:     errors.Wrap(errors.New("dial tcp 10.0.0.5:5432: connection refused"), "take account"),
+			status:  http.StatusInternalServerError,
+			code:    "internal",
+			message: "Internal Server Error",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appErr, detail := Error(test.err)
+			if appErr.Status != test.status || appErr.Code != test.code || appErr.Message != test.message {
+				t.Errorf("error = %d %s %q, want %d %s %q", appErr.Status, appErr.Code, appErr.Message, test.status, test.code, test.message)
+			}
+			if !reflect.DeepEqual(detail, test.detail) {
+				t.Errorf("detail = %v, want %v", detail, test.detail)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		status int
+		body   Response
+	}{
+		{
+			name:   "app error",
+			method: http.MethodPost,
+			err:    errors.Wrap(constant.ErrEmailAlreadyExist, "create invitation"),
+			status: http.StatusConflict,
+			body: Response{
+				Error:   "request-1",
+				Code:    "email_already_exist",
+				Message: "Conflict",
+				Detail:  map[string]string{"email": "email already exist"},
+			},
+		},
+		{
+			name:   "validation",
+			method: http.MethodPost,
+			err:    validationError(t),
+			status: http.StatusBadRequest,
+			body: Response{
+				Error:   "request-1",
+				Code:    "invalid_request",
+				Message: "Bad Request",
+				Detail:  map[string]string{"name": "required", "email": "max"},
+			},
+		},
+		{
+			name:   "echo error",
+			method: http.MethodGet,
+			err:    echo.ErrNotFound,
+			status: http.StatusNotFound,
+			body: Response{
+				Error:   "request-1",
+				Code:    "not_found",
+				Message: "Not Found",
+			},
+		},
+		{
+			name:   "unknown",
+			method: http.MethodGet,
+			err:    errors.Wrap(errors.New("pq: relation \"accounts\" does not exist"), "find accounts"),
+			status: http.StatusInternalServerError,
+			body: Response{
+				Error:   "request-1",
+				Code:    "internal",
+				Message: "Internal Server Error",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, rec := newErrorContext(test.method, "")
+			HTTPErrorHandler(test.err, ctx)
+
+			if rec.Code != test.status {
+				t.Errorf("status = %d, want %d", rec.Code, test.status)
+			}
+			if contentType := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(contentType, echo.MIMEApplicationJSON) {
+				t.Errorf("content type = %q, want %s", contentType, echo.MIMEApplicationJSON)
+			}
+			var body Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(body, test.body) {
+				t.Errorf("body = %+v, want %+v", body, test.body)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerHidesInternalErrors(t *testing.T) {
+	cause := "pq: password authentication failed for user \"postgres\""
+	for _, accept := range []string{"", MIMEApplicationProblemJSON} {
+		ctx, rec := newErrorContext(http.MethodGet, accept)
+		HTTPErrorHandler(errors.Wrap(errors.New(cause), "take account"), ctx)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("accept %q: status = %d, want 500", accept, rec.Code)
+		}
+		for _, leaked := range []string{"pq:", "postgres", "take account"} {
+			if strings.Contains(rec.Body.String(), leaked) {
+				t.Errorf("accept %q: body %s shows %q", accept, rec.Body.String(), leaked)
+			}
+		}
+	}
+}
+
+func TestHTTPErrorHandlerHead(t *testing.T) {
+	ctx, rec := newErrorContext(http.MethodHead, "")
+	HTTPErrorHandler(constant.ErrLocationNotExist, ctx)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want none", rec.Body.String())
+	}
+}
+
+func TestHTTPErrorHandlerCommitted(t *testing.T) {
+	ctx, rec := newErrorContext(http.MethodGet, "")
+	ctx.Response().WriteHeader(http.StatusOK)
+	HTTPErrorHandler(apperror.Internal, ctx)
+
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("committed response was written to: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// newErrorContext is a request to /v1/accounts/42 whose request id is request-1
+func newErrorContext(method string, accept string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/v1/accounts/42", nil)
+	if accept != "" {
+		req.Header.Set(echo.HeaderAccept, accept)
+	}
+	rec := httptest.NewRecorder()
+	rec.Header().Set(echo.HeaderXRequestID, "request-1")
+	return echo.New().NewContext(req, rec), rec
+}
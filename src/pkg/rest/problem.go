@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	echo "github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the media type of RFC 7807 problem details
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+var problemTypeURL string
+
+// SetProblemTypeURL sets the URL the code of an error is appended to for the type of
+// its problem details, without it the type is about:blank
+func SetProblemTypeURL(url string) {
+	problemTypeURL = strings.TrimSuffix(url, "/")
+}
+
+// Problem is the RFC 7807 problem details of an error response. The code and error id
+// are the ones Response has, errors maps request fields to what is wrong with them
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	ErrorID  string            `json:"error_id,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// WantsProblem is true when the client accepts application/problem+json by name and
+// does not prefer application/json to it, the error responses keep the Response
+// format otherwise
+func WantsProblem(ctx echo.Context) bool {
+	accept := ctx.Request().Header.Get(echo.HeaderAccept)
+	problem := quality(accept, MIMEApplicationProblemJSON, false)
+	return problem > 0 && problem >= quality(accept, echo.MIMEApplicationJSON, true)
+}
+
+// quality is the q value the most specific media range of accept gives mediaType, 0
+// when no range matches it. Wildcard ranges only count when wildcards is set, a range
+// that does not parse is skipped.
+func quality(accept string, mediaType string, wildcards bool) (q float64) {
+	mainType := strings.SplitN(mediaType, "/", 2)[0]
+	matched := -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		name, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		specificity := -1
+		switch {
+		case name == mediaType:
+			specificity = 2
+		case wildcards && name == mainType+"/*":
+			specificity = 1
+		case wildcards && name == "*/*":
+			specificity = 0
+		}
+		if specificity < 0 || specificity < matched {
+			continue
+		}
+
+		rangeQ := 1.0
+		if value, ok := params["q"]; ok {
+			rangeQ, err = strconv.ParseFloat(value, 64)
+			if err != nil || rangeQ < 0 || rangeQ > 1 {
+				continue
+			}
+		}
+		if specificity > matched || rangeQ > q {
+			q = rangeQ
+		}
+		matched = specificity
+	}
+	return
+}
+
+// ResponseProblem writes err as problem details
+func ResponseProblem(ctx echo.Context, err error) {
+	appErr, _ := Error(err)
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(appErr.Status),
+		Status:   appErr.Status,
+		Detail:   appErr.Message,
+		Instance: ctx.Request().URL.Path,
+		Code:     appErr.Code,
+		ErrorID:  errorID(ctx),
+	}
+	// echo errors only have the status text, which is already the title
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+	if problemTypeURL != "" {
+		problem.Type = problemTypeURL + "/" + appErr.Code
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		problem.Errors = map[string]string{}
+		for _, fieldErr := range validationErrs {
+			problem.Errors[strings.ToLower(fieldErr.Field())] = validationMessage(fieldErr)
+		}
+	} else if appErr.Field != "" {
+		problem.Errors = map[string]string{appErr.Field: appErr.Message}
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	ctx.JSON(problem.Status, problem)
+}
+
+// validationMessage tells what the failed tag of fieldErr asks for, the tags the
+// entities do not use fall back to naming the tag
+func validationMessage(fieldErr validator.FieldError) string {
+	unit := ""
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		unit = " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required", "required_if", "required_with":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fieldErr.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fieldErr.Param(), unit)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid url"
+	case "uuid":
+		return "must be a valid uuid"
+	case "datetime":
+		return "must be a date in the format " + fieldErr.Param()
+	case "phone":
+		return "must be a valid phone number"
+	case "nik":
+		return "must be a valid ktp number"
+	case "nik_birth_date":
+		return "must match the date of birth"
+	case "nik_gender":
+		return "must match the gender"
+	}
+	return "failed on the " + fieldErr.Tag() + " rule"
+}
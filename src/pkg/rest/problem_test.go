@@ -0,0 +1,194 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"go-rest-api/src/constant"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+func TestWantsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "application/json", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/*", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "Application/Problem+JSON", want: true},
+		{accept: "application/problem+json; charset=utf-8", want: true},
+		{accept: "application/json, application/problem+json", want: true},
+		{accept: "application/problem+json, */*;q=0.1", want: true},
+		{accept: "application/problem+json;q=0", want: false},
+		{accept: "application/problem+json; q=0.0", want: false},
+		{accept: "application/problem+json;q=0.5, application/json", want: false},
+		{accept: "application/problem+json;q=0.5, application/*", want: false},
+		{accept: "application/problem+json;q=0.5, application/json;q=0.4, */*", want: true},
+		{accept: "application/problem+json;q=0.9, application/json;q=0.9", want: true},
+		{accept: "application/json;q=0, application/problem+json;q=0.1", want: true},
+		{accept: "application/problem+json;q=abc", want: false},
+		{accept: "application/problem+json;q=2", want: false},
+		{accept: "application/problem+jsonx", want: false},
+		{accept: "text/html, application/problem+json;q=0.9", want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			ctx, _ := newErrorContext(http.MethodGet, test.accept)
+			if got := WantsProblem(ctx); got != test.want {
+				t.Errorf("WantsProblem(%q) = %v, want %v", test.accept, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerProblem(t *testing.T) {
+	tests := []struct {
+		name    string
+		typeURL string
+		err     error
+		want    Problem
+	}{
+		{
+			name: "app error",
+			err:  errors.Wrap(constant.ErrLocationNotExist, "take location"),
+			want: Problem{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "location is not exist",
+				Instance: "/v1/accounts/42",
+				Code:     "location_not_exist",
+				ErrorID:  "request-1",
+				Errors:   map[string]string{"location_id": "location is not exist"},
+			},
+		},
+		{
+			name:    "app error with a type url",
+			typeURL: "https://docs.example.com/errors/",
+			err:     constant.ErrEmailAlreadyExist,
+			want: Problem{
+				Type:     "https://docs.example.com/errors/email_already_exist",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "email already exist",
+				Instance: "/v1/accounts/42",
+				Code:     "email_already_exist",
+				ErrorID:  "request-1",
+				Errors:   map[string]string{"email": "email already exist"},
+			},
+		},
+		{
+			name: "app error without a field",
+			err:  constant.ErrPermissionDenied,
+			want: Problem{
+				Type:     "about:blank",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "permission denied",
+				Instance: "/v1/accounts/42",
+				Code:     "permission_denied",
+				ErrorID:  "request-1",
+			},
+		},
+		{
+			name: "validation",
+			err:  validationError(t),
+			want: Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request is invalid",
+				Instance: "/v1/accounts/42",
+				Code:     "invalid_request",
+				ErrorID:  "request-1",
+				Errors:   map[string]string{"name": "is required", "email": "must be at most 5 characters"},
+			},
+		},
+		{
+			name: "echo error",
+			err:  echo.ErrNotFound,
+			want: Problem{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Instance: "/v1/accounts/42",
+				Code:     "not_found",
+				ErrorID:  "request-1",
+			},
+		},
+		{
+			name: "unknown",
+			err:  errors.New("pq: deadlock detected"),
+			want: Problem{
+				Type:     "about:blank",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Instance: "/v1/accounts/42",
+				Code:     "internal",
+				ErrorID:  "request-1",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetProblemTypeURL(test.typeURL)
+			defer SetProblemTypeURL("")
+
+			ctx, rec := newErrorContext(http.MethodGet, MIMEApplicationProblemJSON)
+			HTTPErrorHandler(test.err, ctx)
+
+			if rec.Code != test.want.Status {
+				t.Errorf("status = %d, want %d", rec.Code, test.want.Status)
+			}
+			if contentType := rec.Header().Get(echo.HeaderContentType); contentType != MIMEApplicationProblemJSON {
+				t.Errorf("content type = %q, want %s", contentType, MIMEApplicationProblemJSON)
+			}
+			if vary := rec.Header().Get(echo.HeaderVary); vary != echo.HeaderAccept {
+				t.Errorf("vary = %q, want %s", vary, echo.HeaderAccept)
+			}
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(problem, test.want) {
+				t.Errorf("problem = %+v, want %+v", problem, test.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerJSON(t *testing.T) {
+	for _, accept := range []string{"", echo.MIMEApplicationJSON, "*/*", MIMEApplicationProblemJSON + ";q=0"} {
+		t.Run(accept, func(t *testing.T) {
+			ctx, rec := newErrorContext(http.MethodGet, accept)
+			HTTPErrorHandler(constant.ErrLocationNotExist, ctx)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want 404", rec.Code)
+			}
+			if contentType := rec.Header().Get(echo.HeaderContentType); contentType != echo.MIMEApplicationJSONCharsetUTF8 {
+				t.Errorf("content type = %q, want %s", contentType, echo.MIMEApplicationJSONCharsetUTF8)
+			}
+			var body Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			want := Response{
+				Error:   "request-1",
+				Code:    "location_not_exist",
+				Message: "Not Found",
+				Detail:  map[string]string{"location_id": "location is not exist"},
+			}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("body = %+v, want %+v", body, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// MaxRequestIDLength is the size of audit_logs.request_id
+const MaxRequestIDLength = 100
+
+// RequestID keeps the X-Request-ID of the client so its logs can be matched with
+// ours, an id that is empty, too long for the audit log or has other characters than
+// letters, digits and -_.: is replaced with a generated one
+func RequestID() echo.MiddlewareFunc {
+	requestID := middleware.RequestIDWithConfig(middleware.RequestIDConfig{})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		handler := requestID(next)
+		return func(ctx echo.Context) error {
+			header := ctx.Request().Header
+			if !validRequestID(header.Get(echo.HeaderXRequestID)) {
+				header.Del(echo.HeaderXRequestID)
+			}
+			return handler(ctx)
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for _, char := range id {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case char == '-', char == '_', char == '.', char == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IPExtractor takes the client ip from X-Forwarded-For when the request comes from
+// one of trustedProxies, the address of the connection is the client ip otherwise
+// and when there are none
+func IPExtractor(trustedProxies []string) (extractor echo.IPExtractor, err error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		kept bool
+	}{
+		{name: "uuid", id: "9b2f6c1e-2d4a-4f0e-9a57-3c1d2b8e7f60", kept: true},
+		{name: "dotted", id: "lb-1.trace:42_a", kept: true},
+		{name: "longest", id: strings.Repeat("a", MaxRequestIDLength), kept: true},
+		{name: "missing", id: ""},
+		{name: "too long", id: strings.Repeat("a", MaxRequestIDLength+1)},
+		{name: "spaces", id: "drop table"},
+		{name: "newline", id: "abc\ninjected=1"},
+		{name: "quote", id: `abc"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderXRequestID, test.id)
+			rec := httptest.NewRecorder()
+
+			handler := RequestID()(func(ctx echo.Context) error { return nil })
+			if err := handler(e.NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rec.Header().Get(echo.HeaderXRequestID)
+			if test.kept && got != test.id {
+				t.Errorf("request id = %q, want %q", got, test.id)
+			}
+			if !test.kept && (got == test.id || !validRequestID(got)) {
+				t.Errorf("request id = %q, want a generated one", got)
+			}
+		})
+	}
+}
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "no proxies ignores the header", remoteAddr: "203.0.113.7:4000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "no proxies ignores private peers", remoteAddr: "10.0.0.2:4000", forwarded: "198.51.100.1", want: "10.0.0.2"},
+		{name: "trusted proxy", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.2:4000", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "spoofed hop before the proxy", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.2:4000", forwarded: "1.2.3.4, 198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:4000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "untrusted loopback", proxies: []string{"10.0.0.0/8"}, remoteAddr: "127.0.0.1:4000", forwarded: "198.51.100.1", want: "127.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extractor, err := IPExtractor(test.proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, test.forwarded)
+
+			if got := extractor(req); got != test.want {
+				t.Errorf("ip = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := IPExtractor([]string{"10.0.0.1"}); err == nil {
+		t.Error("an ip without a prefix length was accepted")
+	}
+}
@@ -2,13 +2,16 @@ package rest
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"strings"
 
+	"go-rest-api/src/pkg/logging"
+
 	echo "github.com/labstack/echo/v4"
 	"github.com/forkyid/go-utils/v1/pagination"
 	uuid "github.com/forkyid/go-utils/v1/uuid"
@@ -20,6 +23,7 @@ import (
 type Response struct {
 	Result  interface{}       `json:"result,omitempty"`
 	Error   string            `json:"error,omitempty"`
+	Code    string            `json:"code,omitempty"`
 	Message string            `json:"message,omitempty"`
 	Detail  map[string]string `json:"detail,omitempty"`
 	Status  int               `json:"status,omitempty"`
@@ -62,8 +66,7 @@ func (details *ErrorDetails) Add(key, val string) {
 
 func ResponseData(context echo.Context, status int, payload interface{}, msg ...string) ResponseResult {
 	if len(msg) > 1 {
-		log.Println("response cannot contain more than one message")
-		log.Println("proceeding with first message only...")
+		logging.FromContext(context.Request().Context()).Warn("response cannot contain more than one message, proceeding with first message only")
 	}
 	if len(msg) == 0 {
 		msg = []string{http.StatusText(status)}
@@ -87,13 +90,13 @@ func ResponsePagination(context echo.Context, status int, params ResponsePaginat
 	msg := http.StatusText(status)
 
 	if params.Pagination == nil {
-		log.Println("proceeding with default pagination value")
+		logging.FromContext(context.Request().Context()).Debug("proceeding with default pagination value")
 		params.Pagination = &pagination.Pagination{}
 		params.Pagination.Paginate()
 	}
 
 	if params.TotalData == 0 {
-		log.Println("proceeding with 0 total_data...")
+		logging.FromContext(context.Request().Context()).Debug("proceeding with 0 total_data")
 	}
 
 	response := Response{
@@ -101,7 +104,7 @@ func ResponsePagination(context echo.Context, status int, params ResponsePaginat
 			Data:      params.Data,
 			TotalData: params.TotalData,
 			Page:      params.Pagination.Page,
-			TotalPage: params.TotalData / params.Pagination.Limit,
+			TotalPage: (params.TotalData + params.Pagination.Limit - 1) / params.Pagination.Limit,
 		},
 		Message: msg,
 	}
@@ -116,20 +119,18 @@ func ResponsePagination(context echo.Context, status int, params ResponsePaginat
 // msg: string
 func ResponseMessage(context echo.Context, status int, msg ...string) ResponseResult {
 	if len(msg) > 1 {
-		log.Println("response cannot contain more than one message")
-		log.Println("proceeding with first message only...")
+		logging.FromContext(context.Request().Context()).Warn("response cannot contain more than one message, proceeding with first message only")
 	}
 	if len(msg) == 0 {
 		msg = []string{http.StatusText(status)}
-	} else if status < 200 || status > 299 {
-		log.Println("[GOUTILS-debug]", msg[0])
 	}
 
 	response := Response{
 		Message: msg[0],
 	}
 	if status < 200 || status > 299 {
-		response.Error = uuid.GetUUID()
+		response.Error = errorID(context)
+		logging.FromContext(context.Request().Context()).Debug("error response", "status", status, "message", response.Message)
 	}
 
 	context.JSON(status, response)
@@ -138,15 +139,14 @@ func ResponseMessage(context echo.Context, status int, msg ...string) ResponseRe
 
 func ResponseError(context echo.Context, status int, detail interface{}, msg ...string) ResponseResult {
 	if len(msg) > 1 {
-		log.Println("response cannot contain more than one message")
-		log.Println("proceeding with first message only...")
+		logging.FromContext(context.Request().Context()).Warn("response cannot contain more than one message, proceeding with first message only")
 	}
 	if len(msg) == 0 {
 		msg = []string{http.StatusText(status)}
 	}
 
 	response := Response{
-		Error:   uuid.GetUUID(),
+		Error:   errorID(context),
 		Message: msg[0],
 	}
 
@@ -164,12 +164,37 @@ func ResponseError(context echo.Context, status int, detail interface{}, msg ...
 		response.Detail["error"] = det
 	}
 
-	log.Printf("[GOUTILS-debug] %+v\n", response)
+	logging.FromContext(context.Request().Context()).Debug("error response", "status", status, "message", response.Message, "detail", response.Detail)
 
 	context.JSON(status, response)
 	return ResponseResult{context, response.Error}
 }
 
+// errorID is the id clients report for an error response, the request id so the
+// response can be found in the logs
+func errorID(context echo.Context) string {
+	if requestID := logging.RequestID(context); requestID != "" {
+		return requestID
+	}
+	return uuid.GetUUID()
+}
+
+// ResponseCSV writes rows as a downloadable csv attachment
+func ResponseCSV(context echo.Context, filename string, header []string, rows [][]string) error {
+	context.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	context.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	context.Response().WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(context.Response())
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
 // MultipartForm creates multipart payload
 func MultipartForm(fileKey string, files [][]byte, params map[string]string, multiParams map[string][]string) (io.Reader, string) {
 	body := new(bytes.Buffer)
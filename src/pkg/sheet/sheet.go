@@ -0,0 +1,191 @@
+package sheet
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maximumPartSize caps how much of a single xlsx part is read, xlsx files are zip
+// archives and a small upload can expand into a very large xml document
+const maximumPartSize = 32 << 20
+
+var ErrUnsupportedFormat = errors.New("unsupported spreadsheet format")
+
+// Read returns the cells of a csv file, or of the first worksheet of a xlsx file,
+// the format is picked from the file name extension
+func Read(file io.ReaderAt, size int64, filename string) (rows [][]string, err error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		reader := csv.NewReader(io.NewSectionReader(file, 0, size))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return reader.ReadAll()
+	case ".xlsx":
+		return readXLSX(file, size)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ParseSerialDate converts a spreadsheet date serial number, which is what xlsx stores
+// for cells formatted as a date, to a time
+func ParseSerialDate(value string) (date time.Time, ok bool) {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 1 {
+		return
+	}
+	epoch := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	return epoch.AddDate(0, 0, int(serial)), true
+}
+
+type workbook struct {
+	Sheets []struct {
+		RelationID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type sharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type worksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Reference string `xml:"r,attr"`
+			Type      string `xml:"t,attr"`
+			Value     string `xml:"v"`
+			Inline    string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(file io.ReaderAt, size int64) (rows [][]string, err error) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	parts := map[string]*zip.File{}
+	for _, part := range archive.File {
+		parts[part.Name] = part
+	}
+
+	book := workbook{}
+	if err = decodePart(parts, "xl/workbook.xml", &book); err != nil {
+		return
+	}
+	if len(book.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	relations := relationships{}
+	if err = decodePart(parts, "xl/_rels/workbook.xml.rels", &relations); err != nil {
+		return
+	}
+	sheetPath := ""
+	for _, relation := range relations.Relationships {
+		if relation.ID == book.Sheets[0].RelationID {
+			sheetPath = relation.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+
+	shared := sharedStrings{}
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err = decodePart(parts, "xl/sharedStrings.xml", &shared); err != nil {
+			return
+		}
+	}
+	texts := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		texts[i] = item.Text
+		for _, run := range item.Runs {
+			texts[i] += run.Text
+		}
+	}
+
+	sheet := worksheet{}
+	if err = decodePart(parts, sheetPath, &sheet); err != nil {
+		return
+	}
+	for _, row := range sheet.Rows {
+		cells := []string{}
+		for i, cell := range row.Cells {
+			column := columnIndex(cell.Reference)
+			if column < 0 {
+				column = i
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(value)
+				if err != nil || index < 0 || index >= len(texts) {
+					return nil, fmt.Errorf("invalid shared string in cell %s", cell.Reference)
+				}
+				value = texts[index]
+			case "inlineStr":
+				value = cell.Inline
+			}
+			cells[column] = strings.TrimSpace(value)
+		}
+		rows = append(rows, cells)
+	}
+	return
+}
+
+func decodePart(parts map[string]*zip.File, name string, v interface{}) (err error) {
+	part, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("xlsx part %s is missing", name)
+	}
+	reader, err := part.Open()
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	return xml.NewDecoder(io.LimitReader(reader, maximumPartSize)).Decode(v)
+}
+
+// columnIndex turns the letters of a cell reference like "AB12" into a zero based index
+func columnIndex(reference string) (index int) {
+	index = 0
+	letters := 0
+	for _, r := range reference {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return index - 1
+}
@@ -0,0 +1,45 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Message builds the payload a device signs: device id, unix timestamp, request method,
+// request path and the hex encoded sha256 of the request body, separated by new lines
+func Message(deviceID, timestamp, method, path string, body []byte) []byte {
+	hashedBody := sha256.Sum256(body)
+	return []byte(deviceID + "\n" + timestamp + "\n" + method + "\n" + path + "\n" + hex.EncodeToString(hashedBody[:]))
+}
+
+// ParsePublicKey decodes a base64 encoded ed25519 public key
+func ParsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Verify checks a base64 encoded ed25519 signature of message. The encoding is strict,
+// a signature only has one accepted spelling so a used one can be recognized
+func Verify(publicKey, signature string, message []byte) error {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.Strict().DecodeString(signature)
+	if err != nil || !ed25519.Verify(key, message, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
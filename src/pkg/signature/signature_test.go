@@ -0,0 +1,61 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey)
+
+	body := []byte(`{"location_id":1}`)
+	signed := Message("phone-1", "1700000000", http.MethodPost, "/v1/attendances", body)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signed))
+
+	// the last character before the padding carries four unused bits, setting one
+	// spells the same signature differently
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	sigBytes := []byte(sig)
+	last := strings.IndexByte(alphabet, sigBytes[len(sigBytes)-3])
+	sigBytes[len(sigBytes)-3] = alphabet[last^1]
+	if decoded, _ := base64.StdEncoding.DecodeString(string(sigBytes)); string(decoded) != string(ed25519.Sign(privateKey, signed)) {
+		t.Fatal("altered signature does not decode to the same bytes")
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		message   []byte
+		wantErr   error
+	}{
+		{name: "signed request", signature: sig, message: signed},
+		{name: "other method", signature: sig, message: Message("phone-1", "1700000000", http.MethodPut, "/v1/attendances", body), wantErr: ErrInvalidSignature},
+		{name: "other path", signature: sig, message: Message("phone-1", "1700000000", http.MethodPost, "/v1/attendances/batch", body), wantErr: ErrInvalidSignature},
+		{name: "other timestamp", signature: sig, message: Message("phone-1", "1700000001", http.MethodPost, "/v1/attendances", body), wantErr: ErrInvalidSignature},
+		{name: "other body", signature: sig, message: Message("phone-1", "1700000000", http.MethodPost, "/v1/attendances", []byte(`{"location_id":2}`)), wantErr: ErrInvalidSignature},
+		{name: "non canonical encoding", signature: string(sigBytes), message: signed, wantErr: ErrInvalidSignature},
+		{name: "not base64", signature: "not a signature", message: signed, wantErr: ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(encodedKey, test.signature, test.message)
+			if err != test.wantErr {
+				t.Errorf("Verify() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString(make([]byte, 16))); err != ErrInvalidPublicKey {
+		t.Errorf("ParsePublicKey() = %v, want %v", err, ErrInvalidPublicKey)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files below a base directory, meant for development
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) *Local {
+	return &Local{
+		dir: dir,
+	}
+}
+
+func (l *Local) path(key string) string {
+	// keys are slash separated, strip anything that could escape the base directory
+	clean := filepath.Clean("/" + strings.TrimLeft(key, "/"))
+	return filepath.Join(l.dir, filepath.FromSlash(clean))
+}
+
+func (l *Local) Put(key string, body io.Reader, size int64, contentType string) (err error) {
+	path := l.path(key)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, body)
+	if err != nil {
+		os.Remove(path)
+	}
+	return
+}
+
+func (l *Local) Get(key string) (body io.ReadCloser, contentType string, err error) {
+	file, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		err = ErrObjectNotExist
+		return
+	} else if err != nil {
+		return
+	}
+
+	contentType = mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return file, contentType, nil
+}
+
+func (l *Local) Delete(key string) (err error) {
+	err = os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"go-rest-api/src/config"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var ErrObjectNotExist = errors.New("object is not exist")
+
+// Storage stores binary objects under a flat key namespace
+type Storage interface {
+	Put(key string, body io.Reader, size int64, contentType string) (err error)
+	Get(key string) (body io.ReadCloser, contentType string, err error)
+	Delete(key string) (err error)
+}
+
+// New builds the storage selected by the storage driver, defaults to local
+func New(cfg config.Storage) (Storage, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		dir := cfg.LocalDir
+		if dir == "" {
+			dir = "storage"
+		}
+		return NewLocal(dir), nil
+	case DriverS3:
+		return NewS3(S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
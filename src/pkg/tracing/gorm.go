@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"errors"
+
+	"go-rest-api/src/pkg/gormhook"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin opens a span for every statement below the span of the statement
+// context, register it with db.Use
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) (err error) {
+	return gormhook.Register(db, "tracing", startStatement, endStatement)
+}
+
+func startStatement(db *gorm.DB, operation string) {
+	_, span := otel.Tracer(instrumentationName).Start(db.Statement.Context, "gorm."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(operation),
+		),
+	)
+	db.InstanceSet(spanKey, span)
+}
+
+func endStatement(db *gorm.DB, operation string) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	// the statement has placeholders, the values are not recorded
+	span.SetAttributes(
+		semconv.DBSQLTableKey.String(db.Statement.Table),
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
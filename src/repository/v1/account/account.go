@@ -1,9 +1,19 @@
 package account
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"go-rest-api/src/connection"
 	"go-rest-api/src/constant"
 	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/envelope"
+	"go-rest-api/src/pkg/identity"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/repository/v1/outbox"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -25,66 +35,143 @@ func NewRepository(
 }
 
 type Repositorier interface {
-	TakeAccountByID(accountID int) (account model.Account, err error)
-	TakeAccountByEmail(email string) (account model.Account, err error)
-	TakeAccountByKTPNumber(ktpNumber string) (account model.Account, err error)
-	TakeAccountByPhoneNumber(phoneNumber string) (account model.Account, err error)
-	TakeAccountByUsername(username string) (account model.Account, err error)
-	Find(accountIDs []int) (accounts []model.Account, err error)
-	Create(account model.Account) (err error)
-	Update(accountID int, request model.Account) (err error)
-	Delete(accountID int) (err error)
-}
-
-func (repo *Repository) TakeAccountByID(accountID int) (account model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
+	TakeAccountByID(ctx context.Context, accountID int) (account model.Account, err error)
+	TakeAccountByEmail(ctx context.Context, email string) (account model.Account, err error)
+	TakeAccountByKTPNumber(ctx context.Context, ktpNumber string) (account model.Account, err error)
+	TakeAccountByPhoneNumber(ctx context.Context, phoneNumber string) (account model.Account, err error)
+	TakeAccountByUsername(ctx context.Context, username string) (account model.Account, err error)
+	Find(ctx context.Context, accountIDs []int) (accounts []model.Account, err error)
+	FindDirectory(ctx context.Context, filter model.AccountFilter, pgn pagination.Pagination) (accounts []model.Account, total int64, err error)
+	Create(ctx context.Context, account model.Account, events outbox.Builder, audits audit.Builder) (accountID int, err error)
+	CreateInvited(ctx context.Context, account model.Account, invitationID int, events outbox.Builder, audits audit.Builder) (accountID int, err error)
+	CreateBatch(ctx context.Context, accounts []model.Account, events []outbox.Builder, audits []audit.Builder) (accountIDs []int, err error)
+	Update(ctx context.Context, accountID int, request model.Account, events outbox.Builder, audits audit.Builder) (err error)
+	UpdateRequireBoundDevice(ctx context.Context, accountID int, required bool, events outbox.Builder, audits audit.Builder) (err error)
+	UpdateReportingLine(ctx context.Context, accountID int, departmentID, teamID, managerID *int, events outbox.Builder, audits audit.Builder) (err error)
+	FindReports(ctx context.Context, managerID int, direct bool) (accounts []model.Account, err error)
+	CheckReport(ctx context.Context, managerID int, accountID int) (isReport bool, err error)
+	Delete(ctx context.Context, accountID int, events outbox.Builder, audits audit.Builder) (err error)
+	Erase(ctx context.Context, accountID int, events outbox.Builder, audits audit.Builder) (photoURL string, err error)
+	FindCiphertexts(ctx context.Context, afterID int, limit int) (accounts []model.AccountCiphertext, err error)
+	UpdateCiphertext(ctx context.Context, previous model.AccountCiphertext, columns map[string]interface{}) (updated bool, err error)
+	CountMissingBlindIndexes(ctx context.Context) (total int64, err error)
+}
+
+func (repo *Repository) TakeAccountByID(ctx context.Context, accountID int) (account model.Account, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
 		Where("id", accountID).
 		Take(&account)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) TakeAccountByEmail(email string) (account model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
+func (repo *Repository) TakeAccountByEmail(ctx context.Context, email string) (account model.Account, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
 		Where("email", email).
 		Take(&account)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) TakeAccountByKTPNumber(ktpNumber string) (account model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
-		Where("ktp_number", ktpNumber).
+// TakeAccountByKTPNumber looks the account up by the blind index, the ktp number column
+// itself is encrypted
+func (repo *Repository) TakeAccountByKTPNumber(ctx context.Context, ktpNumber string) (account model.Account, err error) {
+	index, err := blindIndex(constant.FieldKTPNumber, ktpNumber)
+	if err != nil {
+		return
+	}
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
+		Where("ktp_number_index", index).
 		Take(&account)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) TakeAccountByPhoneNumber(phoneNumber string) (account model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
-		Where("phone_number", phoneNumber).
+// TakeAccountByPhoneNumber looks the account up by the blind index, the phone number
+// column itself is encrypted
+func (repo *Repository) TakeAccountByPhoneNumber(ctx context.Context, phoneNumber string) (account model.Account, err error) {
+	index, err := blindIndex(constant.FieldPhoneNumber, phoneNumber)
+	if err != nil {
+		return
+	}
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
+		Where("phone_number_index", index).
 		Take(&account)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) TakeAccountByUsername(username string) (account model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
+func (repo *Repository) TakeAccountByUsername(ctx context.Context, username string) (account model.Account, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
 		Where("username", username).
 		Take(&account)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) Find(accountIDs []int) (accounts []model.Account, err error) {
-	query := repo.dbMaster.Model(&model.Account{}).
+func (repo *Repository) Find(ctx context.Context, accountIDs []int) (accounts []model.Account, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
 		Find(&accounts, accountIDs)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) Create(account model.Account) (err error) {
-	query := repo.dbMaster.Model(&account ).Begin().
+// directorySearch is the text matched by the directory search, kept identical to the
+// expression of the trigram index so ILIKE can use it
+const directorySearch = "(full_name || ' ' || username || ' ' || COALESCE(employee_number, ''))"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// FindDirectory pages through accounts matching the filter. Every word of the search
+// has to appear in the name, username or employee number.
+func (repo *Repository) FindDirectory(ctx context.Context, filter model.AccountFilter, pgn pagination.Pagination) (accounts []model.Account, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{})
+	switch filter.Deleted {
+	case constant.DeletedInclude:
+		query = query.Unscoped()
+	case constant.DeletedOnly:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	for _, word := range strings.Fields(filter.Search) {
+		query = query.Where(directorySearch+" ILIKE ?", "%"+likeEscaper.Replace(word)+"%")
+	}
+	if filter.DepartmentID != 0 {
+		query = query.Where("department_id", filter.DepartmentID)
+	}
+	if filter.IsVerified != nil {
+		query = query.Where("is_verified", *filter.IsVerified)
+	}
+	if filter.JobPosition != "" {
+		query = query.Where("job_position ILIKE ?", likeEscaper.Replace(filter.JobPosition))
+	}
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	order := "full_name"
+	if filter.Sort != "" {
+		order = strings.TrimPrefix(filter.Sort, "-")
+		if strings.HasPrefix(filter.Sort, "-") {
+			order += " desc"
+		}
+	}
+
+	err = query.Order(order).
+		Order("id").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&accounts).Error
+	return
+}
+
+func (repo *Repository) Create(ctx context.Context, account model.Account, events outbox.Builder, audits audit.Builder) (accountID int, err error) {
+	if err = setBlindIndexes(&account); err != nil {
+		return
+	}
+	query := repo.dbMaster.WithContext(ctx).Model(&account ).Begin().
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "username"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
@@ -100,13 +187,126 @@ func (repo *Repository) Create(account model.Account) (err error) {
 		return
 	}
 
+	accountID = int(account.ID)
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// CreateInvited registers an account and marks its invitation as accepted in the same
+// transaction, so an invitation can only be used once even by concurrent requests
+func (repo *Repository) CreateInvited(ctx context.Context, account model.Account, invitationID int, events outbox.Builder, audits audit.Builder) (accountID int, err error) {
+	if err = setBlindIndexes(&account); err != nil {
+		return
+	}
+	query := repo.dbMaster.WithContext(ctx).Model(&account).Begin().
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "username"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"full_name", "email", "password", "photo_url", "gender", "is_verified", "role",
+				"department_id", "team_id", "deleted_at",
+			})}).
+		Create(&account)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	accountID = int(account.ID)
+
+	accept := query.Session(&gorm.Session{NewDB: true}).
+		Model(&model.Invitation{}).
+		Where("id", invitationID).
+		Where("accepted_at IS NULL AND expires_at > ?", time.Now()).
+		Updates(map[string]interface{}{
+			"accepted_at": time.Now(),
+			"account_id":  accountID,
+		})
+	err = accept.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if accept.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvitationAlreadyUsed
+		return
+	}
+
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
 
-func (repo *Repository) Update(accountID int, request model.Account) (err error) {
+// CreateBatch inserts every account in one transaction, events[i] is recorded for
+// accounts[i]. Like Create, a soft deleted account with the same username is revived.
+func (repo *Repository) CreateBatch(ctx context.Context, accounts []model.Account, events []outbox.Builder, audits []audit.Builder) (accountIDs []int, err error) {
+	for i := range accounts {
+		if err = setBlindIndexes(&accounts[i]); err != nil {
+			return
+		}
+	}
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).Begin().
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "username"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"full_name", "email", "password", "address", "employee_number", "job_position",
+				"ktp_number", "ktp_number_index", "phone_number", "phone_number_index", "photo_url",
+				"gender", "date_of_birth", "is_verified",
+				"role", "deleted_at",
+			})}).
+		Create(&accounts)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	for i := range accounts {
+		accountID := int(accounts[i].ID)
+		err = outbox.Record(query, accountID, events[i])
+		if err != nil {
+			query.Rollback()
+			return
+		}
+		err = audit.Record(query, accountID, audits[i])
+		if err != nil {
+			query.Rollback()
+			return
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) Update(ctx context.Context, accountID int, request model.Account, events outbox.Builder, audits audit.Builder) (err error) {
+	if err = setBlindIndexes(&request); err != nil {
+		return
+	}
 	account := &model.Account{}
-	query := repo.dbMaster.Model(&account ).Begin().
+	query := repo.dbMaster.WithContext(ctx).Model(&account ).Begin().
 		Where("id", accountID).
 		Updates(request)
 	err = query.Error
@@ -115,13 +315,127 @@ func (repo *Repository) Update(accountID int, request model.Account) (err error)
 		return
 	}
 
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) UpdateRequireBoundDevice(ctx context.Context, accountID int, required bool, events outbox.Builder, audits audit.Builder) (err error) {
+	account := &model.Account{}
+	query := repo.dbMaster.WithContext(ctx).Model(&account).Begin().
+		Where("id", accountID).
+		Update("require_bound_device", required)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
 
-func (repo *Repository) Delete(accountID int) (err error) {
+// UpdateReportingLine writes all three columns, nil clears a column. It fails with
+// ErrReportingLineCycle when the account is above the manager. Reporting line updates
+// hold an advisory lock from the check to the commit, two updates that only make a
+// cycle together, even through other accounts, cannot both pass the check.
+func (repo *Repository) UpdateReportingLine(ctx context.Context, accountID int, departmentID, teamID, managerID *int, events outbox.Builder, audits audit.Builder) (err error) {
+	err = repo.dbMaster.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", constant.ReportingLineLockKey).Error
+		if err != nil {
+			return err
+		}
+
+		if managerID != nil {
+			isReport, err := (&Repository{dbMaster: tx}).CheckReport(ctx, accountID, *managerID)
+			if err != nil {
+				return err
+			}
+			if isReport {
+				return constant.ErrReportingLineCycle
+			}
+		}
+
+		account := &model.Account{}
+		err = tx.Model(account).
+			Where("id", accountID).
+			Updates(map[string]interface{}{
+				"department_id": departmentID,
+				"team_id":       teamID,
+				"manager_id":    managerID,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = outbox.Record(tx, accountID, events)
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, accountID, audits)
+	})
+	return
+}
+
+// reportsQuery walks the reporting lines down from a manager, UNION drops rows that
+// were already visited so the recursion also ends on a cycle
+const reportsQuery = `WITH RECURSIVE reports AS (
+	SELECT id FROM accounts WHERE manager_id = @manager AND deleted_at IS NULL
+	UNION
+	SELECT accounts.id FROM accounts
+	JOIN reports ON accounts.manager_id = reports.id
+	WHERE accounts.deleted_at IS NULL
+)`
+
+// FindReports returns the direct reports of a manager, or every account below the
+// manager in the hierarchy when direct is false
+func (repo *Repository) FindReports(ctx context.Context, managerID int, direct bool) (accounts []model.Account, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{})
+	if direct {
+		query = query.Where("manager_id", managerID)
+	} else {
+		query = query.Where("id IN (?)", repo.dbMaster.WithContext(ctx).Raw(reportsQuery+" SELECT id FROM reports",
+			map[string]interface{}{"manager": managerID}))
+	}
+	query = query.Order("full_name").
+		Find(&accounts)
+	err = query.Error
+	return
+}
+
+// CheckReport tells whether the account is below the manager in the hierarchy
+func (repo *Repository) CheckReport(ctx context.Context, managerID int, accountID int) (isReport bool, err error) {
+	query := repo.dbMaster.WithContext(ctx).Raw(reportsQuery+" SELECT EXISTS (SELECT 1 FROM reports WHERE id = @account)",
+		map[string]interface{}{"manager": managerID, "account": accountID}).
+		Scan(&isReport)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) Delete(ctx context.Context, accountID int, events outbox.Builder, audits audit.Builder) (err error) {
 	account := &model.Account{}
-	query := repo.dbMaster.Model(account).Begin().
+	query := repo.dbMaster.WithContext(ctx).Model(account).Begin().
 		Where("id", accountID).
 		Delete(account )
 	err = query.Error
@@ -135,6 +449,206 @@ func (repo *Repository) Delete(accountID int) (err error) {
 		return
 	}
 
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// FindCiphertexts returns the stored encrypted columns of the accounts after afterID in
+// id order, deleted accounts included
+func (repo *Repository) FindCiphertexts(ctx context.Context, afterID int, limit int) (accounts []model.AccountCiphertext, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).Unscoped().
+		Select("id", "address", "ktp_number", "ktp_number_index", "phone_number", "phone_number_index", "date_of_birth").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&accounts)
+	err = query.Error
+	return
+}
+
+// CountMissingBlindIndexes counts the accounts with a ktp or phone number but no blind
+// index for it, deleted accounts included
+func (repo *Repository) CountMissingBlindIndexes(ctx context.Context) (total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).Unscoped().
+		Where("(ktp_number IS NOT NULL AND ktp_number_index IS NULL) OR (phone_number IS NOT NULL AND phone_number_index IS NULL)").
+		Count(&total)
+	err = query.Error
+	return
+}
+
+// UpdateCiphertext writes already encrypted columns as they are. The row is only
+// written while the columns still hold the previous values, so a concurrent update of
+// the account is never overwritten.
+func (repo *Repository) UpdateCiphertext(ctx context.Context, previous model.AccountCiphertext, columns map[string]interface{}) (updated bool, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).Unscoped().
+		Where("id", previous.ID)
+	stored := map[string]*string{
+		"address":            previous.Address,
+		"ktp_number":         previous.KTPNumber,
+		"ktp_number_index":   previous.KTPNumberIndex,
+		"phone_number":       previous.PhoneNumber,
+		"phone_number_index": previous.PhoneNumberIndex,
+		"date_of_birth":      previous.DateOfBirth,
+	}
+	for column := range columns {
+		if stored[column] == nil {
+			query = query.Where(column + " IS NULL")
+		} else {
+			query = query.Where(column+" = ?", *stored[column])
+		}
+	}
+	query = query.UpdateColumns(columns)
+	err = query.Error
+	updated = query.RowsAffected == 1
+	return
+}
+
+// setBlindIndexes keeps the blind indexes in step with the encrypted columns they are
+// looked up by, columns that are not set are left alone
+func setBlindIndexes(account *model.Account) (err error) {
+	if account.KTPNumber != nil {
+		index, err := blindIndex(constant.FieldKTPNumber, string(*account.KTPNumber))
+		if err != nil {
+			return err
+		}
+		account.KTPNumberIndex = &index
+	}
+	if account.PhoneNumber != nil {
+		index, err := blindIndex(constant.FieldPhoneNumber, string(*account.PhoneNumber))
+		if err != nil {
+			return err
+		}
+		account.PhoneNumberIndex = &index
+	}
+	return
+}
+
+// IndexedValue is the form of a value its blind index is computed over, so a number
+// stored as it was typed matches the normalized number it is looked up by. Phone numbers
+// are indexed in E.164 and ktp numbers without surrounding spaces, a stored phone
+// number NormalizePhoneNumber does not accept is indexed as it is.
+func IndexedValue(field, value string) string {
+	switch field {
+	case constant.FieldKTPNumber:
+		return strings.TrimSpace(value)
+	case constant.FieldPhoneNumber:
+		if phoneNumber, err := identity.NormalizePhoneNumber(value); err == nil {
+			return phoneNumber
+		}
+	}
+	return value
+}
+
+func blindIndex(field, value string) (index string, err error) {
+	return envelope.BlindIndex(field, IndexedValue(field, value))
+}
+
+// Erase removes the personal data of an account and soft deletes it, deleted accounts
+// included. The row is kept with its employment details so attendance stays countable.
+// Invitations of the account go too since they hold its email, and the audit entries of
+// the account lose their values. The photo url the
+// account had is returned so the photo can be removed.
+func (repo *Repository) Erase(ctx context.Context, accountID int, events outbox.Builder, audits audit.Builder) (photoURL string, err error) {
+	account := model.Account{}
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).Unscoped().Begin().
+		Select("id", "photo_url").
+		Where("id", accountID).
+		Take(&account)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	photoURL = account.PhotoURL
+
+	now := time.Now()
+	err = query.Session(&gorm.Session{NewDB: true}).
+		Model(&model.Account{}).Unscoped().
+		Where("id", accountID).
+		UpdateColumns(map[string]interface{}{
+			"username":             fmt.Sprintf(constant.ErasedUsernameFormat, accountID),
+			"full_name":            constant.ErasedFullName,
+			"email":                nil,
+			"password":             "",
+			"address":              nil,
+			"ktp_number":           nil,
+			"ktp_number_index":     nil,
+			"phone_number":         nil,
+			"phone_number_index":   nil,
+			"date_of_birth":        nil,
+			"photo_url":            "",
+			"gender":               "none",
+			"is_verified":          false,
+			"require_bound_device": false,
+			"updated_at":           now,
+			"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Session(&gorm.Session{NewDB: true}).
+		Unscoped().
+		Where("account_id", accountID).
+		Delete(&model.Invitation{}).Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	// the audit log keeps who changed the account and when, but not the values
+	err = query.Session(&gorm.Session{NewDB: true}).
+		Model(&model.AuditLog{}).
+		Where("entity_type", constant.AggregateAccount).
+		Where("entity_id", accountID).
+		UpdateColumn("changes", "{}").Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	// the earlier events keep only the id of the account, the attendance events lose
+	// the name. Webhook deliveries of them that are still pending are sent redacted
+	err = outbox.Redact(query, constant.AggregateAccount, accountID,
+		gorm.Expr("jsonb_build_object('id', payload->'data'->'id')"))
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	attendanceIDs := query.Session(&gorm.Session{NewDB: true}).
+		Model(&model.Attendance{}).Unscoped().
+		Select("id").
+		Where("account_id", accountID)
+	err = outbox.Redact(query, constant.AggregateAttendance, attendanceIDs,
+		gorm.Expr("jsonb_set(payload->'data', '{fullname}', to_jsonb(?::text))", constant.ErasedFullName))
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = outbox.Record(query, accountID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
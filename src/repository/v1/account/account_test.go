@@ -0,0 +1,246 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/pkg/envelope"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// statement is a query the recording driver was sent, with its arguments
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+// recorder is a database/sql driver that records every statement and answers queries
+// with a single row of the columns the test sets, writes affect one row
+type recorder struct {
+	mu         sync.Mutex
+	statements []statement
+	columns    []string
+	row        []driver.Value
+}
+
+func (r *recorder) record(query string, args []driver.NamedValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := []interface{}{}
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	r.statements = append(r.statements, statement{query: query, args: values})
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) {
+	return &recorderConn{r}, nil
+}
+
+type recorderConn struct{ r *recorder }
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *recorderConn) Close() error {
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.r.record("BEGIN", nil)
+	return c, nil
+}
+
+func (c *recorderConn) Commit() error {
+	c.r.record("COMMIT", nil)
+	return nil
+}
+
+func (c *recorderConn) Rollback() error {
+	c.r.record("ROLLBACK", nil)
+	return nil
+}
+
+func (c *recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recorderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.r.record(query, args)
+	return &recorderRows{columns: c.r.columns, row: c.r.row}, nil
+}
+
+type recorderRows struct {
+	columns []string
+	row     []driver.Value
+	done    bool
+}
+
+func (rows *recorderRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *recorderRows) Close() error {
+	return nil
+}
+
+func (rows *recorderRows) Next(dest []driver.Value) error {
+	if rows.done {
+		return io.EOF
+	}
+	rows.done = true
+	copy(dest, rows.row)
+	return nil
+}
+
+func newRecordedRepository(t *testing.T, name string, r *recorder) *Repository {
+	sql.Register(name, r)
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		QueryFields:          true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository(connection.DB{Master: db})
+}
+
+func TestEraseRedactsEventPayloads(t *testing.T) {
+	r := &recorder{
+		columns: []string{"id", "photo_url"},
+		row:     []driver.Value{int64(42), "https://cdn.example.com/42.jpg"},
+	}
+	repo := newRecordedRepository(t, "recorder-erase", r)
+
+	photoURL, err := repo.Erase(context.Background(), 42, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if photoURL != "https://cdn.example.com/42.jpg" {
+		t.Errorf("photo url = %q", photoURL)
+	}
+
+	if len(r.statements) == 0 || r.statements[0].query != "BEGIN" || r.statements[len(r.statements)-1].query != "COMMIT" {
+		t.Fatalf("erasure did not run in one transaction: %v", r.statements)
+	}
+
+	tests := []struct {
+		name          string
+		table         string
+		aggregateType string
+		data          string
+		ids           string
+	}{
+		{
+			name:          "account webhook deliveries",
+			table:         `UPDATE "webhook_deliveries"`,
+			aggregateType: constant.AggregateAccount,
+			data:          "jsonb_build_object('id', payload->'data'->'id')",
+			ids:           "aggregate_id IN ($",
+		},
+		{
+			name:          "account outbox events",
+			table:         `UPDATE "outbox_events"`,
+			aggregateType: constant.AggregateAccount,
+			data:          "jsonb_build_object('id', payload->'data'->'id')",
+			ids:           "aggregate_id IN ($",
+		},
+		{
+			name:          "attendance webhook deliveries",
+			table:         `UPDATE "webhook_deliveries"`,
+			aggregateType: constant.AggregateAttendance,
+			data:          "jsonb_set(payload->'data', '{fullname}', to_jsonb($",
+			ids:           `aggregate_id IN (SELECT "id" FROM "attendances" WHERE "account_id" = $`,
+		},
+		{
+			name:          "attendance outbox events",
+			table:         `UPDATE "outbox_events"`,
+			aggregateType: constant.AggregateAttendance,
+			data:          "jsonb_set(payload->'data', '{fullname}', to_jsonb($",
+			ids:           `aggregate_id IN (SELECT "id" FROM "attendances" WHERE "account_id" = $`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, stmt := range r.statements {
+				if !strings.HasPrefix(stmt.query, test.table) || !strings.Contains(stmt.query, test.data) || !strings.Contains(stmt.query, test.ids) {
+					continue
+				}
+				if !strings.Contains(stmt.query, `SET "payload"=jsonb_set(payload, '{data}', `) {
+					t.Errorf("payload is not replaced: %s", stmt.query)
+				}
+				if !containsArg(stmt.args, test.aggregateType) || !containsArg(stmt.args, int64(42)) {
+					t.Errorf("statement is not limited to the %s events of the account: %s %v", test.aggregateType, stmt.query, stmt.args)
+				}
+				return
+			}
+			t.Errorf("no statement redacts the %s payloads, statements: %v", test.name, r.statements)
+		})
+	}
+}
+
+// containsArg tells whether want was sent, the driver receives ints as int64
+func containsArg(args []interface{}, want interface{}) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBlindIndexNormalizesNumbers(t *testing.T) {
+	keyring, err := envelope.NewKeyring(map[int][]byte{1: make([]byte, 32)}, 1, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope.SetDefault(keyring)
+	defer envelope.SetDefault(nil)
+
+	tests := []struct {
+		name   string
+		field  string
+		stored string
+		lookup string
+	}{
+		{name: "local phone number", field: constant.FieldPhoneNumber, stored: "081234567890", lookup: "+6281234567890"},
+		{name: "formatted phone number", field: constant.FieldPhoneNumber, stored: "0812-3456-7890", lookup: "+6281234567890"},
+		{name: "phone number without plus", field: constant.FieldPhoneNumber, stored: "6281234567890", lookup: "+6281234567890"},
+		{name: "padded ktp number", field: constant.FieldKTPNumber, stored: " 3171234567890001 ", lookup: "3171234567890001"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stored, err := blindIndex(test.field, test.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lookup, err := blindIndex(test.field, test.lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored != lookup {
+				t.Errorf("index of %q differs from the index of %q", test.stored, test.lookup)
+			}
+		})
+	}
+}
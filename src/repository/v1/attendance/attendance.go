@@ -1,11 +1,18 @@
 package attendance
 
 import (
+	"context"
+	"time"
+
 	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
 	"go-rest-api/src/model"
 	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/repository/v1/outbox"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DB struct {
@@ -25,12 +32,37 @@ func NewRepository(
 }
 
 type Repositorier interface {
-	Find(accountID int, pgn pagination.Pagination) (attendaceDatas []model.Attendance, err error)
-	Create(accountID int, account model.Attendance) (err error)
+	TakeAttendanceByID(ctx context.Context, attendanceID int) (attendance model.Attendance, err error)
+	TakeAttendanceByEventID(ctx context.Context, eventID string) (attendance model.Attendance, err error)
+	Find(ctx context.Context, accountID int, pgn pagination.Pagination) (attendaceDatas []model.Attendance, err error)
+	Create(ctx context.Context, accountID int, account model.Attendance, events outbox.Builder, audits audit.Builder) (attendanceID int, err error)
+	CreateEvent(ctx context.Context, attendance model.Attendance, events outbox.Builder, audits audit.Builder) (attendanceID int, created bool, err error)
+	FindPresence(ctx context.Context, locationID int, since time.Time) (presences []model.Presence, err error)
+	FindAll(ctx context.Context, accountID int) (attendances []model.Attendance, err error)
+	FindPhotoKeys(ctx context.Context, accountID int) (photoKeys []string, err error)
+	ClearPhotos(ctx context.Context, accountID int, audits audit.Builder) (err error)
+}
+
+func (repo *Repository) TakeAttendanceByID(ctx context.Context, attendanceID int) (attendance model.Attendance, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).
+		Where("id", attendanceID).
+		Take(&attendance)
+	err = query.Error
+	return
+}
+
+// TakeAttendanceByEventID also returns deleted attendances, the event id stays taken
+// by them so a resent event is still a duplicate
+func (repo *Repository) TakeAttendanceByEventID(ctx context.Context, eventID string) (attendance model.Attendance, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).Unscoped().
+		Where("event_id", eventID).
+		Take(&attendance)
+	err = query.Error
+	return
 }
 
-func (repo *Repository) Find(accountID int, pgn pagination.Pagination) (attendaceDatas []model.Attendance, err error) {
-	query := repo.dbMaster.Model(&model.Attendance{}).
+func (repo *Repository) Find(ctx context.Context, accountID int, pgn pagination.Pagination) (attendaceDatas []model.Attendance, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).
 		Where("account_id", accountID).
 		Order("created_at desc").
 		Limit(pgn.Limit).
@@ -40,14 +72,130 @@ func (repo *Repository) Find(accountID int, pgn pagination.Pagination) (attendac
 	return
 }
 
-func (repo *Repository) Create(accountID int, attendance model.Attendance) (err error) {
-	query := repo.dbMaster.Model(&attendance).Begin().
+// FindPresence returns accounts whose latest attendance since the given time is a
+// check-in, filtered to one location when locationID is not zero. The latest row per
+// account is picked with DISTINCT ON so the whole dashboard is a single query.
+func (repo *Repository) FindPresence(ctx context.Context, locationID int, since time.Time) (presences []model.Presence, err error) {
+	latest := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).
+		Select("DISTINCT ON (account_id) account_id, location_id, status, created_at").
+		Where("created_at >= ?", since).
+		Order("account_id, created_at desc")
+
+	query := repo.dbMaster.WithContext(ctx).Table("(?) AS latest", latest).
+		Select("latest.location_id, locations.name AS location_name, latest.account_id, "+
+			"accounts.username, accounts.full_name, accounts.employee_number, accounts.job_position, "+
+			"accounts.photo_url, latest.created_at AS checked_in_at").
+		Joins("JOIN accounts ON accounts.id = latest.account_id AND accounts.deleted_at IS NULL").
+		Joins("JOIN locations ON locations.id = latest.location_id AND locations.deleted_at IS NULL").
+		Where("latest.status = ?", constant.StatusCheckIn)
+	if locationID != 0 {
+		query = query.Where("latest.location_id = ?", locationID)
+	}
+
+	query = query.Order("locations.name, latest.created_at").
+		Scan(&presences)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) Create(ctx context.Context, accountID int, attendance model.Attendance, events outbox.Builder, audits audit.Builder) (attendanceID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&attendance).Begin().
+		Create(&attendance)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	attendanceID = attendance.ID
+	err = outbox.Record(query, attendanceID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, attendanceID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// CreateEvent inserts an attendance keyed by its client event id, created is false
+// when an attendance with the same event id already exists and no events are recorded
+func (repo *Repository) CreateEvent(ctx context.Context, attendance model.Attendance, events outbox.Builder, audits audit.Builder) (attendanceID int, created bool, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&attendance).Begin().
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_id"}},
+			DoNothing: true,
+		}).
 		Create(&attendance)
 	err = query.Error
 	if err != nil {
 		query.Rollback()
 		return
 	}
+	created = query.RowsAffected == 1
+	attendanceID = attendance.ID
+
+	if created {
+		err = outbox.Record(query, attendanceID, events)
+		if err != nil {
+			query.Rollback()
+			return
+		}
+		err = audit.Record(query, attendanceID, audits)
+		if err != nil {
+			query.Rollback()
+			return
+		}
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// FindAll returns every attendance of the account oldest first
+func (repo *Repository) FindAll(ctx context.Context, accountID int) (attendances []model.Attendance, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).
+		Where("account_id", accountID).
+		Order("created_at").
+		Find(&attendances)
+	err = query.Error
+	return
+}
+
+// FindPhotoKeys returns the keys of the photos of every attendance of the account,
+// deleted ones included
+func (repo *Repository) FindPhotoKeys(ctx context.Context, accountID int) (photoKeys []string, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).Unscoped().
+		Where("account_id", accountID).
+		Where("photo_key IS NOT NULL").
+		Pluck("photo_key", &photoKeys)
+	err = query.Error
+	return
+}
+
+// ClearPhotos detaches the photos of every attendance of the account, deleted ones
+// included
+func (repo *Repository) ClearPhotos(ctx context.Context, accountID int, audits audit.Builder) (err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Attendance{}).Unscoped().Begin().
+		Where("account_id", accountID).
+		Where("photo_key IS NOT NULL").
+		UpdateColumn("photo_key", nil)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = audit.Record(query, accountID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
 
 	err = query.Commit().Error
 	return
@@ -0,0 +1,203 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+// Builder returns the audit entries of a write, it is called inside the write
+// transaction once the id of the entity is known
+type Builder func(entityID int) (entries []model.AuditLog, err error)
+
+// Mask is how a sensitive field shows up in the changes of an entry
+type Mask int
+
+const (
+	// MaskFull hides the whole value
+	MaskFull Mask = iota
+	// MaskLastFour keeps the last four characters, enough to tell numbers apart
+	MaskLastFour
+)
+
+const maskedValue = "[masked]"
+
+// Change is the value of a field before and after a write, nil when the entity did
+// not exist before or does not exist after
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type Repositorier interface {
+	Find(ctx context.Context, filter model.AuditLogFilter, pgn pagination.Pagination) (entries []model.AuditLog, total int64, err error)
+	FindByAccount(ctx context.Context, accountID int) (entries []model.AuditLog, err error)
+}
+
+// Find pages through the entries matching the filter newest first
+func (repo *Repository) Find(ctx context.Context, filter model.AuditLogFilter, pgn pagination.Pagination) (entries []model.AuditLog, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id", filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id", filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = query.Order("id desc").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&entries).Error
+	return
+}
+
+// FindByAccount returns the entries made by the account or about it oldest first
+func (repo *Repository) FindByAccount(ctx context.Context, accountID int) (entries []model.AuditLog, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.AuditLog{}).
+		Where("actor_id = ? OR (entity_type = ? AND entity_id = ?)", accountID, constant.AggregateAccount, accountID).
+		Order("id").
+		Find(&entries)
+	err = query.Error
+	return
+}
+
+// NewEntry describes a write of the actor with the changes between before and after,
+// both are compared field by field as they marshal to json. A nil before is a create
+// and a nil after a delete. changed is false when no field changed.
+func NewEntry(actor model.Actor, action string, entityType string, entityID int, before, after interface{}, masks map[string]Mask) (entry model.AuditLog, changed bool, err error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return
+	}
+
+	changes := map[string]Change{}
+	for name, value := range beforeFields {
+		if afterValue, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[name] = Change{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+	for name, change := range changes {
+		if mask, ok := masks[name]; ok {
+			changes[name] = Change{
+				Before: maskValue(change.Before, mask),
+				After:  maskValue(change.After, mask),
+			}
+		}
+	}
+
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return
+	}
+	entry = model.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    string(payload),
+		CreatedAt:  time.Now(),
+	}
+	if actor.AccountID != 0 {
+		entry.ActorID = &actor.AccountID
+	}
+	if actor.RequestID != "" {
+		entry.RequestID = &actor.RequestID
+	}
+	if actor.ClientIP != "" {
+		entry.ClientIP = &actor.ClientIP
+	}
+	changed = len(changes) > 0
+	return
+}
+
+// Record writes the entries built by build using the transaction of tx, repositories
+// call it right before committing a write. A nil build records nothing.
+func Record(tx *gorm.DB, entityID int, build Builder) (err error) {
+	if build == nil {
+		return
+	}
+
+	entries, err := build(entityID)
+	if err != nil || len(entries) == 0 {
+		return
+	}
+
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Model(&model.AuditLog{}).
+		Create(&entries).Error
+	return
+}
+
+func fields(value interface{}) (fields map[string]interface{}, err error) {
+	fields = map[string]interface{}{}
+	if value == nil {
+		return
+	}
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(payload, &fields)
+	return
+}
+
+func maskValue(value interface{}, mask Mask) interface{} {
+	text, ok := value.(string)
+	if value == nil || (ok && text == "") {
+		return value
+	}
+	if mask == MaskLastFour && ok && len(text) > 4 {
+		return strings.Repeat("*", len(text)-4) + text[len(text)-4:]
+	}
+	return maskedValue
+}
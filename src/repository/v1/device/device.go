@@ -0,0 +1,172 @@
+package device
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+type Repositorier interface {
+	TakeDeviceByID(ctx context.Context, deviceID int) (device model.Device, err error)
+	TakeActiveDevice(ctx context.Context, accountID int, deviceID string) (device model.Device, err error)
+	Find(ctx context.Context, accountID int) (devices []model.Device, err error)
+	CountActive(ctx context.Context, accountID int) (total int64, err error)
+	Create(ctx context.Context, device model.Device) (err error)
+	Update(ctx context.Context, deviceID int, request model.Device) (err error)
+	Revoke(ctx context.Context, deviceID int) (err error)
+	UseSignature(ctx context.Context, signature model.DeviceSignature, expiredBefore time.Time) (err error)
+	Erase(ctx context.Context, accountID int) (err error)
+}
+
+func (repo *Repository) TakeDeviceByID(ctx context.Context, deviceID int) (device model.Device, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Device{}).
+		Where("id", deviceID).
+		Take(&device)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) TakeActiveDevice(ctx context.Context, accountID int, deviceID string) (device model.Device, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Device{}).
+		Where("account_id", accountID).
+		Where("device_id", deviceID).
+		Where("revoked_at IS NULL").
+		Take(&device)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) Find(ctx context.Context, accountID int) (devices []model.Device, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Device{}).
+		Where("account_id", accountID).
+		Order("created_at desc").
+		Find(&devices)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) CountActive(ctx context.Context, accountID int) (total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Device{}).
+		Where("account_id", accountID).
+		Where("revoked_at IS NULL").
+		Count(&total)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) Create(ctx context.Context, device model.Device) (err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&device).Begin().
+		Create(&device)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) Update(ctx context.Context, deviceID int, request model.Device) (err error) {
+	device := &model.Device{}
+	query := repo.dbMaster.WithContext(ctx).Model(&device).Begin().
+		Where("id", deviceID).
+		Updates(request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) Revoke(ctx context.Context, deviceID int) (err error) {
+	device := &model.Device{}
+	query := repo.dbMaster.WithContext(ctx).Model(device).Begin().
+		Where("id", deviceID).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now().UTC())
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// UseSignature records a signature the device is authorized with, it fails with
+// ErrDeviceSignatureUsed when the device already used it. The signatures of the device
+// signed before expiredBefore are dropped, they are rejected by their timestamp anyway
+func (repo *Repository) UseSignature(ctx context.Context, signature model.DeviceSignature, expiredBefore time.Time) (err error) {
+	err = repo.dbMaster.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("device_id", signature.DeviceID).
+			Where("signed_at < ?", expiredBefore).
+			Delete(&model.DeviceSignature{}).Error
+		if err != nil {
+			return err
+		}
+
+		query := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&signature)
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected != 1 {
+			return constant.ErrDeviceSignatureUsed
+		}
+		return nil
+	})
+	return
+}
+
+// Erase revokes every device of the account and removes what identifies the physical
+// device, attendances keep pointing to the rows
+func (repo *Repository) Erase(ctx context.Context, accountID int) (err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Device{}).Unscoped().Begin().
+		Where("account_id", accountID).
+		UpdateColumns(map[string]interface{}{
+			"device_id":  gorm.Expr("'erased-' || id"),
+			"public_key": "",
+			"revoked_at": gorm.Expr("COALESCE(revoked_at, ?)", time.Now().UTC()),
+			"updated_at": time.Now(),
+		})
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
@@ -0,0 +1,147 @@
+package invitation
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+type Repositorier interface {
+	TakeInvitationByID(ctx context.Context, invitationID int) (invitation model.Invitation, err error)
+	TakePendingInvitationByEmail(ctx context.Context, email string) (invitation model.Invitation, err error)
+	Find(ctx context.Context, status string, pgn pagination.Pagination) (invitations []model.Invitation, total int64, err error)
+	Create(ctx context.Context, invitation model.Invitation) (invitationID int, err error)
+	Reissue(ctx context.Context, invitationID int, expiresAt time.Time) (version int, err error)
+	Delete(ctx context.Context, invitationID int) (err error)
+}
+
+func (repo *Repository) TakeInvitationByID(ctx context.Context, invitationID int) (invitation model.Invitation, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Invitation{}).
+		Where("id", invitationID).
+		Take(&invitation)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) TakePendingInvitationByEmail(ctx context.Context, email string) (invitation model.Invitation, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Invitation{}).
+		Where("lower(email) = lower(?)", email).
+		Where("accepted_at IS NULL AND expires_at > ?", time.Now()).
+		Take(&invitation)
+	err = query.Error
+	return
+}
+
+// Find pages through invitations newest first, status is skipped when empty
+func (repo *Repository) Find(ctx context.Context, status string, pgn pagination.Pagination) (invitations []model.Invitation, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Invitation{})
+	switch status {
+	case constant.InvitationStatusPending:
+		query = query.Where("accepted_at IS NULL AND expires_at > ?", time.Now())
+	case constant.InvitationStatusAccepted:
+		query = query.Where("accepted_at IS NOT NULL")
+	case constant.InvitationStatusExpired:
+		query = query.Where("accepted_at IS NULL AND expires_at <= ?", time.Now())
+	}
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = query.Order("id desc").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&invitations).Error
+	return
+}
+
+func (repo *Repository) Create(ctx context.Context, invitation model.Invitation) (invitationID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&invitation).Begin().
+		Create(&invitation)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	invitationID = int(invitation.ID)
+	return
+}
+
+// Reissue extends an invitation that has not been accepted yet and bumps its version,
+// the tokens of the previous versions are no longer accepted
+func (repo *Repository) Reissue(ctx context.Context, invitationID int, expiresAt time.Time) (version int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Invitation{}).Begin().
+		Where("id", invitationID).
+		Where("accepted_at IS NULL").
+		Updates(map[string]interface{}{
+			"expires_at": expiresAt,
+			"version":    gorm.Expr("version + 1"),
+		})
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Session(&gorm.Session{NewDB: true}).Model(&model.Invitation{}).
+		Where("id", invitationID).
+		Select("version").
+		Scan(&version).Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) Delete(ctx context.Context, invitationID int) (err error) {
+	invitation := &model.Invitation{}
+	query := repo.dbMaster.WithContext(ctx).Model(invitation).Begin().
+		Where("id", invitationID).
+		Delete(invitation)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
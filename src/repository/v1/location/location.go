@@ -1,9 +1,12 @@
 package location
 
 import (
+	"context"
 	"go-rest-api/src/connection"
 	"go-rest-api/src/constant"
 	"go-rest-api/src/model"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/repository/v1/outbox"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -25,43 +28,44 @@ func NewRepository(
 }
 
 type Repositorier interface {
-	TakeLocationByID(locationID int) (location model.Location, err error)
-	TakeLocationByName(name string) (location model.Location, err error)
-	Find(locationIDs []int) (locations []model.Location, err error)
-	Create(location model.Location) (err error)
-	Update(locationID int, request model.Location) (err error)
-	Delete(locationID int) (err error)
+	TakeLocationByID(ctx context.Context, locationID int) (location model.Location, err error)
+	TakeLocationByName(ctx context.Context, name string) (location model.Location, err error)
+	Find(ctx context.Context, locationIDs []int) (locations []model.Location, err error)
+	Create(ctx context.Context, location model.Location, events outbox.Builder, audits audit.Builder) (locationID int, err error)
+	Update(ctx context.Context, locationID int, request model.Location, requirePhoto *bool, events outbox.Builder, audits audit.Builder) (err error)
+	Delete(ctx context.Context, locationID int, events outbox.Builder, audits audit.Builder) (err error)
 }
 
-func (repo *Repository) TakeLocationByID(locationID int) (location model.Location, err error) {
-	query := repo.dbMaster.Model(&model.Location{}).
+func (repo *Repository) TakeLocationByID(ctx context.Context, locationID int) (location model.Location, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Location{}).
 		Where("id", locationID).
 		Take(&location)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) TakeLocationByName(name string) (location model.Location, err error) {
-	query := repo.dbMaster.Model(&model.Location{}).
+func (repo *Repository) TakeLocationByName(ctx context.Context, name string) (location model.Location, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Location{}).
 		Where("name", name).
 		Take(&location)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) Find(locationIDs []int) (locations []model.Location, err error) {
-	query := repo.dbMaster.Model(&model.Location{}).
+func (repo *Repository) Find(ctx context.Context, locationIDs []int) (locations []model.Location, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Location{}).
 		Find(&locations, locationIDs)
 	err = query.Error
 	return
 }
 
-func (repo *Repository) Create(location model.Location) (err error) {
-	query := repo.dbMaster.Model(&location).Begin().
+func (repo *Repository) Create(ctx context.Context, location model.Location, events outbox.Builder, audits audit.Builder) (locationID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&location).Begin().
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "name"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
 				"address": location.Address,
+				"require_photo": location.RequirePhoto,
 				"deleted_at": nil,
 			})}).
 		Create(&location)
@@ -71,13 +75,27 @@ func (repo *Repository) Create(location model.Location) (err error) {
 		return
 	}
 
+	locationID = int(location.ID)
+	err = outbox.Record(query, locationID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, locationID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
 
-func (repo *Repository) Update(locationID int, request model.Location) (err error) {
+// Update writes the non zero fields of request, requirePhoto is written on its own
+// when set since Updates skips false
+func (repo *Repository) Update(ctx context.Context, locationID int, request model.Location, requirePhoto *bool, events outbox.Builder, audits audit.Builder) (err error) {
 	location := &model.Location{}
-	query := repo.dbMaster.Model(&location).Begin().
+	query := repo.dbMaster.WithContext(ctx).Model(&location).Begin().
 		Where("id", locationID).
 		Updates(request)
 	err = query.Error
@@ -86,13 +104,35 @@ func (repo *Repository) Update(locationID int, request model.Location) (err erro
 		return
 	}
 
+	if requirePhoto != nil {
+		err = query.Session(&gorm.Session{NewDB: true}).
+			Model(&model.Location{}).
+			Where("id", locationID).
+			Update("require_photo", *requirePhoto).Error
+		if err != nil {
+			query.Rollback()
+			return
+		}
+	}
+
+	err = outbox.Record(query, locationID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, locationID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
 
-func (repo *Repository) Delete(locationID int) (err error) {
+func (repo *Repository) Delete(ctx context.Context, locationID int, events outbox.Builder, audits audit.Builder) (err error) {
 	location := &model.Location{}
-	query := repo.dbMaster.Model(location).Begin().
+	query := repo.dbMaster.WithContext(ctx).Model(location).Begin().
 		Where("id", locationID).
 		Delete(location)
 	err = query.Error
@@ -106,6 +146,17 @@ func (repo *Repository) Delete(locationID int) (err error) {
 		return
 	}
 
+	err = outbox.Record(query, locationID, events)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	err = audit.Record(query, locationID, audits)
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
 	err = query.Commit().Error
 	return
 }
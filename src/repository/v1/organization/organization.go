@@ -0,0 +1,221 @@
+package organization
+
+import (
+	"context"
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+
+	"gorm.io/gorm"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+type Repositorier interface {
+	TakeDepartmentByID(ctx context.Context, departmentID int) (department model.Department, err error)
+	TakeDepartmentByName(ctx context.Context, name string) (department model.Department, err error)
+	FindDepartments(ctx context.Context) (departments []model.Department, err error)
+	CountDepartmentMembers(ctx context.Context, departmentID int) (total int64, err error)
+	CreateDepartment(ctx context.Context, department model.Department) (departmentID int, err error)
+	UpdateDepartment(ctx context.Context, departmentID int, request model.Department, columns []string) (err error)
+	DeleteDepartment(ctx context.Context, departmentID int) (err error)
+	TakeTeamByID(ctx context.Context, teamID int) (team model.Team, err error)
+	TakeTeamByName(ctx context.Context, departmentID int, name string) (team model.Team, err error)
+	FindTeams(ctx context.Context, departmentID int) (teams []model.Team, err error)
+	CountTeamMembers(ctx context.Context, teamID int) (total int64, err error)
+	CreateTeam(ctx context.Context, team model.Team) (teamID int, err error)
+	UpdateTeam(ctx context.Context, teamID int, request model.Team, columns []string) (err error)
+	DeleteTeam(ctx context.Context, teamID int) (err error)
+}
+
+func (repo *Repository) TakeDepartmentByID(ctx context.Context, departmentID int) (department model.Department, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Department{}).
+		Where("id", departmentID).
+		Take(&department)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) TakeDepartmentByName(ctx context.Context, name string) (department model.Department, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Department{}).
+		Where("name", name).
+		Take(&department)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) FindDepartments(ctx context.Context) (departments []model.Department, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Department{}).
+		Order("name").
+		Find(&departments)
+	err = query.Error
+	return
+}
+
+// CountDepartmentMembers counts the teams and accounts still in the department
+func (repo *Repository) CountDepartmentMembers(ctx context.Context, departmentID int) (total int64, err error) {
+	var teams, accounts int64
+	err = repo.dbMaster.WithContext(ctx).Model(&model.Team{}).
+		Where("department_id", departmentID).
+		Count(&teams).Error
+	if err != nil {
+		return
+	}
+
+	err = repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
+		Where("department_id", departmentID).
+		Count(&accounts).Error
+	total = teams + accounts
+	return
+}
+
+func (repo *Repository) CreateDepartment(ctx context.Context, department model.Department) (departmentID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&department).Begin().
+		Create(&department)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	departmentID = int(department.ID)
+	return
+}
+
+func (repo *Repository) UpdateDepartment(ctx context.Context, departmentID int, request model.Department, columns []string) (err error) {
+	department := &model.Department{}
+	query := repo.dbMaster.WithContext(ctx).Model(&department).Begin().
+		Where("id", departmentID).
+		Select(columns).
+		Updates(request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) DeleteDepartment(ctx context.Context, departmentID int) (err error) {
+	department := &model.Department{}
+	query := repo.dbMaster.WithContext(ctx).Model(department).Begin().
+		Where("id", departmentID).
+		Delete(department)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) TakeTeamByID(ctx context.Context, teamID int) (team model.Team, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Team{}).
+		Where("id", teamID).
+		Take(&team)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) TakeTeamByName(ctx context.Context, departmentID int, name string) (team model.Team, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Team{}).
+		Where("department_id", departmentID).
+		Where("name", name).
+		Take(&team)
+	err = query.Error
+	return
+}
+
+// FindTeams returns the teams of a department, or every team when departmentID is zero
+func (repo *Repository) FindTeams(ctx context.Context, departmentID int) (teams []model.Team, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Team{})
+	if departmentID != 0 {
+		query = query.Where("department_id", departmentID)
+	}
+	query = query.Order("name").
+		Find(&teams)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) CountTeamMembers(ctx context.Context, teamID int) (total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.Account{}).
+		Where("team_id", teamID).
+		Count(&total)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) CreateTeam(ctx context.Context, team model.Team) (teamID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&team).Begin().
+		Create(&team)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	teamID = int(team.ID)
+	return
+}
+
+func (repo *Repository) UpdateTeam(ctx context.Context, teamID int, request model.Team, columns []string) (err error) {
+	team := &model.Team{}
+	query := repo.dbMaster.WithContext(ctx).Model(&team).Begin().
+		Where("id", teamID).
+		Select(columns).
+		Updates(request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) DeleteTeam(ctx context.Context, teamID int) (err error) {
+	team := &model.Team{}
+	query := repo.dbMaster.WithContext(ctx).Model(team).Begin().
+		Where("id", teamID).
+		Delete(team)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
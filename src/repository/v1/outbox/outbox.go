@@ -0,0 +1,257 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+// Builder returns the events of a write, it is called inside the write transaction
+// once the id of the aggregate is known
+type Builder func(aggregateID int) (events []model.OutboxEvent, err error)
+
+type Repositorier interface {
+	Lock(ctx context.Context, fn func(repo Repositorier) (err error)) (locked bool, err error)
+	FindUnpublished(ctx context.Context, limit int) (events []model.OutboxEvent, err error)
+	Claim(ctx context.Context, eventIDs []int64, until time.Time) (err error)
+	Complete(ctx context.Context, claimedIDs []int64, publishedIDs []int64, failed []model.OutboxEvent) (err error)
+	FindDead(ctx context.Context, pgn pagination.Pagination) (events []model.OutboxEvent, total int64, err error)
+	Requeue(ctx context.Context, eventID int64) (err error)
+}
+
+// NewEvent wraps data in the domain event envelope stored in the outbox
+func NewEvent(aggregateType string, aggregateID int, eventType string, data interface{}) (outboxEvent model.OutboxEvent, err error) {
+	domainEvent := event.New(eventType, data)
+	payload, err := json.Marshal(domainEvent)
+	if err != nil {
+		return
+	}
+
+	outboxEvent = model.OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventID:       domainEvent.ID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		Status:        constant.OutboxStatusPending,
+		NextAttemptAt: domainEvent.OccurredAt,
+		CreatedAt:     domainEvent.OccurredAt,
+	}
+	return
+}
+
+// Record writes the events built by build using the transaction of tx, repositories
+// call it right before committing a write. A nil build records nothing.
+func Record(tx *gorm.DB, aggregateID int, build Builder) (err error) {
+	if build == nil {
+		return
+	}
+
+	events, err := build(aggregateID)
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Model(&model.OutboxEvent{}).
+		Create(&events).Error
+	return
+}
+
+// Redact replaces the data of the events recorded for the aggregates, published or
+// not, and of the webhook deliveries made from them using the transaction of tx. data
+// is a jsonb expression that may read the old data as payload->'data'. aggregateIDs is
+// an id or a subquery of ids.
+func Redact(tx *gorm.DB, aggregateType string, aggregateIDs interface{}, data clause.Expr) (err error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	eventIDs := db.Model(&model.OutboxEvent{}).
+		Select("event_id").
+		Where("aggregate_type", aggregateType).
+		Where("aggregate_id IN (?)", aggregateIDs)
+	payload := gorm.Expr("jsonb_set(payload, '{data}', ?)", data)
+
+	err = db.Model(&model.WebhookDelivery{}).
+		Where("event_id IN (?)", eventIDs).
+		UpdateColumn("payload", payload).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Model(&model.OutboxEvent{}).
+		Where("aggregate_type", aggregateType).
+		Where("aggregate_id IN (?)", aggregateIDs).
+		UpdateColumn("payload", payload).Error
+	return
+}
+
+// Lock runs fn in a transaction holding the relay advisory lock, so only one relay
+// claims events at a time. locked is false when another relay holds the lock.
+func (repo *Repository) Lock(ctx context.Context, fn func(repo Repositorier) (err error)) (locked bool, err error) {
+	tx := repo.dbMaster.WithContext(ctx).Begin()
+	err = tx.Error
+	if err != nil {
+		return
+	}
+
+	err = tx.Raw("SELECT pg_try_advisory_xact_lock(?)", constant.OutboxLockKey).Scan(&locked).Error
+	if err != nil || !locked {
+		tx.Rollback()
+		return
+	}
+
+	err = fn(&Repository{dbMaster: tx})
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit().Error
+	return
+}
+
+// FindUnpublished returns the oldest pending events, including the ones waiting for a
+// retry so the relay can hold back later events of the same aggregate
+func (repo *Repository) FindUnpublished(ctx context.Context, limit int) (events []model.OutboxEvent, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.OutboxEvent{}).
+		Where("status", constant.OutboxStatusPending).
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	err = query.Error
+	return
+}
+
+// Claim marks the events as being delivered until until, it runs under Lock
+func (repo *Repository) Claim(ctx context.Context, eventIDs []int64, until time.Time) (err error) {
+	if len(eventIDs) == 0 {
+		return
+	}
+
+	query := repo.dbMaster.WithContext(ctx).Model(&model.OutboxEvent{}).
+		Where("id IN ?", eventIDs).
+		Update("claimed_until", until)
+	err = query.Error
+	return
+}
+
+// Complete releases the claimed events in one transaction, the published ones are
+// marked published and the failed ones get their status, attempts, next attempt and
+// last error. Claimed events in neither list are only released.
+func (repo *Repository) Complete(ctx context.Context, claimedIDs []int64, publishedIDs []int64, failed []model.OutboxEvent) (err error) {
+	if len(claimedIDs) == 0 {
+		return
+	}
+
+	query := repo.dbMaster.WithContext(ctx).Begin()
+	err = query.Error
+	if err != nil {
+		return
+	}
+
+	if len(publishedIDs) > 0 {
+		err = query.Model(&model.OutboxEvent{}).
+			Where("id IN ?", publishedIDs).
+			Updates(map[string]interface{}{
+				"status":       constant.OutboxStatusPublished,
+				"published_at": time.Now().UTC(),
+			}).Error
+		if err != nil {
+			query.Rollback()
+			return
+		}
+	}
+
+	for i := range failed {
+		err = query.Model(&model.OutboxEvent{}).
+			Where("id", failed[i].ID).
+			Updates(map[string]interface{}{
+				"status":          failed[i].Status,
+				"attempts":        failed[i].Attempts,
+				"next_attempt_at": failed[i].NextAttemptAt,
+				"last_error":      failed[i].LastError,
+			}).Error
+		if err != nil {
+			query.Rollback()
+			return
+		}
+	}
+
+	err = query.Model(&model.OutboxEvent{}).
+		Where("id IN ?", claimedIDs).
+		Update("claimed_until", nil).Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// FindDead pages through the events that ran out of attempts, oldest first
+func (repo *Repository) FindDead(ctx context.Context, pgn pagination.Pagination) (events []model.OutboxEvent, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.OutboxEvent{}).
+		Where("status", constant.OutboxStatusDead)
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = query.Order("id").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&events).Error
+	return
+}
+
+// Requeue moves a dead event back to pending with a fresh attempt budget
+func (repo *Repository) Requeue(ctx context.Context, eventID int64) (err error) {
+	outboxEvent := &model.OutboxEvent{}
+	query := repo.dbMaster.WithContext(ctx).Model(outboxEvent).Begin().
+		Where("id", eventID).
+		Where("status", constant.OutboxStatusDead).
+		Updates(map[string]interface{}{
+			"status":          constant.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC(),
+		})
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
@@ -0,0 +1,126 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+type Repositorier interface {
+	TakeErasureRequestByID(ctx context.Context, requestID int) (request model.ErasureRequest, err error)
+	TakePendingErasureRequest(ctx context.Context, accountID int) (request model.ErasureRequest, err error)
+	FindErasureRequests(ctx context.Context, status string, pgn pagination.Pagination) (requests []model.ErasureRequest, total int64, err error)
+	FindErasureRequestsByAccount(ctx context.Context, accountID int) (requests []model.ErasureRequest, err error)
+	CreateErasureRequest(ctx context.Context, request model.ErasureRequest) (requestID int, err error)
+	ProcessErasureRequest(ctx context.Context, requestID int, status string, reason *string, processedBy int) (err error)
+}
+
+func (repo *Repository) TakeErasureRequestByID(ctx context.Context, requestID int) (request model.ErasureRequest, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.ErasureRequest{}).
+		Where("id", requestID).
+		Take(&request)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) TakePendingErasureRequest(ctx context.Context, accountID int) (request model.ErasureRequest, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.ErasureRequest{}).
+		Where("account_id", accountID).
+		Where("status", constant.ErasureStatusPending).
+		Take(&request)
+	err = query.Error
+	return
+}
+
+// FindErasureRequests pages through erasure requests oldest first so the longest waiting
+// are handled first, status is skipped when empty
+func (repo *Repository) FindErasureRequests(ctx context.Context, status string, pgn pagination.Pagination) (requests []model.ErasureRequest, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.ErasureRequest{})
+	if status != "" {
+		query = query.Where("status", status)
+	}
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = query.Order("id").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&requests).Error
+	return
+}
+
+func (repo *Repository) FindErasureRequestsByAccount(ctx context.Context, accountID int) (requests []model.ErasureRequest, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.ErasureRequest{}).
+		Where("account_id", accountID).
+		Order("id").
+		Find(&requests)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) CreateErasureRequest(ctx context.Context, request model.ErasureRequest) (requestID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&request).Begin().
+		Create(&request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	requestID = int(request.ID)
+	return
+}
+
+// ProcessErasureRequest completes or rejects a pending request, a request that is no
+// longer pending is left as it is
+func (repo *Repository) ProcessErasureRequest(ctx context.Context, requestID int, status string, reason *string, processedBy int) (err error) {
+	request := &model.ErasureRequest{}
+	query := repo.dbMaster.WithContext(ctx).Model(&request).Begin().
+		Where("id", requestID).
+		Where("status", constant.ErasureStatusPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"reason":       reason,
+			"processed_by": processedBy,
+			"processed_at": time.Now(),
+		})
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrErasureRequestProcessed
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DB struct {
+	Master *gorm.DB
+}
+
+type Repository struct {
+	dbMaster *gorm.DB
+}
+
+func NewRepository(
+	db connection.DB,
+) *Repository {
+	return &Repository{
+		dbMaster: db.Master,
+	}
+}
+
+type Repositorier interface {
+	TakeSubscriptionByID(ctx context.Context, subscriptionID int) (subscription model.WebhookSubscription, err error)
+	FindSubscriptions(ctx context.Context) (subscriptions []model.WebhookSubscription, err error)
+	FindSubscriptionsByEvent(ctx context.Context, eventType string) (subscriptions []model.WebhookSubscription, err error)
+	CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) (subscriptionID int, err error)
+	UpdateSubscription(ctx context.Context, subscriptionID int, request model.WebhookSubscription, columns []string) (err error)
+	DeleteSubscription(ctx context.Context, subscriptionID int) (err error)
+	FindDeliveries(ctx context.Context, subscriptionID int, status string, pgn pagination.Pagination) (deliveries []model.WebhookDelivery, total int64, err error)
+	CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) (err error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []model.WebhookDelivery, err error)
+	UpdateDelivery(ctx context.Context, deliveryID int, request model.WebhookDelivery, columns []string) (err error)
+	RequeueDelivery(ctx context.Context, deliveryID int) (err error)
+}
+
+func (repo *Repository) TakeSubscriptionByID(ctx context.Context, subscriptionID int) (subscription model.WebhookSubscription, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.WebhookSubscription{}).
+		Where("id", subscriptionID).
+		Take(&subscription)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) FindSubscriptions(ctx context.Context) (subscriptions []model.WebhookSubscription, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.WebhookSubscription{}).
+		Order("id").
+		Find(&subscriptions)
+	err = query.Error
+	return
+}
+
+// FindSubscriptionsByEvent returns active subscriptions listening to the event type,
+// either explicitly or through the wildcard
+func (repo *Repository) FindSubscriptionsByEvent(ctx context.Context, eventType string) (subscriptions []model.WebhookSubscription, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.WebhookSubscription{}).
+		Where("is_active").
+		Where("(',' || event_types || ',') LIKE ? OR (',' || event_types || ',') LIKE ?",
+			"%,"+eventType+",%", "%,"+constant.EventWildcard+",%").
+		Find(&subscriptions)
+	err = query.Error
+	return
+}
+
+func (repo *Repository) CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) (subscriptionID int, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&subscription).Begin().
+		Create(&subscription)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	subscriptionID = int(subscription.ID)
+	return
+}
+
+func (repo *Repository) UpdateSubscription(ctx context.Context, subscriptionID int, request model.WebhookSubscription, columns []string) (err error) {
+	subscription := &model.WebhookSubscription{}
+	query := repo.dbMaster.WithContext(ctx).Model(&subscription).Begin().
+		Where("id", subscriptionID).
+		Select(columns).
+		Updates(request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+func (repo *Repository) DeleteSubscription(ctx context.Context, subscriptionID int) (err error) {
+	subscription := &model.WebhookSubscription{}
+	query := repo.dbMaster.WithContext(ctx).Model(subscription).Begin().
+		Where("id", subscriptionID).
+		Delete(subscription)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// FindDeliveries pages through deliveries newest first, subscriptionID and status are
+// skipped when empty
+func (repo *Repository) FindDeliveries(ctx context.Context, subscriptionID int, status string, pgn pagination.Pagination) (deliveries []model.WebhookDelivery, total int64, err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.WebhookDelivery{})
+	if subscriptionID != 0 {
+		query = query.Where("subscription_id", subscriptionID)
+	}
+	if status != "" {
+		query = query.Where("status", status)
+	}
+
+	err = query.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = query.Order("id desc").
+		Limit(pgn.Limit).
+		Offset(pgn.Offset).
+		Find(&deliveries).Error
+	return
+}
+
+func (repo *Repository) CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) (err error) {
+	query := repo.dbMaster.WithContext(ctx).Model(&model.WebhookDelivery{}).Begin().
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&deliveries)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// ClaimDeliveries locks due deliveries and pushes their next attempt past the lease, so
+// other workers skip them while they are being sent
+func (repo *Repository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []model.WebhookDelivery, err error) {
+	err = repo.dbMaster.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.WebhookDelivery{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status", constant.DeliveryStatusPending).
+			Where("next_attempt_at <= ?", time.Now().UTC()).
+			Order("id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		deliveryIDs := make([]int, len(deliveries))
+		for i := range deliveries {
+			deliveryIDs[i] = deliveries[i].ID
+		}
+		return tx.Model(&model.WebhookDelivery{}).
+			Where("id IN ?", deliveryIDs).
+			Update("next_attempt_at", time.Now().UTC().Add(lease)).Error
+	})
+	return
+}
+
+func (repo *Repository) UpdateDelivery(ctx context.Context, deliveryID int, request model.WebhookDelivery, columns []string) (err error) {
+	delivery := &model.WebhookDelivery{}
+	query := repo.dbMaster.WithContext(ctx).Model(&delivery).Begin().
+		Where("id", deliveryID).
+		Select(columns).
+		Updates(request)
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
+
+// RequeueDelivery moves a dead delivery back to pending with a fresh attempt budget
+func (repo *Repository) RequeueDelivery(ctx context.Context, deliveryID int) (err error) {
+	delivery := &model.WebhookDelivery{}
+	query := repo.dbMaster.WithContext(ctx).Model(delivery).Begin().
+		Where("id", deliveryID).
+		Where("status", constant.DeliveryStatusDead).
+		Updates(map[string]interface{}{
+			"status":          constant.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC(),
+		})
+	err = query.Error
+	if err != nil {
+		query.Rollback()
+		return
+	}
+	if query.RowsAffected != 1 {
+		query.Rollback()
+		err = constant.ErrInvalidID
+		return
+	}
+
+	err = query.Commit().Error
+	return
+}
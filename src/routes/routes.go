@@ -2,28 +2,72 @@ package routes
 
 import (
 	//"fmt"
+	"context"
+	"fmt"
 	"os"
+	"time"
 
 	echo "github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/joho/godotenv"
 	"go-rest-api/docs"
+	"go-rest-api/src/config"
 	"go-rest-api/src/connection"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/pkg/broker"
+	"go-rest-api/src/pkg/envelope"
+	"go-rest-api/src/pkg/health"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/identity"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/lifecycle"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/mail"
+	"go-rest-api/src/pkg/metrics"
+	"go-rest-api/src/pkg/pubsub"
+	"go-rest-api/src/pkg/rest"
+	"go-rest-api/src/pkg/storage"
+	"go-rest-api/src/pkg/tracing"
 	"gorm.io/gorm"
 
 	authController "go-rest-api/src/controller/v1/auth"
 	accountController "go-rest-api/src/controller/v1/account"
 	attendanceController "go-rest-api/src/controller/v1/attendance"
+	auditController "go-rest-api/src/controller/v1/audit"
+	deviceController "go-rest-api/src/controller/v1/device"
+	healthController "go-rest-api/src/controller/v1/health"
+	invitationController "go-rest-api/src/controller/v1/invitation"
 	locationController "go-rest-api/src/controller/v1/location"
+	organizationController "go-rest-api/src/controller/v1/organization"
+	outboxController "go-rest-api/src/controller/v1/outbox"
+	photoController "go-rest-api/src/controller/v1/photo"
+	privacyController "go-rest-api/src/controller/v1/privacy"
+	webhookController "go-rest-api/src/controller/v1/webhook"
 
 	accountRepository "go-rest-api/src/repository/v1/account"
 	attendanceRepository "go-rest-api/src/repository/v1/attendance"
+	auditRepository "go-rest-api/src/repository/v1/audit"
+	deviceRepository "go-rest-api/src/repository/v1/device"
+	invitationRepository "go-rest-api/src/repository/v1/invitation"
 	locationRepository "go-rest-api/src/repository/v1/location"
+	organizationRepository "go-rest-api/src/repository/v1/organization"
+	outboxRepository "go-rest-api/src/repository/v1/outbox"
+	privacyRepository "go-rest-api/src/repository/v1/privacy"
+	webhookRepository "go-rest-api/src/repository/v1/webhook"
 
 	accountService "go-rest-api/src/service/v1/account"
 	attendanceService "go-rest-api/src/service/v1/attendance"
+	auditService "go-rest-api/src/service/v1/audit"
+	deviceService "go-rest-api/src/service/v1/device"
+	encryptionService "go-rest-api/src/service/v1/encryption"
+	invitationService "go-rest-api/src/service/v1/invitation"
 	locationService "go-rest-api/src/service/v1/location"
+	organizationService "go-rest-api/src/service/v1/organization"
+	outboxService "go-rest-api/src/service/v1/outbox"
+	photoService "go-rest-api/src/service/v1/photo"
+	privacyService "go-rest-api/src/service/v1/privacy"
+	webhookService "go-rest-api/src/service/v1/webhook"
 
+	"github.com/forkyid/go-utils/v1/validation"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
@@ -34,31 +78,124 @@ type DB struct {
 	Master *gorm.DB
 }
 
-func Run() {	
-	godotenv.Load()
-	RouterSetup()
-	router.Logger.Fatal(router.Start(":5000"))
-	// router.Start(fmt.Sprintf(":%s", os.Getenv("SERVER_PORT")))
+// Run serves until SIGINT or SIGTERM, then drains the connections, stops the
+// workers and closes the database
+func Run(cfg config.Config) {
+	app := lifecycle.New()
+	RouterSetup(cfg, app)
+
+	router.HideBanner = true
+	router.Server.ReadTimeout = cfg.Server.ReadTimeout
+	router.Server.WriteTimeout = cfg.Server.WriteTimeout
+	router.Server.IdleTimeout = cfg.Server.IdleTimeout
+	router.TLSServer.ReadTimeout = cfg.Server.ReadTimeout
+	router.TLSServer.WriteTimeout = cfg.Server.WriteTimeout
+	router.TLSServer.IdleTimeout = cfg.Server.IdleTimeout
+
+	address := fmt.Sprintf(":%d", cfg.Server.Port)
+	err := app.Run(func() error {
+		if cfg.Server.TLS() {
+			return router.StartTLS(address, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		}
+		return router.Start(address)
+	}, func(ctx context.Context) error {
+		select {
+		case <-time.After(cfg.Server.ShutdownDelay):
+		case <-ctx.Done():
+		}
+		return router.Shutdown(ctx)
+	}, cfg.Server.ShutdownTimeout)
+	if err != nil {
+		fatal("serve", err)
+	}
 }
 
-func RouterSetup() {
+func RouterSetup(cfg config.Config, app *lifecycle.Lifecycle) {
 	// set up
-	router.Use(middleware.Logger())
+	router.HTTPErrorHandler = rest.HTTPErrorHandler
+	rest.SetProblemTypeURL(cfg.Server.ProblemTypeURL)
+	ipExtractor, err := rest.IPExtractor(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal("set up trusted proxies", err)
+	}
+	router.IPExtractor = ipExtractor
+	router.Use(rest.RequestID())
+	router.Use(tracing.Middleware())
+	router.Use(metrics.Middleware())
+	router.Use(logging.Middleware())
 	router.Use(middleware.CORS())
 
 	// swagger
 	docs.SwaggerInfo.Title = "Phincon Attendance App Rest API"
 	docs.SwaggerInfo.Description = "Phincon Attendance App Rest API"
 	docs.SwaggerInfo.Version = "1.0"
-	docs.SwaggerInfo.Host = os.Getenv("SWAGGER_HOST")
+	docs.SwaggerInfo.Host = cfg.Swagger.Host
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 	router.GET("/swagger/*any", func(c echo.Context) error {
 		echoSwagger.WrapHandler(c)
 		return nil
 	})
 
+	// tracing, registered before the database so spans are flushed after it closed
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.ServiceName, cfg.Tracing)
+	if err != nil {
+		fatal("set up tracing", err)
+	}
+	app.OnStop("tracing", shutdownTracing)
+
 	// database connection (type *gorm.DB)
-	master = connection.DBMaster()
+	master = connection.DBMaster(cfg.Database)
+	app.OnStop("database", connection.Close)
+	if err := metrics.RegisterDB(master, cfg.Database.Name); err != nil {
+		fatal("register database metrics", err)
+	}
+	if err := master.Use(tracing.GormPlugin{}); err != nil {
+		fatal("register database tracing", err)
+	}
+
+	// health, readiness fails once the shutdown starts
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	checker.Register("database", func(ctx context.Context) error {
+		pool, err := master.DB()
+		if err != nil {
+			return err
+		}
+		return pool.PingContext(ctx)
+	})
+	app.OnShutdown(checker.Drain)
+
+	// bearer and invitation tokens
+	jwt.SetSecretKey(cfg.JWT.SecretKey)
+
+	// field encryption, encrypted account columns are read and written with this keyring
+	fieldKeyring, err := envelope.New(cfg.FieldEncryption)
+	if err != nil {
+		fatal("load field encryption keys", err)
+	}
+	envelope.SetDefault(fieldKeyring)
+
+	// request validation, nik and phone number tags
+	if err := identity.RegisterValidations(validation.Validator); err != nil {
+		fatal("register validations", err)
+	}
+
+	// blob storage
+	blobStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		fatal("set up storage", err)
+	}
+
+	// mail
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		fatal("set up mail", err)
+	}
+
+	// attendance event hub
+	hub := pubsub.NewHub(constant.StreamReplaySize, constant.StreamSubscriberBufferSize)
+	// open streams would keep the server from draining
+	router.Server.RegisterOnShutdown(hub.Close)
+	router.TLSServer.RegisterOnShutdown(hub.Close)
 
 	// repository
 	accountRepo := accountRepository.NewRepository(connection.DB{
@@ -70,83 +207,201 @@ func RouterSetup() {
 	locationRepo := locationRepository.NewRepository(connection.DB{
 		Master: master,
 	})
+	deviceRepo := deviceRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+	webhookRepo := webhookRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+	organizationRepo := organizationRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+	invitationRepo := invitationRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+
+	// accounts stored before the blind indexes existed are invisible to lookups until
+	// the re-encrypt command filled their indexes
+	err = encryptionService.NewService(accountRepo, fieldKeyring).CheckBlindIndexes(context.Background())
+	if err != nil {
+		fatal("check blind indexes", err)
+	}
+	outboxRepo := outboxRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+	privacyRepo := privacyRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+	auditRepo := auditRepository.NewRepository(connection.DB{
+		Master: master,
+	})
+
+	// outbox relay
+	sinks, err := outboxSinks(app, cfg, hub, webhookRepo)
+	if err != nil {
+		fatal("set up outbox sinks", err)
+	}
+	relay := outboxService.NewRelay(outboxRepo, cfg.Outbox, sinks...)
 
 	// service
-	accountSvc := accountService.NewService(accountRepo)
-	locationSvc := locationService.NewService(locationRepo)
-	attendanceSvc := attendanceService.NewService(attendanceRepo, accountSvc, locationSvc)
-	
+	photoSvc := photoService.NewService(blobStorage, cfg.Storage.PhotoBaseURL)
+	accountSvc := accountService.NewService(accountRepo, relay, mailer, photoSvc, cfg.Registration)
+	locationSvc := locationService.NewService(locationRepo, relay, photoSvc)
+	deviceSvc := deviceService.NewService(deviceRepo, accountSvc, cfg.Attendance)
+	attendanceSvc := attendanceService.NewService(attendanceRepo, accountSvc, locationSvc, deviceSvc, blobStorage, hub, relay, cfg.Attendance)
+	webhookSvc := webhookService.NewService(webhookRepo, accountSvc, cfg.Webhook)
+	outboxSvc := outboxService.NewService(outboxRepo, accountSvc, relay)
+	organizationSvc := organizationService.NewService(organizationRepo, accountSvc)
+	invitationSvc := invitationService.NewService(invitationRepo, accountSvc, organizationSvc, mailer, cfg.Registration)
+	auditSvc := auditService.NewService(auditRepo, accountSvc)
+	privacySvc := privacyService.NewService(privacyRepo, accountSvc, attendanceSvc, deviceSvc, auditSvc, blobStorage)
+
+	// background workers
+	app.Go("outbox relay", relay.Run)
+	app.Go("webhook delivery", webhookSvc.Run)
+
 	// controller
 	authController := authController.NewController(accountSvc)
 	accountController := accountController.NewController(accountSvc)
 	attendanceController := attendanceController.NewController(attendanceSvc)
 	locationController := locationController.NewController(locationSvc)
+	deviceController := deviceController.NewController(deviceSvc)
+	webhookController := webhookController.NewController(webhookSvc)
+	outboxController := outboxController.NewController(outboxSvc)
+	organizationController := organizationController.NewController(organizationSvc)
+	invitationController := invitationController.NewController(invitationSvc)
+	photoController := photoController.NewController(photoSvc)
+	privacyController := privacyController.NewController(privacySvc)
+	healthController := healthController.NewController(checker)
+	auditController := auditController.NewController(auditSvc)
+
+	// metrics
+	router.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	// health
+	router.GET("/healthz", healthController.Live)
+	router.GET("/readyz", healthController.Ready)
 
 	// endpoint v1
 	v1 := router.Group("/v1")
 
 	auth := v1.Group("/auth")
-	auth.POST("", func(c echo.Context) error {
-		authController.Login(c)
-		return nil
-	})
-	auth.PATCH("/forgot", func(c echo.Context) error {
-		authController.ForgotPassword(c)
-		return nil
-	})
+	auth.POST("", authController.Login)
+	auth.PATCH("/forgot", authController.ForgotPassword)
 
 	accounts := v1.Group("/accounts")
-	accounts.GET("", func(c echo.Context) error {
-		accountController.Get(c)
-		return nil
-	})
-	accounts.POST("/register", func(c echo.Context) error {
-		accountController.Register(c)
-		return nil
-	})
-	accounts.PATCH("", func(c echo.Context) error {
-		accountController.Update(c)
-		return nil
-	})
-	accounts.DELETE("", func(c echo.Context) error {
-		accountController.Delete(c)
-		return nil
-	})
+	accounts.GET("", accountController.Get)
+	accounts.POST("/register", accountController.Register)
+	accounts.PATCH("", accountController.Update)
+	accounts.DELETE("", accountController.Delete)
+	accounts.GET("/reports", accountController.GetReports)
+	accounts.PUT("/photo", accountController.UpdatePhoto)
+	accounts.GET("/me/export", privacyController.Export)
+	accounts.POST("/me/erasure", privacyController.RequestErasure)
+
+	photos := v1.Group("/photos")
+	photos.GET("/placeholder.svg", photoController.GetPlaceholder)
+	photos.GET("/*", photoController.Get)
+
+	invitations := v1.Group("/invitations")
+	invitations.GET("/verify", invitationController.Verify)
+	invitations.POST("/accept", invitationController.Accept)
 
 	attendance := v1.Group("/attendance")
-	attendance.GET("/history", func(c echo.Context) error {
-		attendanceController.Get(c)
-		return nil
-	})
-	attendance.GET("/locations",  func(c echo.Context) error {
-		attendanceController.GetByLocation(c)
-		return nil
-	})
-	attendance.POST("", func(c echo.Context) error {
-		attendanceController.Add(c)
-		return nil
-	})
+	attendance.GET("/history", attendanceController.Get)
+	attendance.GET("/locations", attendanceController.GetByLocation)
+	attendance.POST("", attendanceController.Add)
+	attendance.GET("/stream", attendanceController.Stream)
+	attendance.POST("/stream/ticket", attendanceController.StreamTicket)
+	attendance.POST("/batch", attendanceController.AddBatch)
+	attendance.GET("/:id/photo", attendanceController.GetPhoto)
+	attendance.GET("/reports/:id", attendanceController.GetReport)
 
 	location := v1.Group("/locations")
-	location.GET("", func(c echo.Context) error {
-		locationController.Get(c)
-		return nil
-	})
-	location.POST("", func(c echo.Context) error {
-		locationController.Create(c)
-		return nil
-	})
-	location.PATCH("", func(c echo.Context) error {
-		locationController.Update(c)
-		return nil
-	})
-	location.DELETE("", func(c echo.Context) error {
-		locationController.Delete(c)
-		return nil
-	})
+	location.GET("", locationController.Get)
+	location.POST("", locationController.Create)
+	location.PATCH("", locationController.Update)
+	location.DELETE("", locationController.Delete)
+	location.PUT("/photo", locationController.UpdatePhoto)
+	location.GET("/presence", attendanceController.GetPresenceSummary)
+	location.GET("/:id/presence", attendanceController.GetPresence)
+
+	departments := v1.Group("/departments")
+	departments.GET("", organizationController.GetDepartments)
+	departments.POST("", organizationController.CreateDepartment)
+	departments.PATCH("/:id", organizationController.UpdateDepartment)
+	departments.DELETE("/:id", organizationController.DeleteDepartment)
+
+	teams := v1.Group("/teams")
+	teams.GET("", organizationController.GetTeams)
+	teams.POST("", organizationController.CreateTeam)
+	teams.PATCH("/:id", organizationController.UpdateTeam)
+	teams.DELETE("/:id", organizationController.DeleteTeam)
+
+	devices := v1.Group("/devices")
+	devices.GET("", deviceController.Get)
+	devices.POST("", deviceController.Register)
+
+	admin := v1.Group("/admin")
+	admin.GET("/accounts", accountController.GetDirectory)
+	admin.POST("/accounts/import", accountController.Import)
+	admin.GET("/accounts/:id/devices", deviceController.GetByAccount)
+	admin.PATCH("/accounts/:id/device-binding", deviceController.UpdateBinding)
+	admin.PATCH("/accounts/:id/reporting-line", organizationController.UpdateReportingLine)
+	admin.DELETE("/devices/:id", deviceController.Revoke)
+	admin.GET("/invitations", invitationController.Get)
+	admin.POST("/invitations", invitationController.Create)
+	admin.POST("/invitations/:id/resend", invitationController.Resend)
+	admin.DELETE("/invitations/:id", invitationController.Revoke)
+	admin.GET("/erasure-requests", privacyController.GetErasureRequests)
+	admin.POST("/erasure-requests/:id/approve", privacyController.ApproveErasureRequest)
+	admin.POST("/erasure-requests/:id/reject", privacyController.RejectErasureRequest)
+	admin.GET("/audit", auditController.Get)
+	admin.GET("/webhooks", webhookController.Get)
+	admin.POST("/webhooks", webhookController.Create)
+	admin.GET("/webhooks/dead-letters", webhookController.GetDeadLetters)
+	admin.PATCH("/webhooks/:id", webhookController.Update)
+	admin.DELETE("/webhooks/:id", webhookController.Delete)
+	admin.GET("/webhooks/:id/deliveries", webhookController.GetDeliveries)
+	admin.POST("/webhooks/deliveries/:id/retry", webhookController.Retry)
+	admin.GET("/outbox/dead-letters", outboxController.GetDeadLetters)
+	admin.POST("/outbox/events/:id/retry", outboxController.Retry)
 
 	// endpoint v2
 
 	// endpoint v3
 
 }
+
+// outboxSinks builds the sinks listed in the outbox config
+func outboxSinks(app *lifecycle.Lifecycle, cfg config.Config, hub *pubsub.Hub, webhookRepo webhookRepository.Repositorier) (sinks []event.Sink, err error) {
+	for _, name := range cfg.Outbox.Sinks {
+		switch name {
+		case constant.SinkStream:
+			channel := pubsub.NewPostgresChannel(master, cfg.Database.DSN(), constant.StreamChannel)
+			streamSink := attendanceService.NewStreamSink(hub, channel)
+			app.Go("attendance stream", streamSink.Run)
+			sinks = append(sinks, streamSink)
+		case constant.SinkWebhook:
+			sinks = append(sinks, webhookService.NewSink(webhookRepo))
+		case constant.SinkNATS:
+			publisher, err := broker.NewNATS(cfg.Outbox.NATSURL)
+			if err != nil {
+				return nil, err
+			}
+			app.OnStop("nats", func() error {
+				publisher.Close()
+				return nil
+			})
+			sinks = append(sinks, broker.NewSink(publisher, cfg.Outbox.NATSSubject))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return
+}
+
+func fatal(msg string, err error) {
+	logging.Default().Error(msg, "error", err)
+	os.Exit(1)
+}
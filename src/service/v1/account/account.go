@@ -1,50 +1,124 @@
 package account
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/forkyid/go-utils/v1/validation"
 	"github.com/jinzhu/copier"
 	"github.com/pkg/errors"
+	"go-rest-api/src/config"
 	"go-rest-api/src/constant"
 	"go-rest-api/src/http"
 	"go-rest-api/src/model"
 	"go-rest-api/src/pkg/bcrypt"
+	"go-rest-api/src/pkg/envelope"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/identity"
+	"go-rest-api/src/pkg/imaging"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/mail"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/metrics"
+	"go-rest-api/src/pkg/sheet"
+	"go-rest-api/src/pkg/tracing"
 	"go-rest-api/src/repository/v1/account"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/repository/v1/outbox"
+	"go-rest-api/src/service/v1/photo"
 	"gorm.io/gorm"
 )
 
+// avatarVariants are the sizes an uploaded account photo is stored in, the photo url
+// points to the medium one
+var avatarVariants = []imaging.Variant{
+	{Name: constant.PhotoVariantLarge, Width: 512, Height: 512, Crop: true},
+	{Name: constant.PhotoVariantMedium, Width: 256, Height: 256, Crop: true},
+	{Name: constant.PhotoVariantSmall, Width: 64, Height: 64, Crop: true},
+}
+
 type Service struct {
-	repo account.Repositorier
+	repo   account.Repositorier
+	outbox event.Notifier
+	mailer mail.Mailer
+	photo  photo.Servicer
+	config config.Registration
 }
 
 func NewService(
 	repositorier account.Repositorier,
+	notifier event.Notifier,
+	mailer mail.Mailer,
+	photoServicer photo.Servicer,
+	cfg config.Registration,
 ) *Service {
 	return &Service{
-		repo: repositorier,
+		repo:   repositorier,
+		outbox: notifier,
+		mailer: mailer,
+		photo:  photoServicer,
+		config: cfg,
 	}
 }
 
 type Servicer interface {
-	TakeAccountByID(accountID int) (accounts http.GetUser, err error)
-	TakeAccountByKTPNumber(ktpNumber string) (account model.Account, err error)
-	TakeAccountByUsername(username string) (account model.Account, err error)
-	Find(accountIDs []int) (accounts []http.GetUser, err error)
-	CheckAccountByID(accountID int) (exist bool, err error)
-	CheckAccountByEmail(email string) (exist bool, err error)
-	CheckAccountByKTPNumber(ktpNumber string) (exist bool, err error)
-	CheckAccountByPhoneNumber(phoneNumber string) (exist bool, err error)
-	CheckAccountByUsername(username string) (exist bool, err error)
-	Create(request http.RegisterUser) (err error)
-	Update(accountID int, request http.UpdateUser) (err error)
-	UpdatePassword(request http.ForgotPassword) (err error)
-	Delete(accountID int) (err error)
-}
-
-func (svc *Service) TakeAccountByID(accountID int) (account http.GetUser, err error) {
-	takeUser, err := svc.repo.TakeAccountByID(accountID)
+	TakeAccountByID(ctx context.Context, accountID int) (accounts http.GetUser, err error)
+	TakeProfile(ctx context.Context, accountID int) (profile http.ExportProfile, err error)
+	TakeAccountByKTPNumber(ctx context.Context, ktpNumber string) (account model.Account, err error)
+	TakeAccountByUsername(ctx context.Context, username string) (account model.Account, err error)
+	Find(ctx context.Context, accountIDs []int) (accounts []http.GetUser, err error)
+	FindDirectory(ctx context.Context, adminID int, request http.FindAccounts, pgn pagination.Pagination) (accounts []http.GetAccount, total int, err error)
+	CheckAccountByID(ctx context.Context, accountID int) (exist bool, err error)
+	CheckAccountByEmail(ctx context.Context, email string) (exist bool, err error)
+	CheckAccountByKTPNumber(ctx context.Context, ktpNumber string) (exist bool, err error)
+	CheckAccountByPhoneNumber(ctx context.Context, phoneNumber string) (exist bool, err error)
+	CheckAccountByUsername(ctx context.Context, username string) (exist bool, err error)
+	CheckAccountRole(ctx context.Context, accountID int, roles ...string) (allowed bool, err error)
+	Login(ctx context.Context, request http.Auth) (account model.Account, err error)
+	Create(ctx context.Context, actor model.Actor, request http.RegisterUser) (err error)
+	CreateInvited(ctx context.Context, actor model.Actor, request http.RegisterUser, invitation model.Invitation) (err error)
+	Import(ctx context.Context, actor model.Actor, rows [][]string, dryRun bool) (result http.ImportAccountsResult, err error)
+	Update(ctx context.Context, actor model.Actor, accountID int, request http.UpdateUser) (err error)
+	UpdatePhoto(ctx context.Context, actor model.Actor, accountID int, request http.Photo) (photoURL string, err error)
+	UpdatePassword(ctx context.Context, actor model.Actor, request http.ForgotPassword) (err error)
+	UpdateRequireBoundDevice(ctx context.Context, actor model.Actor, accountID int, required bool) (err error)
+	UpdateReportingLine(ctx context.Context, actor model.Actor, accountID int, departmentID, teamID, managerID *int) (err error)
+	FindReports(ctx context.Context, managerID int, direct bool) (accounts []http.GetUser, err error)
+	CheckReport(ctx context.Context, managerID int, accountID int) (isReport bool, err error)
+	Delete(ctx context.Context, actor model.Actor, accountID int) (err error)
+	Erase(ctx context.Context, actor model.Actor, accountID int) (err error)
+}
+
+func (svc *Service) TakeAccountByID(ctx context.Context, accountID int) (account http.GetUser, err error) {
+	ctx, span := tracing.Start(ctx, "account.TakeAccountByID")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	takeUser, err := svc.repo.TakeAccountByID(ctx, accountID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrAccountNotRegistered
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take account")
+		return
+	}
+
+	account = svc.getUser(takeUser)
+	return
+}
+
+// TakeProfile returns the account with all of its personal data, it is meant for the
+// account itself
+func (svc *Service) TakeProfile(ctx context.Context, accountID int) (profile http.ExportProfile, err error) {
+	takeUser, err := svc.repo.TakeAccountByID(ctx, accountID)
 	if err == gorm.ErrRecordNotFound {
 		err = constant.ErrAccountNotRegistered
 		return
@@ -53,14 +127,20 @@ func (svc *Service) TakeAccountByID(accountID int) (account http.GetUser, err er
 		return
 	}
 
-	account = http.GetUser{}
-	copier.Copy(&account, &takeUser)
-	account.ID = aes.Encrypt(int(takeUser.ID))
+	profile = http.ExportProfile{
+		GetUser:     svc.getUser(takeUser),
+		PhoneNumber: plaintext(takeUser.PhoneNumber),
+		KTPNumber:   plaintext(takeUser.KTPNumber),
+		Gender:      takeUser.Gender,
+		DateOfBirth: plaintext(takeUser.DateOfBirth),
+		IsVerified:  takeUser.IsVerified,
+		CreatedAt:   takeUser.CreatedAt,
+	}
 	return
 }
 
-func (svc *Service) TakeAccountByKTPNumber(ktpNumber string) (account model.Account, err error) {
-	account, err = svc.repo.TakeAccountByKTPNumber(ktpNumber)
+func (svc *Service) TakeAccountByKTPNumber(ctx context.Context, ktpNumber string) (account model.Account, err error) {
+	account, err = svc.repo.TakeAccountByKTPNumber(ctx, ktpNumber)
 	if err == gorm.ErrRecordNotFound {
 		err = constant.ErrAccountNotRegistered
 		return
@@ -71,8 +151,13 @@ func (svc *Service) TakeAccountByKTPNumber(ktpNumber string) (account model.Acco
 	return
 }
 
-func (svc *Service) TakeAccountByUsername(username string) (account model.Account, err error) {
-	account, err = svc.repo.TakeAccountByUsername(username)
+func (svc *Service) TakeAccountByUsername(ctx context.Context, username string) (account model.Account, err error) {
+	ctx, span := tracing.Start(ctx, "account.TakeAccountByUsername")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	account, err = svc.repo.TakeAccountByUsername(ctx, username)
 	if err == gorm.ErrRecordNotFound {
 		err = constant.ErrAccountNotRegistered
 		return
@@ -83,25 +168,59 @@ func (svc *Service) TakeAccountByUsername(username string) (account model.Accoun
 	return
 }
 
-func (svc *Service) Find(accountIDs []int) (accounts []http.GetUser, err error) {
-	users, err := svc.repo.Find(accountIDs)
+func (svc *Service) Find(ctx context.Context, accountIDs []int) (accounts []http.GetUser, err error) {
+	users, err := svc.repo.Find(ctx, accountIDs)
 	if err != nil {
 		err = errors.Wrap(err, "find accounts")
 		return
 	}
 	for i := range users {
-		account := http.GetUser{}
-		copier.Copy(&account, &users[i])
-		account.ID = aes.Encrypt(int(users[i].ID))
-		log.Print(users[i].PhotoURL)
+		account := svc.getUser(users[i])
 		accounts = append(accounts, account)
 	} 
 	return
 }
 
-func (svc *Service) CheckAccountByID(accountID int) (exist bool, err error) {
+// FindDirectory lists accounts for admins, deleted accounts are left out unless asked for
+func (svc *Service) FindDirectory(ctx context.Context, adminID int, request http.FindAccounts, pgn pagination.Pagination) (accounts []http.GetAccount, total int, err error) {
+	allowed, err := svc.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+
+	filter := model.AccountFilter{}
+	copier.Copy(&filter, &request)
+
+	users, totalData, err := svc.repo.FindDirectory(ctx, filter, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find account directory")
+		return
+	}
+
+	accounts = []http.GetAccount{}
+	for i := range users {
+		account := http.GetAccount{
+			GetUser:    svc.getUser(users[i]),
+			IsVerified: users[i].IsVerified,
+			CreatedAt:  users[i].CreatedAt,
+		}
+		if users[i].DeletedAt.Valid {
+			account.DeletedAt = &users[i].DeletedAt.Time
+		}
+		accounts = append(accounts, account)
+	}
+	total = int(totalData)
+	return
+}
+
+func (svc *Service) CheckAccountByID(ctx context.Context, accountID int) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeAccountByID(accountID)
+	_, err = svc.repo.TakeAccountByID(ctx, accountID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -116,9 +235,9 @@ func (svc *Service) CheckAccountByID(accountID int) (exist bool, err error) {
 	return
 }
 
-func (svc *Service) CheckAccountByEmail(email string) (exist bool, err error) {
+func (svc *Service) CheckAccountByEmail(ctx context.Context, email string) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeAccountByEmail(email)
+	_, err = svc.repo.TakeAccountByEmail(ctx, email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -133,9 +252,9 @@ func (svc *Service) CheckAccountByEmail(email string) (exist bool, err error) {
 	return
 }
 
-func (svc *Service) CheckAccountByKTPNumber(ktpNumber string) (exist bool, err error) {
+func (svc *Service) CheckAccountByKTPNumber(ctx context.Context, ktpNumber string) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeAccountByKTPNumber(ktpNumber)
+	_, err = svc.repo.TakeAccountByKTPNumber(ctx, ktpNumber)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -150,9 +269,9 @@ func (svc *Service) CheckAccountByKTPNumber(ktpNumber string) (exist bool, err e
 	return
 }
 
-func (svc *Service) CheckAccountByPhoneNumber(phoneNumber string) (exist bool, err error) {
+func (svc *Service) CheckAccountByPhoneNumber(ctx context.Context, phoneNumber string) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeAccountByPhoneNumber(phoneNumber)
+	_, err = svc.repo.TakeAccountByPhoneNumber(ctx, phoneNumber)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -167,9 +286,14 @@ func (svc *Service) CheckAccountByPhoneNumber(phoneNumber string) (exist bool, e
 	return
 }
 
-func (svc *Service) CheckAccountByUsername(username string) (exist bool, err error) {
+func (svc *Service) CheckAccountByUsername(ctx context.Context, username string) (exist bool, err error) {
+	ctx, span := tracing.Start(ctx, "account.CheckAccountByUsername")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	exist = false
-	_, err = svc.repo.TakeAccountByUsername(username)
+	_, err = svc.repo.TakeAccountByUsername(ctx, username)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -184,8 +308,67 @@ func (svc *Service) CheckAccountByUsername(username string) (exist bool, err err
 	return
 }
 
-func (svc *Service) Create(request http.RegisterUser) (err error) {
-	exist, err := svc.CheckAccountByUsername(request.Username)
+func (svc *Service) CheckAccountRole(ctx context.Context, accountID int, roles ...string) (allowed bool, err error) {
+	account, err := svc.repo.TakeAccountByID(ctx, accountID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrAccountNotRegistered
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+
+	for i := range roles {
+		if account.Role == roles[i] {
+			allowed = true
+			return
+		}
+	}
+	return
+}
+
+// Login takes the account the username and password belong to, failed logins are
+// counted by whether the account or the password was wrong
+func (svc *Service) Login(ctx context.Context, request http.Auth) (account model.Account, err error) {
+	ctx, span := tracing.Start(ctx, "account.Login")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	exist, err := svc.CheckAccountByUsername(ctx, request.Username)
+	if err != nil {
+		return
+	}
+	if !exist {
+		// the password is still compared so an unknown username takes as long as a wrong
+		// password, the answer is the same for both
+		comparePassword(ctx, unknownAccountPassword, request.Password)
+		metrics.FailedLogins.WithLabelValues(constant.LoginFailureUnknownAccount).Inc()
+		err = constant.ErrInvalidCredentials
+		return
+	}
+
+	account, err = svc.TakeAccountByUsername(ctx, request.Username)
+	if err != nil {
+		return
+	}
+
+	err = comparePassword(ctx, account.Password, request.Password)
+	if err != nil {
+		metrics.FailedLogins.WithLabelValues(constant.LoginFailureWrongPassword).Inc()
+		err = constant.ErrInvalidCredentials
+		return
+	}
+	return
+}
+
+func (svc *Service) Create(ctx context.Context, actor model.Actor, request http.RegisterUser) (err error) {
+	if !svc.config.Open {
+		err = constant.ErrInvitationRequired
+		return
+	}
+
+	exist, err := svc.CheckAccountByUsername(ctx, request.Username)
 	if err != nil {
 		return
 	}
@@ -197,27 +380,404 @@ func (svc *Service) Create(request http.RegisterUser) (err error) {
 		newAccount := model.Account{}
 		copier.Copy(&newAccount, &request)
 
-		hashedPassword, err := bcrypt.HashPassword(newAccount.Password)
+		hashedPassword, err := hashPassword(ctx, newAccount.Password)
 		if err != nil {
 			err = errors.Wrap(err, "hash password")
 			return err
 		}
 		newAccount.Password = hashedPassword
-		newAccount.PhotoURL = "https://thumbs.dreamstime.com/b/user-profile-avatar-solid-black-line-icon-simple-vector-filled-flat-pictogram-isolated-white-background-134042540.jpg"
 		newAccount.Gender = "none"
 		newAccount.IsVerified = false
+		newAccount.Role = constant.RoleEmployee
 
-		err = svc.repo.Create(newAccount)
+		_, err = svc.repo.Create(ctx, newAccount, outboxEvents(constant.EventAccountCreated, svc.getUser(newAccount)), svc.auditEntries(actor, constant.AuditActionCreate, nil, &newAccount))
 		if err != nil {
 			err = errors.Wrap(err, "create new account")
 			return err
 		}
+		svc.outbox.Notify()
 	}
 	return
 }
 
-func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
-	exist, _ := svc.CheckAccountByID(accountID)
+// CreateInvited registers the account of an invitation, the email, role, department and
+// team come from the invitation and the email counts as verified since the invitation
+// link was sent to it
+func (svc *Service) CreateInvited(ctx context.Context, actor model.Actor, request http.RegisterUser, invitation model.Invitation) (err error) {
+	exist, err := svc.CheckAccountByUsername(ctx, request.Username)
+	if err != nil {
+		return
+	}
+	if exist {
+		err = constant.ErrAccountExist
+		return
+	}
+
+	hashedPassword, err := hashPassword(ctx, request.Password)
+	if err != nil {
+		err = errors.Wrap(err, "hash password")
+		return
+	}
+
+	email := invitation.Email
+	newAccount := model.Account{
+		Username:     request.Username,
+		FullName:     request.FullName,
+		Email:        &email,
+		Password:     hashedPassword,
+		Gender:       "none",
+		IsVerified:   true,
+		Role:         invitation.Role,
+		DepartmentID: invitation.DepartmentID,
+		TeamID:       invitation.TeamID,
+	}
+
+	_, err = svc.repo.CreateInvited(ctx, newAccount, int(invitation.ID), outboxEvents(constant.EventAccountCreated, svc.getUser(newAccount)), svc.auditEntries(actor, constant.AuditActionCreate, nil, &newAccount))
+	if errors.Is(err, constant.ErrInvitationAlreadyUsed) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "create invited account")
+		return
+	}
+	svc.outbox.Notify()
+	return
+}
+
+// Import registers the accounts of a spreadsheet, the first row names the columns.
+// Every row is checked first, nothing is written when a row is invalid or when dryRun
+// is set. Imported accounts get a temporary password sent to their email.
+func (svc *Service) Import(ctx context.Context, actor model.Actor, rows [][]string, dryRun bool) (result http.ImportAccountsResult, err error) {
+	allowed, err := svc.CheckAccountRole(ctx, actor.AccountID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+
+	importRows, rowNumbers, err := parseImportRows(rows)
+	if err != nil {
+		return
+	}
+
+	result = http.ImportAccountsResult{
+		DryRun: dryRun,
+		Total:  len(importRows),
+		Rows:   []http.ImportAccountRowResult{},
+	}
+	accounts := []model.Account{}
+	seen := map[string]map[string]bool{}
+	for i := range importRows {
+		account, rowErrors, err := svc.checkImportRow(ctx, importRows[i], seen)
+		if err != nil {
+			return result, err
+		}
+		result.Rows = append(result.Rows, http.ImportAccountRowResult{
+			Row:      rowNumbers[i],
+			Username: importRows[i].Username,
+			Errors:   rowErrors,
+		})
+		if len(rowErrors) == 0 {
+			result.Valid++
+			accounts = append(accounts, account)
+		}
+	}
+
+	if dryRun {
+		return
+	}
+	if result.Valid != result.Total {
+		err = constant.ErrImportRowsInvalid
+		return
+	}
+
+	passwords, err := hashTemporaryPasswords(accounts)
+	if err != nil {
+		return
+	}
+
+	events := []outbox.Builder{}
+	audits := []audit.Builder{}
+	for i := range accounts {
+		events = append(events, outboxEvents(constant.EventAccountCreated, svc.getUser(accounts[i])))
+		audits = append(audits, svc.auditEntries(actor, constant.AuditActionCreate, nil, &accounts[i]))
+	}
+	_, err = svc.repo.CreateBatch(ctx, accounts, events, audits)
+	if err != nil {
+		err = errors.Wrap(err, "create accounts")
+		return
+	}
+	svc.outbox.Notify()
+	result.Imported = len(accounts)
+
+	for i := range accounts {
+		err := svc.mailer.Send(*accounts[i].Email, "Your attendance account", fmt.Sprintf(
+			"Hello %s,\n\nAn attendance account was created for you.\n\nUsername: %s\nTemporary password: %s\n\n"+
+				"Please change the password after your first login.\n",
+			accounts[i].FullName, accounts[i].Username, passwords[i]))
+		if err != nil {
+			logging.FromContext(ctx).Error("send temporary password", "error", err, "username", accounts[i].Username)
+			continue
+		}
+		result.Notified++
+	}
+	return
+}
+
+// importColumns maps the accepted header names to the row fields
+var importColumns = map[string]func(row *http.ImportAccountRow) *string{
+	"username":        func(row *http.ImportAccountRow) *string { return &row.Username },
+	"fullname":        func(row *http.ImportAccountRow) *string { return &row.FullName },
+	"full_name":       func(row *http.ImportAccountRow) *string { return &row.FullName },
+	"email":           func(row *http.ImportAccountRow) *string { return &row.Email },
+	"phone_number":    func(row *http.ImportAccountRow) *string { return &row.PhoneNumber },
+	"ktp_number":      func(row *http.ImportAccountRow) *string { return &row.KTPNumber },
+	"employee_number": func(row *http.ImportAccountRow) *string { return &row.EmployeeNumber },
+	"job_position":    func(row *http.ImportAccountRow) *string { return &row.JobPosition },
+	"address":         func(row *http.ImportAccountRow) *string { return &row.Address },
+	"gender":          func(row *http.ImportAccountRow) *string { return &row.Gender },
+	"date_of_birth":   func(row *http.ImportAccountRow) *string { return &row.DateOfBirth },
+	"role":            func(row *http.ImportAccountRow) *string { return &row.Role },
+}
+
+// parseImportRows reads the header and the non empty rows, rowNumbers holds the line of
+// each row in the file so errors can be found in a spreadsheet
+func parseImportRows(rows [][]string) (importRows []http.ImportAccountRow, rowNumbers []int, err error) {
+	if len(rows) == 0 {
+		err = constant.ErrImportEmpty
+		return
+	}
+
+	header := map[int]func(row *http.ImportAccountRow) *string{}
+	found := map[string]bool{}
+	for i, name := range rows[0] {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+		if field, ok := importColumns[name]; ok {
+			header[i] = field
+			found[name] = true
+		}
+	}
+	for _, column := range []string{"username", "email"} {
+		if !found[column] {
+			err = errors.Wrap(constant.ErrImportColumnMissing, column)
+			return
+		}
+	}
+	if !found["fullname"] && !found["full_name"] {
+		err = errors.Wrap(constant.ErrImportColumnMissing, "fullname")
+		return
+	}
+
+	for i, cells := range rows[1:] {
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue
+		}
+		row := http.ImportAccountRow{}
+		for j, cell := range cells {
+			if field, ok := header[j]; ok {
+				*field(&row) = strings.TrimSpace(cell)
+			}
+		}
+		importRows = append(importRows, row)
+		rowNumbers = append(rowNumbers, i+2)
+	}
+
+	if len(importRows) == 0 {
+		err = constant.ErrImportEmpty
+		return
+	} else if len(importRows) > constant.MaximumImportRows {
+		err = constant.ErrImportTooManyRows
+		return
+	}
+	return
+}
+
+// checkImportRow validates a row and checks its unique fields against the accounts
+// already registered and the rows before it, seen collects the values per column. err
+// is only set when a check fails, the import fails then instead of the row
+func (svc *Service) checkImportRow(ctx context.Context, row http.ImportAccountRow, seen map[string]map[string]bool) (account model.Account, rowErrors map[string]string, err error) {
+	rowErrors = map[string]string{}
+	unique := func(column string, key string, value string, check func(ctx context.Context, value string) (bool, error), errExist error) {
+		if err != nil {
+			return
+		}
+		if seen[column] == nil {
+			seen[column] = map[string]bool{}
+		}
+		if seen[column][key] {
+			rowErrors[column] = constant.ErrImportDuplicateValue.Error()
+			return
+		}
+		seen[column][key] = true
+
+		exist, checkErr := check(ctx, value)
+		if checkErr != nil {
+			err = errors.Wrapf(checkErr, "check import %s", column)
+		} else if exist {
+			rowErrors[column] = errExist.Error()
+		}
+	}
+
+	if row.Username == "" {
+		rowErrors["username"] = constant.ErrFieldRequired.Error()
+	} else if len(row.Username) > 50 {
+		rowErrors["username"] = constant.ErrFieldTooLong.Error()
+	} else {
+		unique("username", strings.ToLower(row.Username), row.Username, svc.CheckAccountByUsername, constant.ErrUsernameAlreadyExist)
+	}
+
+	if row.FullName == "" {
+		rowErrors["fullname"] = constant.ErrFieldRequired.Error()
+	} else if len(row.FullName) > 150 {
+		rowErrors["fullname"] = constant.ErrFieldTooLong.Error()
+	}
+
+	if row.Email == "" {
+		rowErrors["email"] = constant.ErrFieldRequired.Error()
+	} else if len(row.Email) > 150 || validation.Validator.Var(row.Email, "email") != nil {
+		rowErrors["email"] = constant.ErrInvalidEmail.Error()
+	} else {
+		unique("email", strings.ToLower(row.Email), row.Email, svc.CheckAccountByEmail, constant.ErrEmailAlreadyExist)
+	}
+
+	phoneNumber := ""
+	if row.PhoneNumber != "" {
+		var err error
+		phoneNumber, err = identity.NormalizePhoneNumber(row.PhoneNumber)
+		if err != nil {
+			rowErrors["phone_number"] = constant.ErrInvalidPhoneNumber.Error()
+		} else {
+			unique("phone_number", phoneNumber, phoneNumber, svc.CheckAccountByPhoneNumber, constant.ErrPhoneNumberAlreadyExist)
+		}
+	}
+
+	ktpNumber := strings.TrimSpace(row.KTPNumber)
+	var nik identity.NIK
+	if ktpNumber != "" {
+		var err error
+		nik, err = identity.ParseNIK(ktpNumber)
+		if err != nil {
+			rowErrors["ktp_number"] = constant.ErrInvalidKTPNumber.Error()
+		} else {
+			unique("ktp_number", ktpNumber, ktpNumber, svc.CheckAccountByKTPNumber, constant.ErrKTPNumberAlreadyExist)
+		}
+	}
+
+	dateOfBirth := ""
+	if row.DateOfBirth != "" {
+		date, err := time.Parse(constant.DOBFormat, row.DateOfBirth)
+		if err != nil {
+			// xlsx keeps cells formatted as a date as a serial number
+			var ok bool
+			if date, ok = sheet.ParseSerialDate(row.DateOfBirth); !ok {
+				rowErrors["date_of_birth"] = constant.ErrInvalidDOBFormat.Error()
+			}
+		}
+		dateOfBirth = date.Format(constant.DOBFormat)
+		if _, invalid := rowErrors["date_of_birth"]; !invalid && nik.Serial != "" && !nik.MatchesDateOfBirth(date) {
+			rowErrors["ktp_number"] = constant.ErrKTPNumberNotMatch.Error()
+		}
+	}
+
+	role := constant.RoleEmployee
+	if row.Role != "" {
+		role = strings.ToLower(row.Role)
+		if role != constant.RoleEmployee && role != constant.RoleManager && role != constant.RoleAdmin {
+			rowErrors["role"] = constant.ErrInvalidRole.Error()
+		}
+	}
+
+	gender := constant.GenderNone
+	if row.Gender != "" {
+		gender = strings.ToLower(row.Gender)
+		if gender != constant.GenderMale && gender != constant.GenderFemale && gender != constant.GenderNone {
+			rowErrors["gender"] = constant.ErrInvalidGender.Error()
+		} else if nik.Serial != "" && !nik.MatchesGender(gender) {
+			rowErrors["ktp_number"] = constant.ErrKTPNumberNotMatch.Error()
+		}
+	}
+
+	if err != nil || len(rowErrors) > 0 {
+		return
+	}
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+	account = model.Account{
+		Username:       row.Username,
+		FullName:       row.FullName,
+		Email:          &row.Email,
+		Address:        envelope.Ptr(row.Address),
+		EmployeeNumber: optional(row.EmployeeNumber),
+		JobPosition:    optional(row.JobPosition),
+		KTPNumber:      envelope.Ptr(ktpNumber),
+		PhoneNumber:    envelope.Ptr(phoneNumber),
+		Gender:         gender,
+		DateOfBirth:    envelope.Ptr(dateOfBirth),
+		IsVerified:     false,
+		Role:           role,
+	}
+	return
+}
+
+// hashTemporaryPasswords sets a random password on every account and returns the plain
+// passwords, bcrypt is slow on purpose so the hashes are computed in parallel
+func hashTemporaryPasswords(accounts []model.Account) (passwords []string, err error) {
+	passwords = make([]string, len(accounts))
+	for i := range accounts {
+		passwords[i], err = temporaryPassword()
+		if err != nil {
+			err = errors.Wrap(err, "generate temporary password")
+			return
+		}
+	}
+
+	errs := make([]error, len(accounts))
+	workers := make(chan struct{}, runtime.NumCPU())
+	wg := sync.WaitGroup{}
+	for i := range accounts {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			accounts[i].Password, errs[i] = bcrypt.HashPassword(passwords[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range errs {
+		if errs[i] != nil {
+			err = errors.Wrap(errs[i], "hash temporary password")
+			return
+		}
+	}
+	return
+}
+
+const temporaryPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+func temporaryPassword() (password string, err error) {
+	letters := make([]byte, constant.TemporaryPasswordLength)
+	for i := range letters {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(temporaryPasswordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		letters[i] = temporaryPasswordAlphabet[n.Int64()]
+	}
+	return string(letters), nil
+}
+
+func (svc *Service) Update(ctx context.Context, actor model.Actor, accountID int, request http.UpdateUser) (err error) {
+	exist, _ := svc.CheckAccountByID(ctx, accountID)
 	if !exist {
 		err = constant.ErrAccountNotRegistered
 		return
@@ -228,7 +788,7 @@ func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
 			err = constant.ErrUsernameCannotBeEmpty
 			return
 		} else {
-			usernameExist, _ := svc.CheckAccountByUsername(*request.Username)
+			usernameExist, _ := svc.CheckAccountByUsername(ctx, *request.Username)
 			if usernameExist {
 				err = constant.ErrUsernameAlreadyExist
 				return
@@ -237,7 +797,7 @@ func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
 	}
 
 	if request.Email != nil {
-	    emailExist, _ := svc.CheckAccountByEmail(*request.Email)
+	    emailExist, _ := svc.CheckAccountByEmail(ctx, *request.Email)
 	    if emailExist {
 		    err = constant.ErrEmailAlreadyExist
 		    return
@@ -245,19 +805,26 @@ func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
 	}
 
 	if request.KTPNumber != nil {
-	    ktpNumberExist, _ := svc.CheckAccountByKTPNumber(aes.Encrypt(*request.KTPNumber))
+	    ktpNumberExist, _ := svc.CheckAccountByKTPNumber(ctx, *request.KTPNumber)
 	    if ktpNumberExist {
 		    err = constant.ErrKTPNumberAlreadyExist
 		    return
 	    }
 	}
 
+	if request.KTPNumber != nil || request.DOBString != nil || request.Gender != nil {
+		err = svc.checkKTPNumber(ctx, accountID, request)
+		if err != nil {
+			return
+		}
+	}
+
 	if request.Password != nil {
 		if *request.Password == "" {
 			err = constant.ErrPasswordCannotBeEmpty
 			return
 		} else {
-			hashedNewPassword, err := bcrypt.HashPassword(*request.Password)
+			hashedNewPassword, err := hashPassword(ctx, *request.Password)
 			if err != nil {
 				err = errors.Wrap(err, "hash new password")
 				return err
@@ -267,7 +834,15 @@ func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
 	}
 
 	if request.PhoneNumber != nil {
-	    phoneNumberExist, _ := svc.CheckAccountByPhoneNumber(*request.PhoneNumber)
+		var phoneNumber string
+		phoneNumber, err = identity.NormalizePhoneNumber(*request.PhoneNumber)
+		if err != nil {
+			err = constant.ErrInvalidPhoneNumber
+			return
+		}
+		request.PhoneNumber = &phoneNumber
+
+	    phoneNumberExist, _ := svc.CheckAccountByPhoneNumber(ctx, *request.PhoneNumber)
 	    if phoneNumberExist {
 		    err = constant.ErrPhoneNumberAlreadyExist
 		    return
@@ -276,57 +851,91 @@ func (svc *Service) Update(accountID int, request http.UpdateUser) (err error) {
 
 	account := model.Account{}
 	copier.Copy(&account, &request)
-	if request.KTPNumber != nil {
-	    *account.KTPNumber = aes.Encrypt(*request.KTPNumber)
-	}
 	if request.DOBString != nil {
 		DOBString, err := time.Parse(constant.DOBFormat, *request.DOBString)
 		if err != nil {
 			err = constant.ErrInvalidDOBFormat
 			return err
 		}
-		account.DateOfBirth = DOBString
+		account.DateOfBirth = envelope.Ptr(DOBString.Format(constant.DOBFormat))
 	}
 
-	err = svc.repo.Update(accountID, account)
+	// the event carries the account as it is after the update
+	takeUser, err := svc.takeAccount(ctx, accountID)
+	if err != nil {
+		return
+	}
+	updated := takeUser
+	copier.CopyWithOption(&updated, &account, copier.Option{IgnoreEmpty: true})
+
+	err = svc.repo.Update(ctx, accountID, account, outboxEvents(constant.EventAccountUpdated, svc.getUser(updated)), svc.auditEntries(actor, constant.AuditActionUpdate, &takeUser, &updated))
 	if err != nil {
 		err = errors.Wrap(err, "update account")
 		return
 	}
+	svc.outbox.Notify()
 	return
 }
 
-func (svc *Service) UpdatePassword(request http.ForgotPassword) (err error) {
+// UpdatePhoto stores the uploaded photo and points the account to it, the previous
+// photo is removed once the account no longer uses it
+func (svc *Service) UpdatePhoto(ctx context.Context, actor model.Actor, accountID int, request http.Photo) (photoURL string, err error) {
+	takeUser, err := svc.takeAccount(ctx, accountID)
+	if err != nil {
+		return
+	}
+	previousPhotoURL := takeUser.PhotoURL
+
+	photoURL, err = svc.photo.Store(ctx, "accounts", request, avatarVariants)
+	if err != nil {
+		return
+	}
+
+	updated := takeUser
+	updated.PhotoURL = photoURL
+	err = svc.repo.Update(ctx, accountID, model.Account{PhotoURL: photoURL}, outboxEvents(constant.EventAccountUpdated, svc.getUser(updated)), svc.auditEntries(actor, constant.AuditActionUpdate, &takeUser, &updated))
+	if err != nil {
+		svc.photo.Delete(ctx, photoURL, avatarVariants)
+		err = errors.Wrap(err, "update account photo")
+		return
+	}
+	svc.outbox.Notify()
+
+	svc.photo.Delete(ctx, previousPhotoURL, avatarVariants)
+	return
+}
+
+func (svc *Service) UpdatePassword(ctx context.Context, actor model.Actor, request http.ForgotPassword) (err error) {
 	var accountID int
-	if request.KTPNumber != 0 {
-	    ktpNumberExist, _ := svc.CheckAccountByKTPNumber(aes.Encrypt(request.KTPNumber))
+	var takeUser model.Account
+	if request.KTPNumber != "" {
+	    ktpNumberExist, _ := svc.CheckAccountByKTPNumber(ctx, request.KTPNumber)
 	    if !ktpNumberExist {
 		    err = constant.ErrAccountNotRegistered
 		    return
 	    }
 
-		account, err := svc.TakeAccountByKTPNumber(aes.Encrypt(request.KTPNumber))
+		takeUser, err = svc.TakeAccountByKTPNumber(ctx, request.KTPNumber)
 		if err != nil {
 			err = errors.Wrap(err, "take account by ktp number")
 			return err
 		}
-		accountID = int(account.ID)
+		accountID = int(takeUser.ID)
 	}
 
-	hashedNewPassword, err := bcrypt.HashPassword(request.Password)
+	hashedNewPassword, err := hashPassword(ctx, request.Password)
 	if err != nil {
 		err = errors.Wrap(err, "hash new password")
 		return err
 	}
 
-	account := model.Account{}
-	copier.Copy(&account, &request)
-	account.Password = hashedNewPassword
-	if request.KTPNumber != 0 {
-	    *account.KTPNumber = aes.Encrypt(request.KTPNumber)
+	account := model.Account{
+		Password: hashedNewPassword,
 	}
+	updated := takeUser
+	updated.Password = hashedNewPassword
 
-	err = svc.repo.Update(accountID, account)
+	err = svc.repo.Update(ctx, accountID, account, nil, svc.auditEntries(actor, constant.AuditActionUpdate, &takeUser, &updated))
 	if err != nil {
 	    err = errors.Wrap(err, "update password")
 		return
@@ -334,17 +943,273 @@ func (svc *Service) UpdatePassword(request http.ForgotPassword) (err error) {
 	return
 }
 
-func (svc *Service) Delete(accountID int) (err error) {
-	_, err = svc.TakeAccountByID(accountID)
+func (svc *Service) UpdateRequireBoundDevice(ctx context.Context, actor model.Actor, accountID int, required bool) (err error) {
+	takeUser, err := svc.takeAccount(ctx, accountID)
+	if err != nil {
+		return
+	}
+	updated := takeUser
+	updated.RequireBoundDevice = required
+
+	err = svc.repo.UpdateRequireBoundDevice(ctx, accountID, required, outboxEvents(constant.EventAccountUpdated, svc.getUser(updated)), svc.auditEntries(actor, constant.AuditActionUpdate, &takeUser, &updated))
+	if err != nil {
+		err = errors.Wrap(err, "update require bound device")
+		return
+	}
+	svc.outbox.Notify()
+	return
+}
+
+// UpdateReportingLine sets the department, team and manager of an account, a manager
+// that reports to the account, directly or indirectly, would make a cycle. The
+// repository checks for the cycle in the transaction of the update
+func (svc *Service) UpdateReportingLine(ctx context.Context, actor model.Actor, accountID int, departmentID, teamID, managerID *int) (err error) {
+	takeUser, err := svc.takeAccount(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	updated := takeUser
+	updated.DepartmentID, updated.TeamID, updated.ManagerID = departmentID, teamID, managerID
+	if managerID != nil {
+		if *managerID == accountID {
+			err = constant.ErrReportingLineCycle
+			return
+		}
+
+		exist, err := svc.CheckAccountByID(ctx, *managerID)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return constant.ErrManagerNotExist
+		}
+	}
+
+	err = svc.repo.UpdateReportingLine(ctx, accountID, departmentID, teamID, managerID, outboxEvents(constant.EventAccountUpdated, svc.getUser(updated)), svc.auditEntries(actor, constant.AuditActionUpdate, &takeUser, &updated))
+	if errors.Is(err, constant.ErrReportingLineCycle) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "update reporting line")
+		return
+	}
+	svc.outbox.Notify()
+	return
+}
+
+func (svc *Service) FindReports(ctx context.Context, managerID int, direct bool) (accounts []http.GetUser, err error) {
+	reports, err := svc.repo.FindReports(ctx, managerID, direct)
+	if err != nil {
+		err = errors.Wrap(err, "find reports")
+		return
+	}
+
+	accounts = []http.GetUser{}
+	for i := range reports {
+		accounts = append(accounts, svc.getUser(reports[i]))
+	}
+	return
+}
+
+func (svc *Service) CheckReport(ctx context.Context, managerID int, accountID int) (isReport bool, err error) {
+	isReport, err = svc.repo.CheckReport(ctx, managerID, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "check report")
+		return
+	}
+	return
+}
+
+func (svc *Service) Delete(ctx context.Context, actor model.Actor, accountID int) (err error) {
+	takeUser, err := svc.takeAccount(ctx, accountID)
 	if err != nil {
 		err = errors.Wrap(err, "account is not exist")
 		return
 	}
 
-	err = svc.repo.Delete(accountID)
+	err = svc.repo.Delete(ctx, accountID, outboxEvents(constant.EventAccountDeleted, svc.getUser(takeUser)), svc.auditEntries(actor, constant.AuditActionDelete, &takeUser, nil))
 	if err != nil {
 		err = errors.Wrap(err, "delete account")
 		return
 	}
+	svc.outbox.Notify()
+	return
+}
+
+// Erase removes the personal data of the account and its photo, deleted accounts can be
+// erased too. Subscribers get an account.erased event to erase their copies.
+func (svc *Service) Erase(ctx context.Context, actor model.Actor, accountID int) (err error) {
+	erased := http.GetUser{
+		Username: fmt.Sprintf(constant.ErasedUsernameFormat, accountID),
+		FullName: constant.ErasedFullName,
+	}
+	// the entry of the erasure holds no values, they are what is erased
+	photoURL, err := svc.repo.Erase(ctx, accountID, outboxEvents(constant.EventAccountErased, erased), svc.auditEntries(actor, constant.AuditActionErase, nil, nil))
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrAccountNotRegistered
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "erase account")
+		return
+	}
+	svc.outbox.Notify()
+
+	svc.photo.Delete(ctx, photoURL, avatarVariants)
+	return
+}
+
+// outboxEvents builds the event of an account write, the id is only known inside the
+// write transaction when the account is created
+func outboxEvents(eventType string, user http.GetUser) outbox.Builder {
+	return func(accountID int) (events []model.OutboxEvent, err error) {
+		user.ID = aes.Encrypt(accountID)
+		outboxEvent, err := outbox.NewEvent(constant.AggregateAccount, accountID, eventType, user)
+		if err != nil {
+			err = errors.Wrap(err, "new account event")
+			return
+		}
+		events = append(events, outboxEvent)
+		return
+	}
+}
+
+// auditEntries builds the audit entry of an account write, before is nil when the
+// account is created and after is nil when it is deleted. An update that changes
+// nothing is not recorded.
+func (svc *Service) auditEntries(actor model.Actor, action string, before, after *model.Account) audit.Builder {
+	return func(accountID int) (entries []model.AuditLog, err error) {
+		entry, changed, err := audit.NewEntry(actor, action, constant.AggregateAccount, accountID,
+			svc.newAuditedAccount(accountID, before), svc.newAuditedAccount(accountID, after), auditMasks)
+		if err != nil {
+			err = errors.Wrap(err, "new account audit entry")
+			return
+		}
+		if changed || action != constant.AuditActionUpdate {
+			entries = append(entries, entry)
+		}
+		return
+	}
+}
+
+// auditedAccount is what the audit log compares of an account, the password hash only
+// shows that the password changed
+type auditedAccount struct {
+	http.GetUser
+	PhoneNumber string `json:"phone_number"`
+	KTPNumber   string `json:"ktp_number"`
+	Gender      string `json:"gender"`
+	DateOfBirth string `json:"date_of_birth"`
+	IsVerified  bool   `json:"is_verified"`
+	Password    string `json:"password"`
+}
+
+// auditMasks hide the personal data of an account in the audit log
+var auditMasks = map[string]audit.Mask{
+	"password":      audit.MaskFull,
+	"address":       audit.MaskFull,
+	"date_of_birth": audit.MaskFull,
+	"ktp_number":    audit.MaskLastFour,
+	"phone_number":  audit.MaskLastFour,
+}
+
+func (svc *Service) newAuditedAccount(accountID int, account *model.Account) *auditedAccount {
+	if account == nil {
+		return nil
+	}
+	written := *account
+	written.ID = uint(accountID)
+	return &auditedAccount{
+		GetUser:     svc.getUser(written),
+		PhoneNumber: plaintext(account.PhoneNumber),
+		KTPNumber:   plaintext(account.KTPNumber),
+		Gender:      account.Gender,
+		DateOfBirth: plaintext(account.DateOfBirth),
+		IsVerified:  account.IsVerified,
+		Password:    account.Password,
+	}
+}
+
+func (svc *Service) takeAccount(ctx context.Context, accountID int) (account model.Account, err error) {
+	account, err = svc.repo.TakeAccountByID(ctx, accountID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrAccountNotRegistered
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take account")
+		return
+	}
+	return
+}
+
+func (svc *Service) getUser(account model.Account) (user http.GetUser) {
+	copier.Copy(&user, &account)
+	user.ID = aes.Encrypt(int(account.ID))
+	if account.ManagerID != nil {
+		user.ManagerID = aes.Encrypt(*account.ManagerID)
+	}
+	if user.PhotoURL == "" {
+		user.PhotoURL = svc.photo.PlaceholderURL(account.FullName)
+	}
+	return
+}
+
+// checkKTPNumber checks the ktp number the account ends up with against its date of birth
+// and gender, the request validation only sees the fields sent along. Ktp numbers stored
+// before they were validated are left alone.
+func (svc *Service) checkKTPNumber(ctx context.Context, accountID int, request http.UpdateUser) (err error) {
+	takeUser, err := svc.repo.TakeAccountByID(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "take account")
+		return
+	}
+
+	ktpNumber, dateOfBirth, gender := plaintext(takeUser.KTPNumber), plaintext(takeUser.DateOfBirth), takeUser.Gender
+	if request.KTPNumber != nil {
+		ktpNumber = *request.KTPNumber
+	}
+	if request.DOBString != nil {
+		dateOfBirth = *request.DOBString
+	}
+	if request.Gender != nil {
+		gender = *request.Gender
+	}
+
+	nik, err := identity.ParseNIK(ktpNumber)
+	if err != nil {
+		return nil
+	}
+	if date, err := time.Parse(constant.DOBFormat, dateOfBirth); err == nil && !nik.MatchesDateOfBirth(date) {
+		return constant.ErrKTPNumberNotMatch
+	}
+	if !nik.MatchesGender(gender) {
+		return constant.ErrKTPNumberNotMatch
+	}
+	return
+}
+
+func plaintext(value *envelope.String) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+// hashPassword and comparePassword run bcrypt in their own spans, it is slow on
+// purpose and tends to be most of a login or registration
+func hashPassword(ctx context.Context, password string) (hashed string, err error) {
+	_, span := tracing.Start(ctx, "bcrypt.HashPassword")
+	hashed, err = bcrypt.HashPassword(password)
+	tracing.End(span, err)
+	return
+}
+
+// unknownAccountPassword is a hash with the cost of the stored passwords that login
+// compares against when the username does not exist
+const unknownAccountPassword = "$2a$14$zxD/h1tPoaPitqvtgombCu8oAlgTc7afLCGclRBgb.LgXxSISQoVa"
+
+func comparePassword(ctx context.Context, hashed string, password string) (err error) {
+	_, span := tracing.Start(ctx, "bcrypt.ComparePassword")
+	err = bcrypt.ComparePassword(hashed, password)
+	span.End()
 	return
 }
@@ -1,47 +1,90 @@
 package attendance
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
 	"time"
 
+	"go-rest-api/src/config"
 	"go-rest-api/src/constant"
 	"go-rest-api/src/http"
 	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/metrics"
 	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/pubsub"
+	"go-rest-api/src/pkg/storage"
+	"go-rest-api/src/pkg/tracing"
 	"go-rest-api/src/repository/v1/attendance"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/repository/v1/outbox"
 	"go-rest-api/src/service/v1/account"
+	"go-rest-api/src/service/v1/device"
 	"go-rest-api/src/service/v1/location"
 
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/forkyid/go-utils/v1/uuid"
+	"github.com/forkyid/go-utils/v1/validation"
 	"github.com/jinzhu/copier"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
 type Service struct {
 	repo     attendance.Repositorier
 	account  account.Servicer
 	location location.Servicer
+	device   device.Servicer
+	storage  storage.Storage
+	hub      *pubsub.Hub
+	outbox   event.Notifier
+	config   config.Attendance
 }
 
 func NewService(
 	repositorier attendance.Repositorier,
 	accountSvc   account.Servicer,
 	locationSvc  location.Servicer,
+	deviceSvc    device.Servicer,
+	blobStorage  storage.Storage,
+	hub          *pubsub.Hub,
+	notifier     event.Notifier,
+	cfg          config.Attendance,
 ) *Service {
 	return &Service{
 		repo:     repositorier,
 		account:  accountSvc,
 		location: locationSvc,
+		device:   deviceSvc,
+		storage:  blobStorage,
+		hub:      hub,
+		outbox:   notifier,
+		config:   cfg,
 	}
 }
 
 type Servicer interface {
-	FindAttendanceHistory(accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error)
-	FindByLocation(accountID int, pgn pagination.Pagination) (responses []http.GetAttendanceByLocation, err error)
-	Add(accountID int, request http.AddAttendance) (err error)
+	FindAttendanceHistory(ctx context.Context, accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error)
+	FindByLocation(ctx context.Context, accountID int, pgn pagination.Pagination) (responses []http.GetAttendanceByLocation, err error)
+	FindReportAttendanceHistory(ctx context.Context, managerID int, accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error)
+	Add(ctx context.Context, actor model.Actor, accountID int, request http.AddAttendance) (err error)
+	AddBatch(ctx context.Context, actor model.Actor, accountID int, request http.AddAttendanceBatch) (responses []http.AttendanceEventResult, err error)
+	TakePhoto(ctx context.Context, accountID int, attendanceID int) (photo io.ReadCloser, contentType string, err error)
+	FindPresence(ctx context.Context, accountID int, locationID int) (response http.GetPresence, err error)
+	FindPresenceSummary(ctx context.Context, accountID int) (response http.GetPresenceSummary, err error)
+	Subscribe(ctx context.Context, accountID int, lastEventID uint64, request http.StreamAttendance) (subscription *pubsub.Subscription, missed []pubsub.Event, err error)
+	Unsubscribe(ctx context.Context, subscription *pubsub.Subscription)
+	FindExport(ctx context.Context, accountID int) (attendances []http.ExportAttendance, err error)
+	ErasePhotos(ctx context.Context, actor model.Actor, accountID int) (err error)
 }
 
-func (svc *Service) FindAttendanceHistory(accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error) {
-	accountExist, err := svc.account.CheckAccountByID(accountID)
+func (svc *Service) FindAttendanceHistory(ctx context.Context, accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error) {
+	accountExist, err := svc.account.CheckAccountByID(ctx, accountID)
 	if err != nil {
 		err = errors.Wrap(err, "check account by id")
 		return
@@ -51,7 +94,7 @@ func (svc *Service) FindAttendanceHistory(accountID int, pgn pagination.Paginati
 		return
 	}
 
-	attendanceDatas, err := svc.repo.Find(accountID, pgn)
+	attendanceDatas, err := svc.repo.Find(ctx, accountID, pgn)
 	if err != nil {
 		err = errors.Wrap(err, "find attendance datas")
 		return
@@ -83,14 +126,16 @@ func (svc *Service) FindAttendanceHistory(accountID int, pgn pagination.Paginati
 			}
 
 			attendance := http.GetAttendance{}
-			location, err := svc.location.TakeLocationByID(attendanceDatas[i].LocationID)
+			location, err := svc.location.TakeLocationByID(ctx, attendanceDatas[i].LocationID)
 			if err != nil {
 				err = errors.Wrap(err, "check location by id")
 				return nil, err
 			}
+			attendance.ID = attendanceDatas[i].ID
 			attendance.LocationID = attendanceDatas[i].LocationID
 			attendance.LocationName = location.LocationName
 			attendance.Status = attendanceDatas[i].Status
+			attendance.HasPhoto = attendanceDatas[i].PhotoKey != nil
 			attendance.Time = attendanceDatas[i].CreatedAt.In(loc).Format("03:04 PM")
 			if attendanceDatas[i].Status == constant.StatusCheckIn {
 				attendance.Description = fmt.Sprintf("Check In - %s - %v", location.LocationName, attendanceDatas[i].CreatedAt.In(loc).Format("03:04 PM"))
@@ -104,8 +149,29 @@ func (svc *Service) FindAttendanceHistory(accountID int, pgn pagination.Paginati
 	return
 }
 
-func (svc *Service) FindByLocation(accountID int, pgn pagination.Pagination) (responses []http.GetAttendanceByLocation, err error) {
-	accountExist, err := svc.account.CheckAccountByID(accountID)
+// FindReportAttendanceHistory lets a manager read the history of an account reporting
+// to them, directly or indirectly, admins can read any account
+func (svc *Service) FindReportAttendanceHistory(ctx context.Context, managerID int, accountID int, pgn pagination.Pagination, filter string) (responses []http.GetAttendance, err error) {
+	isAdmin, err := svc.account.CheckAccountRole(ctx, managerID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !isAdmin {
+		isReport, err := svc.account.CheckReport(ctx, managerID, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if !isReport {
+			return nil, constant.ErrPermissionDenied
+		}
+	}
+
+	return svc.FindAttendanceHistory(ctx, accountID, pgn, filter)
+}
+
+func (svc *Service) FindByLocation(ctx context.Context, accountID int, pgn pagination.Pagination) (responses []http.GetAttendanceByLocation, err error) {
+	accountExist, err := svc.account.CheckAccountByID(ctx, accountID)
 	if err != nil {
 		err = errors.Wrap(err, "check account by id")
 		return
@@ -115,7 +181,7 @@ func (svc *Service) FindByLocation(accountID int, pgn pagination.Pagination) (re
 		return
 	}
 
-	attendanceDatas, err := svc.repo.Find(accountID, pgn)
+	attendanceDatas, err := svc.repo.Find(ctx, accountID, pgn)
 	if err != nil {
 		err = errors.Wrap(err, "find attendance datas")
 		return
@@ -131,7 +197,7 @@ func (svc *Service) FindByLocation(accountID int, pgn pagination.Pagination) (re
 			}
 
 			attendance := http.GetAttendanceByLocation{}
-			location, err := svc.location.TakeLocationByID(attendanceDatas[i].LocationID)
+			location, err := svc.location.TakeLocationByID(ctx, attendanceDatas[i].LocationID)
 			if err != nil {
 				err = errors.Wrap(err, "check location by id")
 				return nil, err
@@ -146,24 +212,43 @@ func (svc *Service) FindByLocation(accountID int, pgn pagination.Pagination) (re
 	return
 }
 
-func (svc *Service) Add(accountID int, request http.AddAttendance) (err error) {
-	accountExist, err := svc.account.CheckAccountByID(accountID)
-	if err != nil {
-		err = errors.Wrap(err, "check account by id")
+func (svc *Service) Add(ctx context.Context, actor model.Actor, accountID int, request http.AddAttendance) (err error) {
+	ctx, span := tracing.Start(ctx, "attendance.Add", attribute.Int("location_id", request.LocationID))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	defer func() {
+		for _, reason := range rejectionReasons {
+			if errors.Is(err, reason) {
+				metrics.AttendanceRejections.WithLabelValues(reason.Error()).Inc()
+				return
+			}
+		}
+	}()
+
+	user, err := svc.account.TakeAccountByID(ctx, accountID)
+	if errors.Is(err, constant.ErrAccountNotRegistered) {
 		return
-	}
-	if !accountExist {
-		err = constant.ErrAccountNotRegistered
+	} else if err != nil {
+		err = errors.Wrap(err, "take account by id")
 		return
 	}
 
-	locationExist, err := svc.location.CheckLocationByID(request.LocationID)
+	deviceID, err := svc.device.Authorize(ctx, accountID, request.Device)
 	if err != nil {
-		err = errors.Wrap(err, "check location by id")
+		err = errors.Wrap(err, "authorize device")
+		return
+	}
+
+	location, err := svc.location.TakeLocationByID(ctx, request.LocationID)
+	if errors.Is(err, constant.ErrLocationNotExist) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take location by id")
 		return
 	}
-	if !locationExist {
-		err = constant.ErrLocationNotExist
+	if location.RequirePhoto && request.Photo == nil {
+		err = constant.ErrPhotoRequired
 		return
 	}
 
@@ -172,17 +257,524 @@ func (svc *Service) Add(accountID int, request http.AddAttendance) (err error) {
 		newAttendance := model.Attendance{}
 		copier.Copy(&newAttendance, &request)
 		newAttendance.AccountID = accountID
+		newAttendance.DeviceID = deviceID
 		newAttendance.CreatedAt = time.Now().UTC()
 		newAttendance.UpdatedAt = time.Now().UTC()
 
-		err = svc.repo.Create(accountID, newAttendance)
+		if request.Photo != nil {
+			_, stepSpan := tracing.Start(ctx, "attendance.storePhoto", attribute.Int64("photo_size", request.Photo.Size))
+			photoKey, err := svc.storePhoto(accountID, *request.Photo)
+			tracing.End(stepSpan, err)
+			if err != nil {
+				return err
+			}
+			newAttendance.PhotoKey = &photoKey
+		}
+
+		stepCtx, stepSpan := tracing.Start(ctx, "attendance.Create")
+		_, err = svc.repo.Create(stepCtx, accountID, newAttendance, outboxEvents(user, location, newAttendance), auditEntries(actor, user, newAttendance))
+		tracing.End(stepSpan, err)
 		if err != nil {
+			if newAttendance.PhotoKey != nil {
+				svc.storage.Delete(*newAttendance.PhotoKey)
+			}
 			err = errors.Wrap(err, "create new attendance")
 			return
 		}
+		svc.outbox.Notify()
+		countCheckIn(newAttendance)
 	} else {
 		err = constant.ErrInvalidStatusAttendance
 		return
 	}
 	return
 }
+
+// AddBatch records attendance events queued offline by the mobile app. Every event
+// gets its own result so one bad event does not fail the whole batch, and events are
+// deduplicated on their client generated id so retrying a batch is safe.
+func (svc *Service) AddBatch(ctx context.Context, actor model.Actor, accountID int, request http.AddAttendanceBatch) (responses []http.AttendanceEventResult, err error) {
+	ctx, span := tracing.Start(ctx, "attendance.AddBatch", attribute.Int("events", len(request.Events)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	user, err := svc.account.TakeAccountByID(ctx, accountID)
+	if errors.Is(err, constant.ErrAccountNotRegistered) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take account by id")
+		return
+	}
+
+	deviceID, err := svc.device.Authorize(ctx, accountID, request.Device)
+	if err != nil {
+		err = errors.Wrap(err, "authorize device")
+		return
+	}
+
+	now := time.Now().UTC()
+	locations := make(map[int]http.GetLocation)
+	for i := range request.Events {
+		event := request.Events[i]
+		response := http.AttendanceEventResult{
+			ID:     event.ID,
+			Result: constant.BatchResultRejected,
+		}
+
+		stepCtx, stepSpan := tracing.Start(ctx, "attendance.validateEvent")
+		reason, err := svc.validateEvent(stepCtx, event, now, locations)
+		tracing.End(stepSpan, err)
+		if err != nil {
+			return nil, err
+		}
+		if reason != nil {
+			metrics.AttendanceRejections.WithLabelValues(reason.Error()).Inc()
+			response.Reason = reason.Error()
+			responses = append(responses, response)
+			continue
+		}
+
+		newAttendance := model.Attendance{
+			AccountID:  accountID,
+			LocationID: event.LocationID,
+			Status:     event.Status,
+			EventID:    &request.Events[i].ID,
+			DeviceID:   deviceID,
+			CreatedAt:  event.ClientTime.UTC(),
+			UpdatedAt:  now,
+		}
+		stepCtx, stepSpan = tracing.Start(ctx, "attendance.CreateEvent")
+		_, created, err := svc.repo.CreateEvent(stepCtx, newAttendance, outboxEvents(user, locations[event.LocationID], newAttendance), auditEntries(actor, user, newAttendance))
+		tracing.End(stepSpan, err)
+		if err != nil {
+			err = errors.Wrap(err, "create attendance event")
+			return nil, err
+		}
+
+		if created {
+			response.Result = constant.BatchResultAccepted
+			svc.outbox.Notify()
+			countCheckIn(newAttendance)
+		} else {
+			existing, err := svc.repo.TakeAttendanceByEventID(ctx, event.ID)
+			if err != nil {
+				err = errors.Wrap(err, "take attendance by event id")
+				return nil, err
+			}
+			if existing.AccountID != accountID {
+				response.Reason = constant.ErrEventIDAlreadyUsed.Error()
+			} else {
+				response.Result = constant.BatchResultDuplicate
+			}
+		}
+		responses = append(responses, response)
+	}
+	return
+}
+
+// rejectionReasons are the errors of Add that refuse the attendance itself, they are
+// counted the same as the reasons a batched event is rejected
+var rejectionReasons = []error{
+	constant.ErrDeviceNotBound,
+	constant.ErrInvalidDeviceSignature,
+	constant.ErrLocationNotExist,
+	constant.ErrInvalidStatusAttendance,
+	constant.ErrPhotoRequired,
+}
+
+func countCheckIn(attendance model.Attendance) {
+	if attendance.Status == constant.StatusCheckIn {
+		metrics.CheckIns.WithLabelValues(strconv.Itoa(attendance.LocationID)).Inc()
+	}
+}
+
+// validateEvent returns the reason an event is rejected, err is only set on lookup failures
+func (svc *Service) validateEvent(ctx context.Context, event http.AttendanceEvent, now time.Time, locations map[int]http.GetLocation) (reason error, err error) {
+	if err := validation.Validator.Struct(event); err != nil {
+		reason = constant.ErrInvalidFormat
+		return reason, nil
+	}
+	if event.Status != constant.StatusCheckIn && event.Status != constant.StatusCheckOut {
+		reason = constant.ErrInvalidStatusAttendance
+		return
+	}
+	if event.ClientTime.Sub(now) > svc.config.ClockSkewTolerance {
+		reason = constant.ErrClockSkewExceeded
+		return
+	}
+	if now.Sub(event.ClientTime) > svc.config.BatchMaxAge {
+		reason = constant.ErrEventTooOld
+		return
+	}
+
+	location, ok := locations[event.LocationID]
+	if !ok {
+		location, err = svc.location.TakeLocationByID(ctx, event.LocationID)
+		if errors.Is(err, constant.ErrLocationNotExist) {
+			reason, err = constant.ErrLocationNotExist, nil
+			return
+		} else if err != nil {
+			err = errors.Wrap(err, "take location by id")
+			return
+		}
+		locations[event.LocationID] = location
+	}
+	// batched events cannot carry a selfie
+	if location.RequirePhoto {
+		reason = constant.ErrPhotoRequired
+		return
+	}
+	return
+}
+
+func (svc *Service) TakePhoto(ctx context.Context, accountID int, attendanceID int) (photo io.ReadCloser, contentType string, err error) {
+	err = svc.checkManager(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	attendanceData, err := svc.repo.TakeAttendanceByID(ctx, attendanceID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrAttendanceNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take attendance by id")
+		return
+	}
+	if attendanceData.PhotoKey == nil {
+		err = constant.ErrPhotoNotExist
+		return
+	}
+
+	photo, contentType, err = svc.storage.Get(*attendanceData.PhotoKey)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		err = constant.ErrPhotoNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "get attendance photo")
+		return
+	}
+	return
+}
+
+func (svc *Service) FindPresence(ctx context.Context, accountID int, locationID int) (response http.GetPresence, err error) {
+	err = svc.checkManager(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	location, err := svc.location.TakeLocationByID(ctx, locationID)
+	if errors.Is(err, constant.ErrLocationNotExist) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take location by id")
+		return
+	}
+
+	presences, err := svc.repo.FindPresence(ctx, locationID, time.Now().Add(-svc.config.PresenceSessionMaxAge))
+	if err != nil {
+		err = errors.Wrap(err, "find presence")
+		return
+	}
+
+	response = http.GetPresence{
+		LocationID:   location.ID,
+		LocationName: location.LocationName,
+		Accounts:     []http.PresenceAccount{},
+	}
+	for i := range presences {
+		response.Accounts = append(response.Accounts, presenceAccount(presences[i]))
+	}
+	response.Total = len(response.Accounts)
+	return
+}
+
+func (svc *Service) FindPresenceSummary(ctx context.Context, accountID int) (response http.GetPresenceSummary, err error) {
+	err = svc.checkManager(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	presences, err := svc.repo.FindPresence(ctx, 0, time.Now().Add(-svc.config.PresenceSessionMaxAge))
+	if err != nil {
+		err = errors.Wrap(err, "find presence")
+		return
+	}
+
+	// rows are ordered by location name, so a location's accounts are contiguous
+	response.Locations = []http.GetPresence{}
+	for i := range presences {
+		last := len(response.Locations) - 1
+		if last < 0 || response.Locations[last].LocationID != presences[i].LocationID {
+			response.Locations = append(response.Locations, http.GetPresence{
+				LocationID:   presences[i].LocationID,
+				LocationName: presences[i].LocationName,
+			})
+			last++
+		}
+		response.Locations[last].Accounts = append(response.Locations[last].Accounts, presenceAccount(presences[i]))
+		response.Locations[last].Total++
+	}
+	response.Total = len(presences)
+	return
+}
+
+func (svc *Service) Subscribe(ctx context.Context, accountID int, lastEventID uint64, request http.StreamAttendance) (subscription *pubsub.Subscription, missed []pubsub.Event, err error) {
+	err = svc.checkManager(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	filter := func(event pubsub.Event) bool {
+		payload, ok := event.Payload.(http.AttendanceStreamEvent)
+		if !ok {
+			return false
+		}
+		if request.TeamID != 0 && (payload.TeamID == nil || *payload.TeamID != request.TeamID) {
+			return false
+		}
+		return request.LocationID == 0 || payload.LocationID == request.LocationID
+	}
+	subscription, missed = svc.hub.Subscribe(lastEventID, filter)
+	return
+}
+
+func (svc *Service) Unsubscribe(ctx context.Context, subscription *pubsub.Subscription) {
+	svc.hub.Unsubscribe(subscription)
+}
+
+// outboxEvents builds the check-in or check-out event of a recorded attendance
+func outboxEvents(user http.GetUser, location http.GetLocation, attendanceData model.Attendance) outbox.Builder {
+	return func(attendanceID int) (events []model.OutboxEvent, err error) {
+		eventType := constant.EventAttendanceCheckIn
+		if attendanceData.Status == constant.StatusCheckOut {
+			eventType = constant.EventAttendanceCheckOut
+		}
+
+		outboxEvent, err := outbox.NewEvent(constant.AggregateAttendance, attendanceID, eventType, http.AttendanceStreamEvent{
+			ID:           attendanceID,
+			AccountID:    user.ID,
+			FullName:     user.FullName,
+			LocationID:   location.ID,
+			LocationName: location.LocationName,
+			TeamID:       user.TeamID,
+			Status:       attendanceData.Status,
+			Time:         attendanceData.CreatedAt,
+		})
+		if err != nil {
+			err = errors.Wrap(err, "new attendance event")
+			return
+		}
+		events = append(events, outboxEvent)
+		return
+	}
+}
+
+// auditEntries builds the audit entry of a created attendance
+func auditEntries(actor model.Actor, user http.GetUser, attendanceData model.Attendance) audit.Builder {
+	return func(attendanceID int) (entries []model.AuditLog, err error) {
+		entry, _, err := audit.NewEntry(actor, constant.AuditActionCreate, constant.AggregateAttendance, attendanceID, nil, auditedAttendance{
+			AccountID:  user.ID,
+			LocationID: attendanceData.LocationID,
+			Status:     attendanceData.Status,
+			DeviceID:   attendanceData.DeviceID,
+			EventID:    attendanceData.EventID,
+			Photo:      attendanceData.PhotoKey != nil,
+			Time:       attendanceData.CreatedAt,
+		}, nil)
+		if err != nil {
+			err = errors.Wrap(err, "new attendance audit entry")
+			return
+		}
+		entries = append(entries, entry)
+		return
+	}
+}
+
+// auditedAttendance is what the audit log keeps of an attendance, the photo is only
+// noted
+type auditedAttendance struct {
+	AccountID  string    `json:"account_id"`
+	LocationID int       `json:"location_id"`
+	Status     string    `json:"status"`
+	DeviceID   *int      `json:"device_id"`
+	EventID    *string   `json:"event_id"`
+	Photo      bool      `json:"photo"`
+	Time       time.Time `json:"time"`
+}
+
+// StreamSink publishes relayed attendance events to the live stream. The relay runs on
+// one replica at a time, so the events go through the postgres channel and every
+// replica publishes them to its own hub from Run. The stream event id is the outbox
+// sequence of the event, it is the same on every replica so a client can resume on any
+// of them. The stream event type is the attendance status.
+type StreamSink struct {
+	hub     *pubsub.Hub
+	channel *pubsub.PostgresChannel
+}
+
+// streamNotification is the payload sent through the postgres channel
+type streamNotification struct {
+	ID    uint64          `json:"id"`
+	Event json.RawMessage `json:"event"`
+}
+
+func NewStreamSink(hub *pubsub.Hub, channel *pubsub.PostgresChannel) *StreamSink {
+	return &StreamSink{
+		hub:     hub,
+		channel: channel,
+	}
+}
+
+func (sink *StreamSink) Deliver(ctx context.Context, domainEvent event.Event) (err error) {
+	if domainEvent.Type != constant.EventAttendanceCheckIn && domainEvent.Type != constant.EventAttendanceCheckOut {
+		return
+	}
+
+	data, err := json.Marshal(domainEvent.Data)
+	if err != nil {
+		return
+	}
+	payload, err := json.Marshal(streamNotification{
+		ID:    uint64(domainEvent.Sequence),
+		Event: data,
+	})
+	if err != nil {
+		return
+	}
+	return sink.channel.Notify(ctx, payload)
+}
+
+// Run publishes the events any replica delivered to the hub of this one until ctx is
+// cancelled
+func (sink *StreamSink) Run(ctx context.Context) {
+	sink.channel.Listen(ctx, func(payload []byte) {
+		notification := streamNotification{}
+		streamEvent := http.AttendanceStreamEvent{}
+		err := json.Unmarshal(payload, &notification)
+		if err == nil && notification.ID == 0 {
+			err = errors.New("missing event id")
+		}
+		if err == nil {
+			err = json.Unmarshal(notification.Event, &streamEvent)
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("unmarshal attendance stream event", "error", err)
+			return
+		}
+		sink.hub.Publish(notification.ID, streamEvent.Status, streamEvent)
+	})
+}
+
+func presenceAccount(presence model.Presence) (account http.PresenceAccount) {
+	copier.Copy(&account, &presence)
+	account.ID = aes.Encrypt(presence.AccountID)
+	return
+}
+
+func (svc *Service) checkManager(ctx context.Context, accountID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, accountID, constant.RoleManager, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
+
+// FindExport returns every attendance of the account for its data export
+func (svc *Service) FindExport(ctx context.Context, accountID int) (attendances []http.ExportAttendance, err error) {
+	attendanceDatas, err := svc.repo.FindAll(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "find attendances")
+		return
+	}
+
+	locationIDs := []int{}
+	for i := range attendanceDatas {
+		locationIDs = append(locationIDs, attendanceDatas[i].LocationID)
+	}
+	locationNames := map[int]string{}
+	if len(locationIDs) > 0 {
+		locations, err := svc.location.Find(ctx, locationIDs)
+		if err != nil {
+			return nil, errors.Wrap(err, "find locations")
+		}
+		for i := range locations {
+			locationNames[locations[i].ID] = locations[i].LocationName
+		}
+	}
+
+	for i := range attendanceDatas {
+		attendance := http.ExportAttendance{
+			ID:           attendanceDatas[i].ID,
+			LocationID:   attendanceDatas[i].LocationID,
+			LocationName: locationNames[attendanceDatas[i].LocationID],
+			Status:       attendanceDatas[i].Status,
+			DeviceID:     attendanceDatas[i].DeviceID,
+			CreatedAt:    attendanceDatas[i].CreatedAt,
+		}
+		if attendanceDatas[i].PhotoKey != nil {
+			attendance.PhotoKey = *attendanceDatas[i].PhotoKey
+		}
+		attendances = append(attendances, attendance)
+	}
+	return
+}
+
+// ErasePhotos removes the selfies of every attendance of the account, the attendances
+// themselves are kept. The photos are deleted before they are detached so a failed
+// delete is retried on the next run.
+func (svc *Service) ErasePhotos(ctx context.Context, actor model.Actor, accountID int) (err error) {
+	photoKeys, err := svc.repo.FindPhotoKeys(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "find attendance photos")
+		return
+	}
+
+	for i := range photoKeys {
+		err = svc.storage.Delete(photoKeys[i])
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			err = errors.Wrap(err, "delete attendance photo")
+			return
+		}
+	}
+
+	err = svc.repo.ClearPhotos(ctx, accountID, func(accountID int) (entries []model.AuditLog, err error) {
+		if len(photoKeys) == 0 {
+			return
+		}
+		entry, _, err := audit.NewEntry(actor, constant.AuditActionErasePhotos, constant.AggregateAccount, accountID,
+			map[string]int{"attendance_photos": len(photoKeys)}, map[string]int{"attendance_photos": 0}, nil)
+		if err != nil {
+			err = errors.Wrap(err, "new attendance audit entry")
+			return
+		}
+		entries = append(entries, entry)
+		return
+	})
+	if err != nil {
+		err = errors.Wrap(err, "clear attendance photos")
+		return
+	}
+	return
+}
+
+func (svc *Service) storePhoto(accountID int, photo http.Photo) (photoKey string, err error) {
+	extension := ".jpg"
+	if photo.ContentType == "image/png" {
+		extension = ".png"
+	}
+	photoKey = fmt.Sprintf("attendances/%d/%s%s", accountID, uuid.GetUUID(), extension)
+
+	err = svc.storage.Put(photoKey, photo.Body, photo.Size, photo.ContentType)
+	if err != nil {
+		err = errors.Wrap(err, "store attendance photo")
+		return
+	}
+	return
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/audit"
+	"go-rest-api/src/service/v1/account"
+
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/pkg/errors"
+)
+
+type Service struct {
+	repo    audit.Repositorier
+	account account.Servicer
+}
+
+func NewService(
+	repositorier audit.Repositorier,
+	accountSvc account.Servicer,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		account: accountSvc,
+	}
+}
+
+type Servicer interface {
+	Find(ctx context.Context, adminID int, request http.FindAuditLogs, pgn pagination.Pagination) (entries []http.GetAuditLog, total int, err error)
+	FindByAccount(ctx context.Context, accountID int) (entries []http.GetAuditLog, err error)
+}
+
+func (svc *Service) Find(ctx context.Context, adminID int, request http.FindAuditLogs, pgn pagination.Pagination) (entries []http.GetAuditLog, total int, err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+
+	filter, err := auditLogFilter(request)
+	if err != nil {
+		return
+	}
+
+	auditLogs, count, err := svc.repo.Find(ctx, filter, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find audit logs")
+		return
+	}
+
+	entries = []http.GetAuditLog{}
+	for i := range auditLogs {
+		entry, err := getAuditLog(auditLogs[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	total = int(count)
+	return
+}
+
+// FindByAccount returns the entries made by the account or about it, meant for the
+// account itself. The request id and client ip of other actors are left out.
+func (svc *Service) FindByAccount(ctx context.Context, accountID int) (entries []http.GetAuditLog, err error) {
+	auditLogs, err := svc.repo.FindByAccount(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "find audit logs by account")
+		return
+	}
+
+	entries = []http.GetAuditLog{}
+	for i := range auditLogs {
+		entry, err := getAuditLog(auditLogs[i])
+		if err != nil {
+			return nil, err
+		}
+		if auditLogs[i].ActorID == nil || *auditLogs[i].ActorID != accountID {
+			entry.RequestID, entry.ClientIP = "", ""
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+// auditLogFilter turns the query into a filter, account ids are sent encrypted like
+// everywhere else and the to date is included
+func auditLogFilter(request http.FindAuditLogs) (filter model.AuditLogFilter, err error) {
+	filter = model.AuditLogFilter{
+		EntityType: request.EntityType,
+		Action:     request.Action,
+	}
+
+	if request.ActorID != "" {
+		filter.ActorID = aes.Decrypt(request.ActorID)
+		if filter.ActorID <= 0 {
+			err = constant.ErrInvalidActorID
+			return
+		}
+	}
+
+	if request.EntityID != "" {
+		if request.EntityType == constant.AggregateAccount {
+			filter.EntityID = aes.Decrypt(request.EntityID)
+		} else {
+			filter.EntityID, _ = strconv.Atoi(request.EntityID)
+		}
+		if filter.EntityID <= 0 {
+			err = constant.ErrInvalidEntityID
+			return
+		}
+	}
+
+	if request.From != "" {
+		filter.From, _ = time.Parse(constant.DateFormat, request.From)
+	}
+	if request.To != "" {
+		filter.To, _ = time.Parse(constant.DateFormat, request.To)
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+	return
+}
+
+func getAuditLog(auditLog model.AuditLog) (entry http.GetAuditLog, err error) {
+	entry = http.GetAuditLog{
+		ID:         auditLog.ID,
+		Action:     auditLog.Action,
+		EntityType: auditLog.EntityType,
+		EntityID:   strconv.Itoa(auditLog.EntityID),
+		CreatedAt:  auditLog.CreatedAt,
+	}
+	if auditLog.EntityType == constant.AggregateAccount {
+		entry.EntityID = aes.Encrypt(auditLog.EntityID)
+	}
+	if auditLog.ActorID != nil {
+		entry.ActorID = aes.Encrypt(*auditLog.ActorID)
+	}
+	if auditLog.RequestID != nil {
+		entry.RequestID = *auditLog.RequestID
+	}
+	if auditLog.ClientIP != nil {
+		entry.ClientIP = *auditLog.ClientIP
+	}
+
+	err = json.Unmarshal([]byte(auditLog.Changes), &entry.Changes)
+	if err != nil {
+		err = errors.Wrap(err, "unmarshal audit log changes")
+		return
+	}
+	return
+}
@@ -0,0 +1,252 @@
+package device
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/signature"
+	"go-rest-api/src/pkg/tracing"
+	"go-rest-api/src/repository/v1/device"
+	"go-rest-api/src/service/v1/account"
+
+	"github.com/jinzhu/copier"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo    device.Repositorier
+	account account.Servicer
+	config  config.Attendance
+}
+
+func NewService(
+	repositorier device.Repositorier,
+	accountSvc account.Servicer,
+	cfg config.Attendance,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		account: accountSvc,
+		config:  cfg,
+	}
+}
+
+type Servicer interface {
+	Find(ctx context.Context, accountID int) (devices []http.GetDevice, err error)
+	FindByAdmin(ctx context.Context, adminID int, accountID int) (devices []http.GetDevice, err error)
+	Register(ctx context.Context, accountID int, request http.RegisterDevice) (err error)
+	Revoke(ctx context.Context, adminID int, deviceID int) (err error)
+	UpdateBinding(ctx context.Context, actor model.Actor, accountID int, request http.UpdateDeviceBinding) (err error)
+	Authorize(ctx context.Context, accountID int, proof *http.DeviceProof) (deviceID *int, err error)
+	Erase(ctx context.Context, accountID int) (err error)
+}
+
+func (svc *Service) Find(ctx context.Context, accountID int) (devices []http.GetDevice, err error) {
+	devicesData, err := svc.repo.Find(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "find devices")
+		return
+	}
+	for i := range devicesData {
+		device := http.GetDevice{}
+		copier.Copy(&device, &devicesData[i])
+		device.ID = int(devicesData[i].ID)
+		devices = append(devices, device)
+	}
+	return
+}
+
+func (svc *Service) FindByAdmin(ctx context.Context, adminID int, accountID int) (devices []http.GetDevice, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	exist, err := svc.account.CheckAccountByID(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "check account by id")
+		return
+	}
+	if !exist {
+		err = constant.ErrAccountNotRegistered
+		return
+	}
+	return svc.Find(ctx, accountID)
+}
+
+// Register binds a device to the account. An active device id is never registered
+// again, replacing its key would let anyone with the account token take over the
+// binding, so a reinstalled app needs the old device revoked first
+func (svc *Service) Register(ctx context.Context, accountID int, request http.RegisterDevice) (err error) {
+	if _, err = signature.ParsePublicKey(request.PublicKey); err != nil {
+		err = constant.ErrInvalidPublicKey
+		return
+	}
+
+	_, err = svc.repo.TakeActiveDevice(ctx, accountID, request.DeviceID)
+	if err == nil {
+		err = constant.ErrDeviceAlreadyRegistered
+		return
+	} else if err != gorm.ErrRecordNotFound {
+		err = errors.Wrap(err, "take active device")
+		return
+	}
+
+	total, err := svc.repo.CountActive(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "count active devices")
+		return
+	}
+	if total >= int64(svc.config.MaxActiveDevices) {
+		err = constant.ErrDeviceLimitReached
+		return
+	}
+
+	newDevice := model.Device{}
+	copier.Copy(&newDevice, &request)
+	newDevice.AccountID = accountID
+
+	err = svc.repo.Create(ctx, newDevice)
+	if err != nil {
+		err = errors.Wrap(err, "create new device")
+		return
+	}
+	return
+}
+
+func (svc *Service) Revoke(ctx context.Context, adminID int, deviceID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.Revoke(ctx, deviceID)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrDeviceNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "revoke device")
+		return
+	}
+	return
+}
+
+func (svc *Service) UpdateBinding(ctx context.Context, actor model.Actor, accountID int, request http.UpdateDeviceBinding) (err error) {
+	err = svc.checkAdmin(ctx, actor.AccountID)
+	if err != nil {
+		return
+	}
+
+	err = svc.account.UpdateRequireBoundDevice(ctx, actor, accountID, *request.Required)
+	if err != nil {
+		err = errors.Wrap(err, "update device binding")
+		return
+	}
+	return
+}
+
+// Authorize resolves the device an attendance is submitted from. A request without
+// proof is only allowed when the account is not restricted to bound devices, a request
+// with proof must be signed by an active device of the account for its method and path,
+// and each signature is only accepted once.
+func (svc *Service) Authorize(ctx context.Context, accountID int, proof *http.DeviceProof) (deviceID *int, err error) {
+	ctx, span := tracing.Start(ctx, "device.Authorize")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	account, err := svc.account.TakeAccountByID(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "take account by id")
+		return
+	}
+
+	if proof == nil {
+		if account.RequireBoundDevice {
+			err = constant.ErrDeviceNotBound
+		}
+		return
+	}
+
+	device, err := svc.repo.TakeActiveDevice(ctx, accountID, proof.DeviceID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrDeviceNotBound
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take active device")
+		return
+	}
+
+	signedAt, err := strconv.ParseInt(proof.Timestamp, 10, 64)
+	if err != nil {
+		err = constant.ErrInvalidDeviceSignature
+		return
+	}
+	skew := time.Since(time.Unix(signedAt, 0))
+	if skew > svc.config.ClockSkewTolerance || -skew > svc.config.ClockSkewTolerance {
+		err = constant.ErrInvalidDeviceSignature
+		return
+	}
+
+	message := signature.Message(proof.DeviceID, proof.Timestamp, proof.Method, proof.Path, proof.Body)
+	if err = signature.Verify(device.PublicKey, proof.Signature, message); err != nil {
+		err = constant.ErrInvalidDeviceSignature
+		return
+	}
+
+	// a signature is accepted once, a captured request cannot be sent again while its
+	// timestamp is still within the tolerance
+	err = svc.repo.UseSignature(ctx, model.DeviceSignature{
+		DeviceID:  int(device.ID),
+		Signature: proof.Signature,
+		SignedAt:  time.Unix(signedAt, 0).UTC(),
+	}, time.Now().UTC().Add(-svc.config.ClockSkewTolerance))
+	if errors.Is(err, constant.ErrDeviceSignatureUsed) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "use device signature")
+		return
+	}
+
+	now := time.Now().UTC()
+	err = svc.repo.Update(ctx, int(device.ID), model.Device{
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "update device last used at")
+		return
+	}
+
+	id := int(device.ID)
+	deviceID = &id
+	return
+}
+
+// Erase revokes the devices of the account and removes what identifies them
+func (svc *Service) Erase(ctx context.Context, accountID int) (err error) {
+	err = svc.repo.Erase(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "erase devices")
+		return
+	}
+	return
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package encryption
+
+import (
+	"context"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/envelope"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/repository/v1/account"
+
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/pkg/errors"
+)
+
+// Service re-encrypts the encrypted account columns after the key is rotated. Values
+// encrypted with an older key get their data key rewrapped with the active key, values
+// stored before the columns were encrypted are encrypted, and blind indexes that are
+// missing or stale are recomputed.
+type Service struct {
+	repo    account.Repositorier
+	keyring *envelope.Keyring
+}
+
+func NewService(
+	repositorier account.Repositorier,
+	keyring *envelope.Keyring,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		keyring: keyring,
+	}
+}
+
+type Servicer interface {
+	Reencrypt(ctx context.Context, batchSize int) (scanned int, updated int, err error)
+	CheckBlindIndexes(ctx context.Context) (err error)
+}
+
+// CheckBlindIndexes fails when an account has a ktp or phone number without its blind
+// index. Lookups and duplicate checks only use the indexes, so such an account could
+// not log in and its numbers could be registered again. Reencrypt fills them.
+func (svc *Service) CheckBlindIndexes(ctx context.Context) (err error) {
+	total, err := svc.repo.CountMissingBlindIndexes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "count missing blind indexes")
+	}
+	if total > 0 {
+		return errors.Errorf("%d accounts have no blind index, run go run ./src/cmd/reencrypt first", total)
+	}
+	return
+}
+
+// Reencrypt walks every account in batches until all of them are up to date or ctx is
+// cancelled. Accounts already up to date are skipped, so it can be stopped and run again.
+func (svc *Service) Reencrypt(ctx context.Context, batchSize int) (scanned int, updated int, err error) {
+	afterID := 0
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		accounts, err := svc.repo.FindCiphertexts(ctx, afterID, batchSize)
+		if err != nil {
+			return scanned, updated, errors.Wrap(err, "find account ciphertexts")
+		}
+		if len(accounts) == 0 {
+			return scanned, updated, nil
+		}
+
+		for i := range accounts {
+			columns, err := svc.reencryptAccount(accounts[i])
+			if err != nil {
+				return scanned, updated, errors.Wrapf(err, "re-encrypt account %d", accounts[i].ID)
+			}
+			if len(columns) > 0 {
+				written, err := svc.repo.UpdateCiphertext(ctx, accounts[i], columns)
+				if err != nil {
+					return scanned, updated, errors.Wrapf(err, "update account %d", accounts[i].ID)
+				}
+				// a concurrent update already wrote the columns with the active key
+				if written {
+					updated++
+				}
+			}
+			scanned++
+		}
+		afterID = accounts[len(accounts)-1].ID
+		logging.FromContext(ctx).Info("re-encrypt accounts", "scanned", scanned, "updated", updated)
+	}
+}
+
+// reencryptAccount returns the columns of the account that have to be written
+func (svc *Service) reencryptAccount(account model.AccountCiphertext) (columns map[string]interface{}, err error) {
+	columns = map[string]interface{}{}
+	fields := []struct {
+		column      string
+		value       *string
+		indexColumn string
+		index       *string
+		legacy      func(value string) string
+	}{
+		{column: "address", value: account.Address},
+		{column: constant.FieldKTPNumber, value: account.KTPNumber, indexColumn: "ktp_number_index", index: account.KTPNumberIndex, legacy: legacyKTPNumber},
+		{column: constant.FieldPhoneNumber, value: account.PhoneNumber, indexColumn: "phone_number_index", index: account.PhoneNumberIndex},
+		{column: "date_of_birth", value: account.DateOfBirth},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+
+		plaintext := ""
+		ciphertext := *field.value
+		if envelope.IsEncrypted(ciphertext) {
+			plaintext, err = svc.keyring.Decrypt(ciphertext)
+			if err != nil {
+				return
+			}
+			ciphertext, err = svc.keyring.Rewrap(ciphertext)
+		} else {
+			plaintext = ciphertext
+			if field.legacy != nil {
+				plaintext = field.legacy(ciphertext)
+			}
+			ciphertext, err = svc.keyring.Encrypt(plaintext)
+		}
+		if err != nil {
+			return
+		}
+		if ciphertext != *field.value {
+			columns[field.column] = ciphertext
+		}
+
+		if field.indexColumn == "" {
+			continue
+		}
+		index := svc.blindIndex(field.column, plaintext)
+		if field.index == nil || *field.index != index {
+			columns[field.indexColumn] = index
+		}
+	}
+	return
+}
+
+// blindIndex hashes the value the way the account repository looks it up
+func (svc *Service) blindIndex(field, plaintext string) string {
+	return svc.keyring.BlindIndex(field, account.IndexedValue(field, plaintext))
+}
+
+// legacyKTPNumber reads a ktp number stored before the column was encrypted, those were
+// encoded like the ids
+func legacyKTPNumber(value string) string {
+	if number := aes.Decrypt(value); number > 0 {
+		return strconv.Itoa(number)
+	}
+	return value
+}
@@ -0,0 +1,316 @@
+package invitation
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/jwt"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/mail"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/invitation"
+	"go-rest-api/src/service/v1/account"
+	"go-rest-api/src/service/v1/organization"
+
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo         invitation.Repositorier
+	account      account.Servicer
+	organization organization.Servicer
+	mailer       mail.Mailer
+	config       config.Registration
+}
+
+func NewService(
+	repositorier invitation.Repositorier,
+	accountSvc account.Servicer,
+	organizationSvc organization.Servicer,
+	mailer mail.Mailer,
+	cfg config.Registration,
+) *Service {
+	return &Service{
+		repo:         repositorier,
+		account:      accountSvc,
+		organization: organizationSvc,
+		mailer:       mailer,
+		config:       cfg,
+	}
+}
+
+type Servicer interface {
+	Find(ctx context.Context, adminID int, status string, pgn pagination.Pagination) (invitations []http.GetInvitation, total int, err error)
+	Create(ctx context.Context, adminID int, request http.CreateInvitation) (created http.CreatedInvitation, err error)
+	Resend(ctx context.Context, adminID int, invitationID int) (created http.CreatedInvitation, err error)
+	Revoke(ctx context.Context, adminID int, invitationID int) (err error)
+	Verify(ctx context.Context, token string) (invitation http.GetInvitation, err error)
+	Accept(ctx context.Context, actor model.Actor, request http.AcceptInvitation) (err error)
+}
+
+func (svc *Service) Find(ctx context.Context, adminID int, status string, pgn pagination.Pagination) (invitations []http.GetInvitation, total int, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	invitationDatas, totalData, err := svc.repo.Find(ctx, status, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find invitations")
+		return
+	}
+
+	invitations = []http.GetInvitation{}
+	for i := range invitationDatas {
+		invitations = append(invitations, getInvitation(invitationDatas[i]))
+	}
+	total = int(totalData)
+	return
+}
+
+// Create invites an email that is not registered yet and mails it the registration link,
+// the department of the account follows the team
+func (svc *Service) Create(ctx context.Context, adminID int, request http.CreateInvitation) (created http.CreatedInvitation, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	email := strings.ToLower(request.Email)
+	exist, err := svc.account.CheckAccountByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+	if exist {
+		err = constant.ErrEmailAlreadyExist
+		return
+	}
+
+	_, err = svc.repo.TakePendingInvitationByEmail(ctx, email)
+	if err == nil {
+		err = constant.ErrInvitationAlreadyExist
+		return
+	} else if err != gorm.ErrRecordNotFound {
+		err = errors.Wrap(err, "take pending invitation by email")
+		return
+	}
+
+	newInvitation := model.Invitation{
+		Email:     email,
+		Role:      constant.RoleEmployee,
+		InvitedBy: adminID,
+		ExpiresAt: time.Now().Add(svc.config.InvitationExpiry),
+		Version:   1,
+	}
+	if request.Role != "" {
+		newInvitation.Role = request.Role
+	}
+	if request.ExpiresInHours != 0 {
+		newInvitation.ExpiresAt = time.Now().Add(time.Duration(request.ExpiresInHours) * time.Hour)
+	}
+	if request.TeamID != nil {
+		team, err := svc.organization.TakeTeamByID(ctx, *request.TeamID)
+		if err != nil {
+			return created, err
+		}
+		newInvitation.TeamID = &team.ID
+		newInvitation.DepartmentID = &team.DepartmentID
+	}
+
+	invitationID, err := svc.repo.Create(ctx, newInvitation)
+	if err != nil {
+		err = errors.Wrap(err, "create invitation")
+		return
+	}
+	newInvitation.ID = uint(invitationID)
+	newInvitation.CreatedAt = time.Now()
+
+	return svc.send(ctx, newInvitation)
+}
+
+// Resend extends a pending or expired invitation and mails a new link, the links sent
+// before stop working
+func (svc *Service) Resend(ctx context.Context, adminID int, invitationID int) (created http.CreatedInvitation, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	invitationData, err := svc.takeInvitation(ctx, invitationID)
+	if err != nil {
+		return
+	}
+	if invitationData.AcceptedAt != nil {
+		err = constant.ErrInvitationAlreadyUsed
+		return
+	}
+
+	invitationData.ExpiresAt = time.Now().Add(svc.config.InvitationExpiry)
+	invitationData.Version, err = svc.repo.Reissue(ctx, invitationID, invitationData.ExpiresAt)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrInvitationAlreadyUsed
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "reissue invitation")
+		return
+	}
+
+	return svc.send(ctx, invitationData)
+}
+
+func (svc *Service) Revoke(ctx context.Context, adminID int, invitationID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	invitationData, err := svc.takeInvitation(ctx, invitationID)
+	if err != nil {
+		return
+	}
+	if invitationData.AcceptedAt != nil {
+		err = constant.ErrInvitationAlreadyUsed
+		return
+	}
+
+	err = svc.repo.Delete(ctx, invitationID)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrInvitationNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "delete invitation")
+		return
+	}
+	return
+}
+
+// Verify tells whether a token belongs to an invitation that can still be accepted
+func (svc *Service) Verify(ctx context.Context, token string) (invitation http.GetInvitation, err error) {
+	invitationData, err := svc.takeUsableInvitation(ctx, token)
+	if err != nil {
+		return
+	}
+	invitation = getInvitation(invitationData)
+	return
+}
+
+func (svc *Service) Accept(ctx context.Context, actor model.Actor, request http.AcceptInvitation) (err error) {
+	invitationData, err := svc.takeUsableInvitation(ctx, request.Token)
+	if err != nil {
+		return
+	}
+
+	err = svc.account.CreateInvited(ctx, actor, http.RegisterUser{
+		Username: strings.ToLower(request.Username),
+		FullName: request.FullName,
+		Password: request.Password,
+	}, invitationData)
+	return
+}
+
+// send mails the registration link, a mail failure is only logged since the link is
+// also returned to the admin
+func (svc *Service) send(ctx context.Context, invitationData model.Invitation) (created http.CreatedInvitation, err error) {
+	token, err := jwt.GenerateInvitationToken(aes.Encrypt(int(invitationData.ID)), invitationData.Version, invitationData.ExpiresAt)
+	if err != nil {
+		err = errors.Wrap(err, "generate invitation token")
+		return
+	}
+
+	created = http.CreatedInvitation{
+		GetInvitation: getInvitation(invitationData),
+		Link:          svc.config.InvitationURL + "?token=" + url.QueryEscape(token),
+	}
+
+	err = svc.mailer.Send(invitationData.Email, "You are invited to the attendance app", fmt.Sprintf(
+		"Hello,\n\nYou are invited to register an attendance account. Open the link below to "+
+			"choose your username and password, it is valid until %s.\n\n%s\n",
+		invitationData.ExpiresAt.Format(time.RFC1123), created.Link))
+	if err != nil {
+		logging.FromContext(ctx).Error("send invitation", "error", err, "invitation_id", invitationData.ID)
+		err = nil
+	}
+	return
+}
+
+func (svc *Service) takeUsableInvitation(ctx context.Context, token string) (invitation model.Invitation, err error) {
+	invitationID, version, err := jwt.ExtractInvitationID(token)
+	if err != nil {
+		err = constant.ErrInvalidInvitationToken
+		return
+	}
+
+	invitation, err = svc.takeInvitation(ctx, invitationID)
+	if err != nil {
+		return
+	}
+	// a link replaced by a resend is never accepted again, even before it expires
+	if version != invitation.Version {
+		err = constant.ErrInvalidInvitationToken
+		return
+	}
+	if invitation.AcceptedAt != nil {
+		err = constant.ErrInvitationAlreadyUsed
+		return
+	}
+	if !invitation.ExpiresAt.After(time.Now()) {
+		err = constant.ErrInvitationExpired
+		return
+	}
+	return
+}
+
+func (svc *Service) takeInvitation(ctx context.Context, invitationID int) (invitation model.Invitation, err error) {
+	invitation, err = svc.repo.TakeInvitationByID(ctx, invitationID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrInvitationNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take invitation by id")
+		return
+	}
+	return
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
+
+func getInvitation(invitation model.Invitation) http.GetInvitation {
+	status := constant.InvitationStatusPending
+	if invitation.AcceptedAt != nil {
+		status = constant.InvitationStatusAccepted
+	} else if !invitation.ExpiresAt.After(time.Now()) {
+		status = constant.InvitationStatusExpired
+	}
+
+	return http.GetInvitation{
+		ID:           int(invitation.ID),
+		Email:        invitation.Email,
+		Role:         invitation.Role,
+		DepartmentID: invitation.DepartmentID,
+		TeamID:       invitation.TeamID,
+		Status:       status,
+		ExpiresAt:    invitation.ExpiresAt,
+		AcceptedAt:   invitation.AcceptedAt,
+		CreatedAt:    invitation.CreatedAt,
+	}
+}
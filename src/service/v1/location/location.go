@@ -1,40 +1,67 @@
 package location
 
 import (
+	"context"
 	"github.com/jinzhu/copier"
 	"github.com/pkg/errors"
 	"go-rest-api/src/constant"
 	"go-rest-api/src/http"
 	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/imaging"
+	"go-rest-api/src/pkg/tracing"
+	"go-rest-api/src/repository/v1/audit"
 	"go-rest-api/src/repository/v1/location"
+	"go-rest-api/src/repository/v1/outbox"
+	"go-rest-api/src/service/v1/photo"
 	"gorm.io/gorm"
 )
 
+// photoVariants are the sizes an uploaded location photo is stored in, the larger ones
+// keep the aspect of the photo and the photo url points to the medium one
+var photoVariants = []imaging.Variant{
+	{Name: constant.PhotoVariantLarge, Width: 1280, Height: 1280},
+	{Name: constant.PhotoVariantMedium, Width: 640, Height: 640},
+	{Name: constant.PhotoVariantSmall, Width: 200, Height: 200, Crop: true},
+}
+
 type Service struct {
-	repo location.Repositorier
+	repo   location.Repositorier
+	outbox event.Notifier
+	photo  photo.Servicer
 }
 
 func NewService(
 	repositorier location.Repositorier,
+	notifier event.Notifier,
+	photoServicer photo.Servicer,
 ) *Service {
 	return &Service{
-		repo: repositorier,
+		repo:   repositorier,
+		outbox: notifier,
+		photo:  photoServicer,
 	}
 }
 
 type Servicer interface {
-	TakeLocationByID(locationID int) (locations http.GetLocation, err error)
-	TakeLocationByName(locationName string) (location model.Location, err error)
-	Find(locationIDs []int) (locations []http.GetLocation, err error)
-	CheckLocationByID(locationID int) (exist bool, err error)
-	CheckLocationByName(locationName string) (exist bool, err error)
-	Create(request http.CreateLocation) (err error)
-	Update(locationID int, request http.UpdateLocation) (err error)
-	Delete(locationID int) (err error)
-}
-
-func (svc *Service) TakeLocationByID(locationID int) (location http.GetLocation, err error) {
-	takeLocation, err := svc.repo.TakeLocationByID(locationID)
+	TakeLocationByID(ctx context.Context, locationID int) (locations http.GetLocation, err error)
+	TakeLocationByName(ctx context.Context, locationName string) (location model.Location, err error)
+	Find(ctx context.Context, locationIDs []int) (locations []http.GetLocation, err error)
+	CheckLocationByID(ctx context.Context, locationID int) (exist bool, err error)
+	CheckLocationByName(ctx context.Context, locationName string) (exist bool, err error)
+	Create(ctx context.Context, actor model.Actor, request http.CreateLocation) (err error)
+	Update(ctx context.Context, actor model.Actor, locationID int, request http.UpdateLocation) (err error)
+	UpdatePhoto(ctx context.Context, actor model.Actor, locationID int, request http.Photo) (photoURL string, err error)
+	Delete(ctx context.Context, actor model.Actor, locationID int) (err error)
+}
+
+func (svc *Service) TakeLocationByID(ctx context.Context, locationID int) (location http.GetLocation, err error) {
+	ctx, span := tracing.Start(ctx, "location.TakeLocationByID")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	takeLocation, err := svc.repo.TakeLocationByID(ctx, locationID)
 	if err == gorm.ErrRecordNotFound {
 		err = constant.ErrLocationNotExist
 		return
@@ -43,14 +70,12 @@ func (svc *Service) TakeLocationByID(locationID int) (location http.GetLocation,
 		return
 	}
 
-	location = http.GetLocation{}
-	copier.Copy(&location, &takeLocation)
-	location.ID = int(location.ID)
+	location = svc.getLocation(takeLocation)
 	return
 }
 
-func (svc *Service) TakeLocationByName(locationName string) (location model.Location, err error) {
-	location, err = svc.repo.TakeLocationByName(locationName)
+func (svc *Service) TakeLocationByName(ctx context.Context, locationName string) (location model.Location, err error) {
+	location, err = svc.repo.TakeLocationByName(ctx, locationName)
 	if err == gorm.ErrRecordNotFound {
 		err = constant.ErrLocationNotExist
 		return
@@ -61,24 +86,22 @@ func (svc *Service) TakeLocationByName(locationName string) (location model.Loca
 	return
 }
 
-func (svc *Service) Find(locationIDs []int) (locations []http.GetLocation, err error) {
-	locationsData, err := svc.repo.Find(locationIDs)
+func (svc *Service) Find(ctx context.Context, locationIDs []int) (locations []http.GetLocation, err error) {
+	locationsData, err := svc.repo.Find(ctx, locationIDs)
 	if err != nil {
 		err = errors.Wrap(err, "find locations")
 		return
 	}
 	for i := range locationsData {
-		location := http.GetLocation{}
-		copier.Copy(&location, &locationsData[i])
-		location.ID = int(location.ID)
+		location := svc.getLocation(locationsData[i])
 		locations = append(locations, location)
 	} 
 	return
 }
 
-func (svc *Service) CheckLocationByID(locationID int) (exist bool, err error) {
+func (svc *Service) CheckLocationByID(ctx context.Context, locationID int) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeLocationByID(locationID)
+	_, err = svc.repo.TakeLocationByID(ctx, locationID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -93,9 +116,9 @@ func (svc *Service) CheckLocationByID(locationID int) (exist bool, err error) {
 	return
 }
 
-func (svc *Service) CheckLocationByName(locationName string) (exist bool, err error) {
+func (svc *Service) CheckLocationByName(ctx context.Context, locationName string) (exist bool, err error) {
 	exist = false
-	_, err = svc.repo.TakeLocationByName(locationName)
+	_, err = svc.repo.TakeLocationByName(ctx, locationName)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			exist = false
@@ -110,7 +133,7 @@ func (svc *Service) CheckLocationByName(locationName string) (exist bool, err er
 	return
 }
 
-func (svc *Service) Create(request http.CreateLocation) (err error) {
+func (svc *Service) Create(ctx context.Context, actor model.Actor, request http.CreateLocation) (err error) {
 	if request.LocationName == "" {
 		err = constant.ErrInvalidLocationName
 		return
@@ -121,7 +144,7 @@ func (svc *Service) Create(request http.CreateLocation) (err error) {
 		return
 	}
 
-	exist, err := svc.CheckLocationByName(request.LocationName)
+	exist, err := svc.CheckLocationByName(ctx, request.LocationName)
 	if err != nil {
 		return
 	}
@@ -131,28 +154,27 @@ func (svc *Service) Create(request http.CreateLocation) (err error) {
 	} else {
 		newLocation := model.Location{}
 		copier.Copy(&newLocation, &request)
-		if newLocation.PhotoURL == "" {
-			newLocation.PhotoURL = "https://th.bing.com/th/id/OIP.gBRzG71aa1f6dy_MuGUwOAHaEo?pid=ImgDet&rs=1"
-		}
 
-		err = svc.repo.Create(newLocation)
+		created := svc.getLocation(newLocation)
+		_, err = svc.repo.Create(ctx, newLocation, outboxEvents(constant.EventLocationCreated, created), auditEntries(actor, constant.AuditActionCreate, nil, &created))
 		if err != nil {
 			err = errors.Wrap(err, "create new location")
 			return err
 		}
+		svc.outbox.Notify()
 	}
 	return
 }
 
-func (svc *Service) Update(locationID int, request http.UpdateLocation) (err error) {
-	exist, _ := svc.CheckLocationByID(locationID)
+func (svc *Service) Update(ctx context.Context, actor model.Actor, locationID int, request http.UpdateLocation) (err error) {
+	exist, _ := svc.CheckLocationByID(ctx, locationID)
 	if !exist {
 		err = constant.ErrLocationNotExist
 		return
 	}
 
 	if request.LocationName != "" {
-	    locationName, _ := svc.CheckLocationByName(request.LocationName)
+	    locationName, _ := svc.CheckLocationByName(ctx, request.LocationName)
 	    if locationName {
 		    err = constant.ErrLocationNameAlreadyExist
 		    return
@@ -162,25 +184,117 @@ func (svc *Service) Update(locationID int, request http.UpdateLocation) (err err
 	location := model.Location{}
 	copier.Copy(&location, &request)
 
-	err = svc.repo.Update(locationID, location)
+	// the event carries the location as it is after the update
+	updated, err := svc.TakeLocationByID(ctx, locationID)
+	if err != nil {
+		return
+	}
+	before := updated
+	copier.CopyWithOption(&updated, &location, copier.Option{IgnoreEmpty: true})
+	if request.RequirePhoto != nil {
+		updated.RequirePhoto = *request.RequirePhoto
+	}
+
+	err = svc.repo.Update(ctx, locationID, location, request.RequirePhoto, outboxEvents(constant.EventLocationUpdated, updated), auditEntries(actor, constant.AuditActionUpdate, &before, &updated))
 	if err != nil {
 		err = errors.Wrap(err, "update location")
 		return
 	}
+	svc.outbox.Notify()
+	return
+}
+
+// UpdatePhoto stores the uploaded photo and points the location to it, the previous
+// photo is removed once the location no longer uses it
+func (svc *Service) UpdatePhoto(ctx context.Context, actor model.Actor, locationID int, request http.Photo) (photoURL string, err error) {
+	takeLocation, err := svc.repo.TakeLocationByID(ctx, locationID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrLocationNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take location")
+		return
+	}
+	previousPhotoURL := takeLocation.PhotoURL
+
+	photoURL, err = svc.photo.Store(ctx, "locations", request, photoVariants)
+	if err != nil {
+		return
+	}
+
+	before := svc.getLocation(takeLocation)
+	takeLocation.PhotoURL = photoURL
+	updated := svc.getLocation(takeLocation)
+	err = svc.repo.Update(ctx, locationID, model.Location{PhotoURL: photoURL}, nil, outboxEvents(constant.EventLocationUpdated, updated), auditEntries(actor, constant.AuditActionUpdate, &before, &updated))
+	if err != nil {
+		svc.photo.Delete(ctx, photoURL, photoVariants)
+		err = errors.Wrap(err, "update location photo")
+		return
+	}
+	svc.outbox.Notify()
+
+	svc.photo.Delete(ctx, previousPhotoURL, photoVariants)
 	return
 }
 
-func (svc *Service) Delete(locationID int) (err error) {
-	_, err = svc.TakeLocationByID(locationID)
+func (svc *Service) Delete(ctx context.Context, actor model.Actor, locationID int) (err error) {
+	location, err := svc.TakeLocationByID(ctx, locationID)
 	if err != nil {
 		err = errors.Wrap(err, "location is not exist")
 		return
 	}
 
-	err = svc.repo.Delete(locationID)
+	err = svc.repo.Delete(ctx, locationID, outboxEvents(constant.EventLocationDeleted, location), auditEntries(actor, constant.AuditActionDelete, &location, nil))
 	if err != nil {
 		err = errors.Wrap(err, "delete location")
 		return
 	}
+	svc.outbox.Notify()
+	return
+}
+
+// outboxEvents builds the event of a location write, the id is only known inside the
+// write transaction when the location is created
+func outboxEvents(eventType string, location http.GetLocation) outbox.Builder {
+	return func(locationID int) (events []model.OutboxEvent, err error) {
+		location.ID = locationID
+		outboxEvent, err := outbox.NewEvent(constant.AggregateLocation, locationID, eventType, location)
+		if err != nil {
+			err = errors.Wrap(err, "new location event")
+			return
+		}
+		events = append(events, outboxEvent)
+		return
+	}
+}
+
+// auditEntries builds the audit entry of a location write, before is nil when the
+// location is created and after is nil when it is deleted
+func auditEntries(actor model.Actor, action string, before, after *http.GetLocation) audit.Builder {
+	return func(locationID int) (entries []model.AuditLog, err error) {
+		var location *http.GetLocation
+		if after != nil {
+			written := *after
+			written.ID = locationID
+			location = &written
+		}
+		entry, changed, err := audit.NewEntry(actor, action, constant.AggregateLocation, locationID, before, location, nil)
+		if err != nil {
+			err = errors.Wrap(err, "new location audit entry")
+			return
+		}
+		if changed {
+			entries = append(entries, entry)
+		}
+		return
+	}
+}
+
+func (svc *Service) getLocation(location model.Location) (getLocation http.GetLocation) {
+	copier.Copy(&getLocation, &location)
+	getLocation.ID = int(location.ID)
+	if getLocation.PhotoURL == "" {
+		getLocation.PhotoURL = svc.photo.PlaceholderURL(location.LocationName)
+	}
 	return
 }
@@ -0,0 +1,445 @@
+package organization
+
+import (
+	"context"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/repository/v1/organization"
+	"go-rest-api/src/service/v1/account"
+
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo    organization.Repositorier
+	account account.Servicer
+}
+
+func NewService(
+	repositorier organization.Repositorier,
+	accountSvc account.Servicer,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		account: accountSvc,
+	}
+}
+
+type Servicer interface {
+	FindDepartments(ctx context.Context) (departments []http.GetDepartment, err error)
+	CreateDepartment(ctx context.Context, adminID int, request http.CreateDepartment) (err error)
+	UpdateDepartment(ctx context.Context, adminID int, departmentID int, request http.UpdateDepartment) (err error)
+	DeleteDepartment(ctx context.Context, adminID int, departmentID int) (err error)
+	FindTeams(ctx context.Context, departmentID int) (teams []http.GetTeam, err error)
+	TakeTeamByID(ctx context.Context, teamID int) (team http.GetTeam, err error)
+	CreateTeam(ctx context.Context, adminID int, request http.CreateTeam) (err error)
+	UpdateTeam(ctx context.Context, adminID int, teamID int, request http.UpdateTeam) (err error)
+	DeleteTeam(ctx context.Context, adminID int, teamID int) (err error)
+	UpdateReportingLine(ctx context.Context, actor model.Actor, accountID int, request http.UpdateReportingLine) (err error)
+}
+
+func (svc *Service) FindDepartments(ctx context.Context) (departments []http.GetDepartment, err error) {
+	departmentDatas, err := svc.repo.FindDepartments(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "find departments")
+		return
+	}
+
+	departments = []http.GetDepartment{}
+	for i := range departmentDatas {
+		department := http.GetDepartment{
+			ID:   int(departmentDatas[i].ID),
+			Name: departmentDatas[i].Name,
+		}
+		if departmentDatas[i].Description != nil {
+			department.Description = *departmentDatas[i].Description
+		}
+		departments = append(departments, department)
+	}
+	return
+}
+
+func (svc *Service) CreateDepartment(ctx context.Context, adminID int, request http.CreateDepartment) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.checkDepartmentName(ctx, request.Name)
+	if err != nil {
+		return
+	}
+
+	department := model.Department{
+		Name: request.Name,
+	}
+	if request.Description != "" {
+		department.Description = &request.Description
+	}
+	_, err = svc.repo.CreateDepartment(ctx, department)
+	if err != nil {
+		err = errors.Wrap(err, "create department")
+		return
+	}
+	return
+}
+
+func (svc *Service) UpdateDepartment(ctx context.Context, adminID int, departmentID int, request http.UpdateDepartment) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	current, err := svc.takeDepartment(ctx, departmentID)
+	if err != nil {
+		return
+	}
+
+	department := model.Department{}
+	columns := []string{}
+	if request.Name != nil && *request.Name != current.Name {
+		err = svc.checkDepartmentName(ctx, *request.Name)
+		if err != nil {
+			return
+		}
+		department.Name = *request.Name
+		columns = append(columns, "name")
+	}
+	if request.Description != nil {
+		department.Description = request.Description
+		columns = append(columns, "description")
+	}
+	if len(columns) == 0 {
+		return
+	}
+
+	err = svc.repo.UpdateDepartment(ctx, departmentID, department, columns)
+	if err != nil {
+		err = errors.Wrap(err, "update department")
+		return
+	}
+	return
+}
+
+func (svc *Service) DeleteDepartment(ctx context.Context, adminID int, departmentID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.takeDepartment(ctx, departmentID)
+	if err != nil {
+		return
+	}
+
+	total, err := svc.repo.CountDepartmentMembers(ctx, departmentID)
+	if err != nil {
+		err = errors.Wrap(err, "count department members")
+		return
+	}
+	if total != 0 {
+		err = constant.ErrDepartmentNotEmpty
+		return
+	}
+
+	err = svc.repo.DeleteDepartment(ctx, departmentID)
+	if err != nil {
+		err = errors.Wrap(err, "delete department")
+		return
+	}
+	return
+}
+
+func (svc *Service) FindTeams(ctx context.Context, departmentID int) (teams []http.GetTeam, err error) {
+	teamDatas, err := svc.repo.FindTeams(ctx, departmentID)
+	if err != nil {
+		err = errors.Wrap(err, "find teams")
+		return
+	}
+
+	departments := make(map[int]string)
+	teams = []http.GetTeam{}
+	for i := range teamDatas {
+		name, ok := departments[teamDatas[i].DepartmentID]
+		if !ok {
+			department, err := svc.takeDepartment(ctx, teamDatas[i].DepartmentID)
+			if err != nil {
+				return nil, err
+			}
+			name = department.Name
+			departments[teamDatas[i].DepartmentID] = name
+		}
+
+		teams = append(teams, http.GetTeam{
+			ID:             int(teamDatas[i].ID),
+			Name:           teamDatas[i].Name,
+			DepartmentID:   teamDatas[i].DepartmentID,
+			DepartmentName: name,
+		})
+	}
+	return
+}
+
+func (svc *Service) TakeTeamByID(ctx context.Context, teamID int) (team http.GetTeam, err error) {
+	teamData, err := svc.takeTeam(ctx, teamID)
+	if err != nil {
+		return
+	}
+	department, err := svc.takeDepartment(ctx, teamData.DepartmentID)
+	if err != nil {
+		return
+	}
+
+	team = http.GetTeam{
+		ID:             int(teamData.ID),
+		Name:           teamData.Name,
+		DepartmentID:   teamData.DepartmentID,
+		DepartmentName: department.Name,
+	}
+	return
+}
+
+func (svc *Service) CreateTeam(ctx context.Context, adminID int, request http.CreateTeam) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.takeDepartment(ctx, request.DepartmentID)
+	if err != nil {
+		return
+	}
+
+	err = svc.checkTeamName(ctx, request.DepartmentID, request.Name)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.repo.CreateTeam(ctx, model.Team{
+		Name:         request.Name,
+		DepartmentID: request.DepartmentID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "create team")
+		return
+	}
+	return
+}
+
+// UpdateTeam renames a team or moves it to another department, a team can only be
+// moved while it has no accounts so the department of its members stays consistent
+func (svc *Service) UpdateTeam(ctx context.Context, adminID int, teamID int, request http.UpdateTeam) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	current, err := svc.takeTeam(ctx, teamID)
+	if err != nil {
+		return
+	}
+
+	team := model.Team{}
+	columns := []string{}
+	departmentID := current.DepartmentID
+	if request.DepartmentID != nil && *request.DepartmentID != current.DepartmentID {
+		_, err = svc.takeDepartment(ctx, *request.DepartmentID)
+		if err != nil {
+			return
+		}
+
+		total, err := svc.repo.CountTeamMembers(ctx, teamID)
+		if err != nil {
+			return errors.Wrap(err, "count team members")
+		}
+		if total != 0 {
+			return constant.ErrTeamNotEmpty
+		}
+
+		departmentID = *request.DepartmentID
+		team.DepartmentID = departmentID
+		columns = append(columns, "department_id")
+	}
+
+	name := current.Name
+	if request.Name != nil {
+		name = *request.Name
+		team.Name = name
+		columns = append(columns, "name")
+	}
+	if name != current.Name || departmentID != current.DepartmentID {
+		err = svc.checkTeamName(ctx, departmentID, name)
+		if err != nil {
+			return
+		}
+	}
+	if len(columns) == 0 {
+		return
+	}
+
+	err = svc.repo.UpdateTeam(ctx, teamID, team, columns)
+	if err != nil {
+		err = errors.Wrap(err, "update team")
+		return
+	}
+	return
+}
+
+func (svc *Service) DeleteTeam(ctx context.Context, adminID int, teamID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.takeTeam(ctx, teamID)
+	if err != nil {
+		return
+	}
+
+	total, err := svc.repo.CountTeamMembers(ctx, teamID)
+	if err != nil {
+		err = errors.Wrap(err, "count team members")
+		return
+	}
+	if total != 0 {
+		err = constant.ErrTeamNotEmpty
+		return
+	}
+
+	err = svc.repo.DeleteTeam(ctx, teamID)
+	if err != nil {
+		err = errors.Wrap(err, "delete team")
+		return
+	}
+	return
+}
+
+// UpdateReportingLine places an account in a department, a team and under a manager.
+// Fields left out of the request keep their value, the department follows the team
+// when only the team is sent.
+func (svc *Service) UpdateReportingLine(ctx context.Context, actor model.Actor, accountID int, request http.UpdateReportingLine) (err error) {
+	err = svc.checkAdmin(ctx, actor.AccountID)
+	if err != nil {
+		return
+	}
+
+	user, err := svc.account.TakeAccountByID(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	departmentID, teamID := user.DepartmentID, user.TeamID
+	var managerID *int
+	if user.ManagerID != "" {
+		id := aes.Decrypt(user.ManagerID)
+		managerID = &id
+	}
+
+	if request.DepartmentID != nil {
+		departmentID = nil
+		if *request.DepartmentID != 0 {
+			_, err = svc.takeDepartment(ctx, *request.DepartmentID)
+			if err != nil {
+				return
+			}
+			departmentID = request.DepartmentID
+		}
+	}
+
+	if request.TeamID != nil {
+		teamID = nil
+		if *request.TeamID != 0 {
+			teamID = request.TeamID
+		}
+	}
+	if teamID != nil {
+		team, err := svc.takeTeam(ctx, *teamID)
+		if err != nil {
+			return err
+		}
+		if request.DepartmentID == nil && request.TeamID != nil {
+			departmentID = &team.DepartmentID
+		} else if departmentID == nil || *departmentID != team.DepartmentID {
+			return constant.ErrTeamNotInDepartment
+		}
+	}
+
+	if request.ManagerID != nil {
+		managerID = nil
+		if *request.ManagerID != "" {
+			id := aes.Decrypt(*request.ManagerID)
+			if id < 0 {
+				err = constant.ErrManagerNotExist
+				return
+			}
+			managerID = &id
+		}
+	}
+
+	err = svc.account.UpdateReportingLine(ctx, actor, accountID, departmentID, teamID, managerID)
+	if err != nil {
+		err = errors.Wrap(err, "update reporting line")
+		return
+	}
+	return
+}
+
+func (svc *Service) takeDepartment(ctx context.Context, departmentID int) (department model.Department, err error) {
+	department, err = svc.repo.TakeDepartmentByID(ctx, departmentID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrDepartmentNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take department by id")
+		return
+	}
+	return
+}
+
+func (svc *Service) takeTeam(ctx context.Context, teamID int) (team model.Team, err error) {
+	team, err = svc.repo.TakeTeamByID(ctx, teamID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrTeamNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take team by id")
+		return
+	}
+	return
+}
+
+func (svc *Service) checkDepartmentName(ctx context.Context, name string) (err error) {
+	_, err = svc.repo.TakeDepartmentByName(ctx, name)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "take department by name")
+	}
+	return constant.ErrDepartmentAlreadyExist
+}
+
+func (svc *Service) checkTeamName(ctx context.Context, departmentID int, name string) (err error) {
+	_, err = svc.repo.TakeTeamByName(ctx, departmentID, name)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "take team by name")
+	}
+	return constant.ErrTeamNameAlreadyExist
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
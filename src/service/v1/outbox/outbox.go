@@ -0,0 +1,276 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/outbox"
+	"go-rest-api/src/service/v1/account"
+
+	"github.com/jinzhu/copier"
+	"github.com/pkg/errors"
+)
+
+// Relay publishes committed outbox events to the sinks. Events are published in id
+// order, when one fails the later events of the same aggregate wait for its retry,
+// so every sink sees the events of an aggregate at least once and in order. The
+// events are claimed in a short transaction and delivered outside of it, a slow sink
+// holds neither a connection nor the lock. An event that fails max attempts times is
+// dead, it stops holding back its aggregate and waits for an admin to requeue it.
+type Relay struct {
+	repo   outbox.Repositorier
+	sinks  []event.Sink
+	wake   chan struct{}
+	config config.Outbox
+}
+
+func NewRelay(
+	repositorier outbox.Repositorier,
+	cfg config.Outbox,
+	sinks ...event.Sink,
+) *Relay {
+	return &Relay{
+		repo:   repositorier,
+		sinks:  sinks,
+		wake:   make(chan struct{}, 1),
+		config: cfg,
+	}
+}
+
+// Notify wakes the relay up before its next poll, services call it after a commit
+func (relay *Relay) Notify() {
+	select {
+	case relay.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays events until ctx is cancelled
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-relay.wake:
+		}
+
+		err := relay.publish(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Error("relay outbox events", "error", err)
+		}
+	}
+}
+
+func (relay *Relay) publish(ctx context.Context) (err error) {
+	var claimed []model.OutboxEvent
+	_, err = relay.repo.Lock(ctx, func(repo outbox.Repositorier) (err error) {
+		claimed, err = relay.claim(ctx, repo)
+		return
+	})
+	if err != nil || len(claimed) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	blocked := make(map[string]bool)
+	claimedIDs := []int64{}
+	published := []int64{}
+	failed := []model.OutboxEvent{}
+	for i := range claimed {
+		claimedIDs = append(claimedIDs, claimed[i].ID)
+		aggregate := aggregateKey(claimed[i])
+		if blocked[aggregate] {
+			continue
+		}
+
+		deliverErr := relay.deliver(ctx, claimed[i])
+		if deliverErr == nil {
+			published = append(published, claimed[i].ID)
+			continue
+		}
+
+		blocked[aggregate] = true
+		message := deliverErr.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+		failedEvent := claimed[i]
+		failedEvent.Attempts++
+		failedEvent.NextAttemptAt = now.Add(relay.backoff(failedEvent.Attempts))
+		failedEvent.LastError = &message
+		if failedEvent.Attempts >= relay.config.MaxAttempts {
+			failedEvent.Status = constant.OutboxStatusDead
+			logging.FromContext(ctx).Error("outbox event ran out of attempts", "error", deliverErr, "event_id", failedEvent.EventID, "aggregate", aggregate)
+		}
+		failed = append(failed, failedEvent)
+	}
+
+	// the claim is released even when ctx is cancelled, so another relay can take over
+	err = relay.repo.Complete(context.Background(), claimedIDs, published, failed)
+	if err != nil {
+		err = errors.Wrap(err, "complete outbox events")
+		return
+	}
+	return
+}
+
+// claim returns the events due for delivery in id order and claims them. An event
+// waiting for its retry or claimed by another relay holds back the later events of
+// its aggregate.
+func (relay *Relay) claim(ctx context.Context, repo outbox.Repositorier) (claimed []model.OutboxEvent, err error) {
+	events, err := repo.FindUnpublished(ctx, constant.OutboxBatchSize)
+	if err != nil {
+		err = errors.Wrap(err, "find unpublished outbox events")
+		return
+	}
+
+	now := time.Now().UTC()
+	blocked := make(map[string]bool)
+	claimedIDs := []int64{}
+	for i := range events {
+		aggregate := aggregateKey(events[i])
+		if blocked[aggregate] {
+			continue
+		}
+		if events[i].NextAttemptAt.After(now) || (events[i].ClaimedUntil != nil && events[i].ClaimedUntil.After(now)) {
+			blocked[aggregate] = true
+			continue
+		}
+		claimed = append(claimed, events[i])
+		claimedIDs = append(claimedIDs, events[i].ID)
+	}
+
+	err = repo.Claim(ctx, claimedIDs, now.Add(relay.config.ClaimTimeout))
+	if err != nil {
+		err = errors.Wrap(err, "claim outbox events")
+		return
+	}
+	return
+}
+
+func aggregateKey(outboxEvent model.OutboxEvent) string {
+	return outboxEvent.AggregateType + ":" + strconv.Itoa(outboxEvent.AggregateID)
+}
+
+func (relay *Relay) deliver(ctx context.Context, outboxEvent model.OutboxEvent) (err error) {
+	envelope := struct {
+		event.Event
+		Data json.RawMessage `json:"data"`
+	}{}
+	err = json.Unmarshal([]byte(outboxEvent.Payload), &envelope)
+	if err != nil {
+		return errors.Wrap(err, "unmarshal outbox payload")
+	}
+	domainEvent := envelope.Event
+	domainEvent.Sequence = outboxEvent.ID
+	domainEvent.Data = envelope.Data
+
+	for _, sink := range relay.sinks {
+		err = sink.Deliver(ctx, domainEvent)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (relay *Relay) backoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < relay.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > relay.config.BackoffMax {
+		delay = relay.config.BackoffMax
+	}
+	return delay
+}
+
+// Service lets admins see the dead events and requeue them, a requeued event is relayed
+// after the events of its aggregate that were published meanwhile
+type Service struct {
+	repo    outbox.Repositorier
+	account account.Servicer
+	relay   event.Notifier
+}
+
+func NewService(
+	repositorier outbox.Repositorier,
+	accountSvc account.Servicer,
+	relay event.Notifier,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		account: accountSvc,
+		relay:   relay,
+	}
+}
+
+type Servicer interface {
+	FindDeadLetters(ctx context.Context, adminID int, pgn pagination.Pagination) (events []http.GetOutboxEvent, total int, err error)
+	Retry(ctx context.Context, adminID int, eventID int64) (err error)
+}
+
+func (svc *Service) FindDeadLetters(ctx context.Context, adminID int, pgn pagination.Pagination) (events []http.GetOutboxEvent, total int, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	eventsData, totalData, err := svc.repo.FindDead(ctx, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find dead outbox events")
+		return
+	}
+	events = []http.GetOutboxEvent{}
+	for i := range eventsData {
+		outboxEvent := http.GetOutboxEvent{}
+		copier.Copy(&outboxEvent, &eventsData[i])
+		events = append(events, outboxEvent)
+	}
+	total = int(totalData)
+	return
+}
+
+func (svc *Service) Retry(ctx context.Context, adminID int, eventID int64) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.Requeue(ctx, eventID)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrOutboxEventNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "requeue outbox event")
+		return
+	}
+	svc.relay.Notify()
+	return
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package photo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/pkg/imaging"
+	"go-rest-api/src/pkg/storage"
+
+	uuid "github.com/forkyid/go-utils/v1/uuid"
+	"github.com/pkg/errors"
+)
+
+// publicPrefix keeps the publicly served photos apart from private objects like the
+// attendance selfies that live in the same storage
+const publicPrefix = "public/"
+
+const contentTypeJPEG = "image/jpeg"
+
+type Service struct {
+	storage storage.Storage
+	baseURL string
+}
+
+// NewService links the stored photos and placeholders below baseURL, relative when
+// it is empty
+func NewService(
+	blobStorage storage.Storage,
+	baseURL string,
+) *Service {
+	return &Service{
+		storage: blobStorage,
+		baseURL: baseURL,
+	}
+}
+
+type Servicer interface {
+	Store(ctx context.Context, folder string, photo http.Photo, variants []imaging.Variant) (photoURL string, err error)
+	Delete(ctx context.Context, photoURL string, variants []imaging.Variant)
+	Take(ctx context.Context, photoPath string) (photo io.ReadCloser, contentType string, err error)
+	Placeholder(ctx context.Context, name string) (placeholder []byte)
+	PlaceholderURL(name string) (placeholderURL string)
+}
+
+// Store resizes the photo into its variants under a new random directory of the folder
+// and returns the url of the medium variant, the other variants sit next to it
+func (svc *Service) Store(ctx context.Context, folder string, photo http.Photo, variants []imaging.Variant) (photoURL string, err error) {
+	data, err := ioutil.ReadAll(io.LimitReader(photo.Body, constant.MaximumPhotoSize+1))
+	if err != nil {
+		err = errors.Wrap(err, "read photo")
+		return
+	}
+	if len(data) > constant.MaximumPhotoSize {
+		err = constant.ErrPhotoTooLarge
+		return
+	}
+
+	images, err := imaging.Process(data, variants)
+	if errors.Is(err, imaging.ErrInvalidImage) {
+		err = constant.ErrInvalidPhotoType
+		return
+	} else if errors.Is(err, imaging.ErrImageTooLarge) {
+		err = constant.ErrPhotoDimensionsTooLarge
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "process photo")
+		return
+	}
+
+	directory := folder + "/" + uuid.GetUUID()
+	for i := range images {
+		key := publicPrefix + directory + "/" + images[i].Name + ".jpg"
+		err = svc.storage.Put(key, bytes.NewReader(images[i].Body), int64(len(images[i].Body)), contentTypeJPEG)
+		if err != nil {
+			for j := 0; j < i; j++ {
+				svc.storage.Delete(publicPrefix + directory + "/" + images[j].Name + ".jpg")
+			}
+			err = errors.Wrap(err, "store photo")
+			return
+		}
+	}
+
+	photoURL = svc.baseURL + "/v1/photos/" + directory + "/" + constant.PhotoVariantMedium + ".jpg"
+	return
+}
+
+// Delete removes every variant of a stored photo, urls of placeholders or of other
+// hosts are left alone
+func (svc *Service) Delete(ctx context.Context, photoURL string, variants []imaging.Variant) {
+	photoPath := strings.TrimPrefix(photoURL, svc.baseURL+"/v1/photos/")
+	if photoPath == photoURL || strings.Contains(photoPath, "?") {
+		return
+	}
+
+	directory := path.Dir(path.Clean("/" + photoPath))
+	for _, variant := range variants {
+		svc.storage.Delete(publicPrefix + strings.TrimPrefix(directory, "/") + "/" + variant.Name + ".jpg")
+	}
+}
+
+func (svc *Service) Take(ctx context.Context, photoPath string) (photo io.ReadCloser, contentType string, err error) {
+	// clean before adding the prefix so the path cannot climb out of the public photos
+	key := strings.TrimPrefix(path.Clean("/"+photoPath), "/")
+	if key == "" {
+		err = constant.ErrPhotoNotExist
+		return
+	}
+
+	photo, contentType, err = svc.storage.Get(publicPrefix + key)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		err = constant.ErrPhotoNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "get photo")
+		return
+	}
+	return
+}
+
+func (svc *Service) Placeholder(ctx context.Context, name string) (placeholder []byte) {
+	return imaging.Placeholder(name)
+}
+
+// PlaceholderURL is the url of the generated placeholder shown until a photo is uploaded
+func (svc *Service) PlaceholderURL(name string) (placeholderURL string) {
+	return svc.baseURL + "/v1/photos/placeholder.svg?name=" + url.QueryEscape(name)
+}
@@ -0,0 +1,346 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+
+	"go-rest-api/src/constant"
+	"go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/pkg/storage"
+	"go-rest-api/src/repository/v1/privacy"
+	"go-rest-api/src/service/v1/account"
+	"go-rest-api/src/service/v1/attendance"
+	"go-rest-api/src/service/v1/audit"
+	"go-rest-api/src/service/v1/device"
+
+	"github.com/forkyid/go-utils/v1/aes"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo       privacy.Repositorier
+	account    account.Servicer
+	attendance attendance.Servicer
+	device     device.Servicer
+	audit      audit.Servicer
+	storage    storage.Storage
+}
+
+func NewService(
+	repositorier privacy.Repositorier,
+	accountSvc account.Servicer,
+	attendanceSvc attendance.Servicer,
+	deviceSvc device.Servicer,
+	auditSvc audit.Servicer,
+	blobStorage storage.Storage,
+) *Service {
+	return &Service{
+		repo:       repositorier,
+		account:    accountSvc,
+		attendance: attendanceSvc,
+		device:     deviceSvc,
+		audit:      auditSvc,
+		storage:    blobStorage,
+	}
+}
+
+type Servicer interface {
+	Export(ctx context.Context, accountID int) (export http.AccountExport, err error)
+	WriteExport(ctx context.Context, export http.AccountExport, archive io.Writer) (err error)
+	RequestErasure(ctx context.Context, accountID int) (response http.GetErasureRequest, err error)
+	FindErasureRequests(ctx context.Context, adminID int, status string, pgn pagination.Pagination) (responses []http.GetErasureRequest, total int, err error)
+	ApproveErasure(ctx context.Context, actor model.Actor, requestID int) (err error)
+	RejectErasure(ctx context.Context, adminID int, requestID int, reason string) (err error)
+}
+
+// Export gathers everything held about the account, the attendance photos are only
+// read when the export is written
+func (svc *Service) Export(ctx context.Context, accountID int) (export http.AccountExport, err error) {
+	export.Profile, err = svc.account.TakeProfile(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	export.Attendances, err = svc.attendance.FindExport(ctx, accountID)
+	if err != nil {
+		return
+	}
+	for i := range export.Attendances {
+		if export.Attendances[i].PhotoKey != "" {
+			export.Attendances[i].Photo = fmt.Sprintf("photos/attendance-%d%s", export.Attendances[i].ID, path.Ext(export.Attendances[i].PhotoKey))
+		}
+	}
+
+	export.Devices, err = svc.device.Find(ctx, accountID)
+	if err != nil {
+		return
+	}
+
+	requests, err := svc.repo.FindErasureRequestsByAccount(ctx, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "find erasure requests")
+		return
+	}
+	for i := range requests {
+		export.ErasureRequests = append(export.ErasureRequests, getErasureRequest(requests[i]))
+	}
+
+	export.AuditEntries, err = svc.audit.FindByAccount(ctx, accountID)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// WriteExport writes the export as a zip of json files, the attendances also as csv,
+// with the attendance photos in a photos folder
+func (svc *Service) WriteExport(ctx context.Context, export http.AccountExport, archive io.Writer) (err error) {
+	zipWriter := zip.NewWriter(archive)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{name: "profile.json", data: export.Profile},
+		{name: "attendances.json", data: export.Attendances},
+		{name: "devices.json", data: export.Devices},
+		{name: "erasure_requests.json", data: export.ErasureRequests},
+		{name: "audit_log.json", data: export.AuditEntries},
+	}
+	for _, file := range files {
+		writer, err := zipWriter.Create(file.name)
+		if err != nil {
+			return errors.Wrap(err, "create "+file.name)
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.data); err != nil {
+			return errors.Wrap(err, "write "+file.name)
+		}
+	}
+
+	writer, err := zipWriter.Create("attendances.csv")
+	if err != nil {
+		return errors.Wrap(err, "create attendances.csv")
+	}
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{"id", "location_id", "location_name", "status", "created_at", "photo"})
+	for _, attendance := range export.Attendances {
+		csvWriter.Write([]string{
+			strconv.Itoa(attendance.ID),
+			strconv.Itoa(attendance.LocationID),
+			attendance.LocationName,
+			attendance.Status,
+			attendance.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			attendance.Photo,
+		})
+	}
+	csvWriter.Flush()
+	if err = csvWriter.Error(); err != nil {
+		return errors.Wrap(err, "write attendances.csv")
+	}
+
+	for _, attendance := range export.Attendances {
+		if attendance.PhotoKey == "" {
+			continue
+		}
+		err = svc.writePhoto(zipWriter, attendance.Photo, attendance.PhotoKey)
+		if err != nil {
+			return
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+func (svc *Service) writePhoto(zipWriter *zip.Writer, name string, photoKey string) (err error) {
+	photo, _, err := svc.storage.Get(photoKey)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "get attendance photo")
+	}
+	defer photo.Close()
+
+	// photos are already compressed
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return errors.Wrap(err, "create "+name)
+	}
+	if _, err = io.Copy(writer, photo); err != nil {
+		return errors.Wrap(err, "write "+name)
+	}
+	return
+}
+
+// RequestErasure files a request to erase the personal data of the account, an admin
+// has to approve it
+func (svc *Service) RequestErasure(ctx context.Context, accountID int) (response http.GetErasureRequest, err error) {
+	exist, err := svc.account.CheckAccountByID(ctx, accountID)
+	if err != nil {
+		return
+	}
+	if !exist {
+		err = constant.ErrAccountNotRegistered
+		return
+	}
+
+	_, err = svc.repo.TakePendingErasureRequest(ctx, accountID)
+	if err == nil {
+		err = constant.ErrErasureRequestExist
+		return
+	} else if err != gorm.ErrRecordNotFound {
+		err = errors.Wrap(err, "take pending erasure request")
+		return
+	}
+
+	request := model.ErasureRequest{
+		AccountID: accountID,
+		Status:    constant.ErasureStatusPending,
+	}
+	requestID, err := svc.repo.CreateErasureRequest(ctx, request)
+	if err != nil {
+		err = errors.Wrap(err, "create erasure request")
+		return
+	}
+
+	request, err = svc.repo.TakeErasureRequestByID(ctx, requestID)
+	if err != nil {
+		err = errors.Wrap(err, "take erasure request")
+		return
+	}
+	response = getErasureRequest(request)
+	return
+}
+
+func (svc *Service) FindErasureRequests(ctx context.Context, adminID int, status string, pgn pagination.Pagination) (responses []http.GetErasureRequest, total int, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	requests, count, err := svc.repo.FindErasureRequests(ctx, status, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find erasure requests")
+		return
+	}
+
+	responses = []http.GetErasureRequest{}
+	for i := range requests {
+		responses = append(responses, getErasureRequest(requests[i]))
+	}
+	total = int(count)
+	return
+}
+
+// ApproveErasure erases the personal data of the account of the request. Attendances
+// are kept for payroll without their photos, devices are revoked and anonymized, and
+// the account is anonymized and deleted together with the copies of its data in the
+// outbox events and webhook deliveries. Every step can be repeated, so a failed
+// approval can simply be retried.
+func (svc *Service) ApproveErasure(ctx context.Context, actor model.Actor, requestID int) (err error) {
+	err = svc.checkAdmin(ctx, actor.AccountID)
+	if err != nil {
+		return
+	}
+
+	request, err := svc.takePendingErasureRequest(ctx, requestID)
+	if err != nil {
+		return
+	}
+
+	err = svc.attendance.ErasePhotos(ctx, actor, request.AccountID)
+	if err != nil {
+		return
+	}
+	err = svc.device.Erase(ctx, request.AccountID)
+	if err != nil {
+		return
+	}
+	err = svc.account.Erase(ctx, actor, request.AccountID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.ProcessErasureRequest(ctx, requestID, constant.ErasureStatusCompleted, nil, actor.AccountID)
+	if errors.Is(err, constant.ErrErasureRequestProcessed) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "complete erasure request")
+		return
+	}
+	return
+}
+
+func (svc *Service) RejectErasure(ctx context.Context, adminID int, requestID int, reason string) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	_, err = svc.takePendingErasureRequest(ctx, requestID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.ProcessErasureRequest(ctx, requestID, constant.ErasureStatusRejected, &reason, adminID)
+	if errors.Is(err, constant.ErrErasureRequestProcessed) {
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "reject erasure request")
+		return
+	}
+	return
+}
+
+func (svc *Service) takePendingErasureRequest(ctx context.Context, requestID int) (request model.ErasureRequest, err error) {
+	request, err = svc.repo.TakeErasureRequestByID(ctx, requestID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrErasureRequestNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take erasure request")
+		return
+	}
+	if request.Status != constant.ErasureStatusPending {
+		err = constant.ErrErasureRequestProcessed
+		return
+	}
+	return
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
+
+func getErasureRequest(request model.ErasureRequest) http.GetErasureRequest {
+	return http.GetErasureRequest{
+		ID:          int(request.ID),
+		AccountID:   aes.Encrypt(request.AccountID),
+		Status:      request.Status,
+		Reason:      request.Reason,
+		ProcessedAt: request.ProcessedAt,
+		CreatedAt:   request.CreatedAt,
+	}
+}
@@ -0,0 +1,475 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rest-api/src/config"
+	"go-rest-api/src/constant"
+	entity "go-rest-api/src/http"
+	"go-rest-api/src/model"
+	"go-rest-api/src/pkg/event"
+	"go-rest-api/src/pkg/logging"
+	"go-rest-api/src/pkg/netguard"
+	"go-rest-api/src/pkg/pagination"
+	"go-rest-api/src/repository/v1/webhook"
+	"go-rest-api/src/service/v1/account"
+
+	"github.com/jinzhu/copier"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const claimBatchSize = 20
+
+type Service struct {
+	repo    webhook.Repositorier
+	account account.Servicer
+	client  *http.Client
+	config  config.Webhook
+}
+
+func NewService(
+	repositorier webhook.Repositorier,
+	accountSvc account.Servicer,
+	cfg config.Webhook,
+) *Service {
+	return &Service{
+		repo:    repositorier,
+		account: accountSvc,
+		client:  netguard.Client(cfg.Timeout),
+		config:  cfg,
+	}
+}
+
+type Servicer interface {
+	Find(ctx context.Context, adminID int) (webhooks []entity.GetWebhook, err error)
+	Create(ctx context.Context, adminID int, request entity.CreateWebhook) (response entity.CreatedWebhook, err error)
+	Update(ctx context.Context, adminID int, subscriptionID int, request entity.UpdateWebhook) (err error)
+	Delete(ctx context.Context, adminID int, subscriptionID int) (err error)
+	FindDeliveries(ctx context.Context, adminID int, subscriptionID int, status string, pgn pagination.Pagination) (deliveries []entity.GetWebhookDelivery, total int, err error)
+	Retry(ctx context.Context, adminID int, deliveryID int) (err error)
+	Run(ctx context.Context)
+}
+
+func (svc *Service) Find(ctx context.Context, adminID int) (webhooks []entity.GetWebhook, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	subscriptions, err := svc.repo.FindSubscriptions(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "find webhook subscriptions")
+		return
+	}
+	webhooks = []entity.GetWebhook{}
+	for i := range subscriptions {
+		webhooks = append(webhooks, entity.GetWebhook{
+			ID:         int(subscriptions[i].ID),
+			URL:        subscriptions[i].URL,
+			EventTypes: subscriptions[i].Events(),
+			IsActive:   subscriptions[i].IsActive,
+			CreatedAt:  subscriptions[i].CreatedAt,
+		})
+	}
+	return
+}
+
+func (svc *Service) Create(ctx context.Context, adminID int, request entity.CreateWebhook) (response entity.CreatedWebhook, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	if err = validateURL(ctx, request.URL); err != nil {
+		return
+	}
+	eventTypes, err := validateEventTypes(request.EventTypes)
+	if err != nil {
+		return
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			err = errors.Wrap(err, "generate webhook secret")
+			return
+		}
+	}
+
+	subscriptionID, err := svc.repo.CreateSubscription(ctx, model.WebhookSubscription{
+		URL:        request.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		IsActive:   true,
+		CreatedBy:  adminID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "create webhook subscription")
+		return
+	}
+
+	response = entity.CreatedWebhook{
+		ID:     subscriptionID,
+		Secret: secret,
+	}
+	return
+}
+
+func (svc *Service) Update(ctx context.Context, adminID int, subscriptionID int, request entity.UpdateWebhook) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.checkSubscription(ctx, subscriptionID)
+	if err != nil {
+		return
+	}
+
+	subscription := model.WebhookSubscription{}
+	columns := []string{}
+	if request.URL != nil {
+		if err = validateURL(ctx, *request.URL); err != nil {
+			return
+		}
+		subscription.URL = *request.URL
+		columns = append(columns, "url")
+	}
+	if request.EventTypes != nil {
+		subscription.EventTypes, err = validateEventTypes(request.EventTypes)
+		if err != nil {
+			return
+		}
+		columns = append(columns, "event_types")
+	}
+	if request.IsActive != nil {
+		subscription.IsActive = *request.IsActive
+		columns = append(columns, "is_active")
+	}
+	if len(columns) == 0 {
+		return
+	}
+
+	err = svc.repo.UpdateSubscription(ctx, subscriptionID, subscription, columns)
+	if err != nil {
+		err = errors.Wrap(err, "update webhook subscription")
+		return
+	}
+	return
+}
+
+func (svc *Service) Delete(ctx context.Context, adminID int, subscriptionID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.DeleteSubscription(ctx, subscriptionID)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrWebhookNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "delete webhook subscription")
+		return
+	}
+	return
+}
+
+// FindDeliveries returns the delivery log of a subscription, or of every subscription
+// when subscriptionID is zero, e.g. to list the dead letters
+func (svc *Service) FindDeliveries(ctx context.Context, adminID int, subscriptionID int, status string, pgn pagination.Pagination) (deliveries []entity.GetWebhookDelivery, total int, err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	if subscriptionID != 0 {
+		err = svc.checkSubscription(ctx, subscriptionID)
+		if err != nil {
+			return
+		}
+	}
+
+	deliveriesData, totalData, err := svc.repo.FindDeliveries(ctx, subscriptionID, status, pgn)
+	if err != nil {
+		err = errors.Wrap(err, "find webhook deliveries")
+		return
+	}
+	deliveries = []entity.GetWebhookDelivery{}
+	for i := range deliveriesData {
+		delivery := entity.GetWebhookDelivery{}
+		copier.Copy(&delivery, &deliveriesData[i])
+		deliveries = append(deliveries, delivery)
+	}
+	total = int(totalData)
+	return
+}
+
+func (svc *Service) Retry(ctx context.Context, adminID int, deliveryID int) (err error) {
+	err = svc.checkAdmin(ctx, adminID)
+	if err != nil {
+		return
+	}
+
+	err = svc.repo.RequeueDelivery(ctx, deliveryID)
+	if errors.Is(err, constant.ErrInvalidID) {
+		err = constant.ErrWebhookDeliveryNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "requeue webhook delivery")
+		return
+	}
+	return
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (svc *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(svc.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deliveries, err := svc.repo.ClaimDeliveries(ctx, claimBatchSize, claimBatchSize*svc.config.Timeout)
+			if err != nil {
+				logging.FromContext(ctx).Error("claim webhook deliveries", "error", err)
+				continue
+			}
+			for i := range deliveries {
+				svc.deliver(ctx, deliveries[i])
+			}
+		}
+	}
+}
+
+// deliver sends one attempt and schedules the next one with exponential backoff, after
+// the last attempt the delivery is moved to the dead letters
+func (svc *Service) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	subscription, err := svc.repo.TakeSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err == gorm.ErrRecordNotFound || (err == nil && !subscription.IsActive) {
+		message := "subscription is deleted or inactive"
+		err = svc.repo.UpdateDelivery(ctx, delivery.ID, model.WebhookDelivery{
+			Status:    constant.DeliveryStatusDead,
+			LastError: &message,
+		}, []string{"status", "last_error"})
+		if err != nil {
+			logging.FromContext(ctx).Error("update webhook delivery", "error", err, "delivery_id", delivery.ID)
+		}
+		return
+	} else if err != nil {
+		logging.FromContext(ctx).Error("take webhook subscription", "error", err, "subscription_id", delivery.SubscriptionID)
+		return
+	}
+
+	status, err := svc.send(ctx, subscription, delivery)
+	now := time.Now().UTC()
+	update := model.WebhookDelivery{
+		Attempts:       delivery.Attempts + 1,
+		ResponseStatus: &status,
+	}
+	columns := []string{"attempts", "response_status", "status", "last_error"}
+	if err == nil {
+		update.Status = constant.DeliveryStatusDelivered
+		update.DeliveredAt = &now
+		columns = append(columns, "delivered_at")
+	} else {
+		message := err.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+		update.LastError = &message
+		update.Status = constant.DeliveryStatusPending
+		if update.Attempts >= svc.config.MaxAttempts {
+			update.Status = constant.DeliveryStatusDead
+		} else {
+			update.NextAttemptAt = now.Add(svc.backoff(update.Attempts))
+			columns = append(columns, "next_attempt_at")
+		}
+	}
+
+	err = svc.repo.UpdateDelivery(ctx, delivery.ID, update, columns)
+	if err != nil {
+		logging.FromContext(ctx).Error("update webhook delivery", "error", err, "delivery_id", delivery.ID)
+	}
+}
+
+func (svc *Service) send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (status int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", constant.ContentTypeApplicationJson)
+	req.Header.Set(constant.HeaderWebhookID, delivery.EventID)
+	req.Header.Set(constant.HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(constant.HeaderWebhookSignature, fmt.Sprintf("t=%s,v1=%s",
+		timestamp, Sign(subscription.Secret, timestamp, []byte(delivery.Payload))))
+
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	status = resp.StatusCode
+	if status < 200 || status > 299 {
+		err = fmt.Errorf("unexpected response status %d", status)
+	}
+	return
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "timestamp.body", receivers recompute it
+// from the X-Webhook-Signature timestamp to verify a delivery
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (svc *Service) backoff(attempts int) time.Duration {
+	delay := svc.config.BackoffBase
+	for i := 1; i < attempts && delay < svc.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > svc.config.BackoffMax {
+		delay = svc.config.BackoffMax
+	}
+	return delay
+}
+
+func (svc *Service) checkSubscription(ctx context.Context, subscriptionID int) (err error) {
+	_, err = svc.repo.TakeSubscriptionByID(ctx, subscriptionID)
+	if err == gorm.ErrRecordNotFound {
+		err = constant.ErrWebhookNotExist
+		return
+	} else if err != nil {
+		err = errors.Wrap(err, "take webhook subscription")
+		return
+	}
+	return
+}
+
+func (svc *Service) checkAdmin(ctx context.Context, adminID int) (err error) {
+	allowed, err := svc.account.CheckAccountRole(ctx, adminID, constant.RoleAdmin)
+	if err != nil {
+		err = errors.Wrap(err, "check account role")
+		return
+	}
+	if !allowed {
+		err = constant.ErrPermissionDenied
+		return
+	}
+	return
+}
+
+// validateURL accepts an absolute http or https url whose host only resolves to public
+// addresses. The client checks the address again when it connects, the host may
+// resolve differently by then
+func validateURL(ctx context.Context, rawURL string) (err error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || !target.IsAbs() || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return constant.ErrInvalidWebhookURL
+	}
+	if err = netguard.CheckHost(ctx, target.Hostname()); err != nil {
+		return constant.ErrWebhookURLNotAllowed
+	}
+	return nil
+}
+
+func validateEventTypes(eventTypes []string) (joined string, err error) {
+	for _, eventType := range eventTypes {
+		valid := eventType == constant.EventWildcard
+		for _, known := range constant.EventTypes {
+			if eventType == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			err = errors.Wrap(constant.ErrInvalidEventType, eventType)
+			return
+		}
+	}
+	joined = strings.Join(eventTypes, ",")
+	return
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sink fans a domain event out to a pending delivery for every subscribed webhook
+type Sink struct {
+	repo webhook.Repositorier
+}
+
+func NewSink(
+	repositorier webhook.Repositorier,
+) *Sink {
+	return &Sink{
+		repo: repositorier,
+	}
+}
+
+// Deliver queues the event, deliveries are unique per subscription and event id so an
+// event relayed twice is only sent once
+func (sink *Sink) Deliver(ctx context.Context, domainEvent event.Event) (err error) {
+	subscriptions, err := sink.repo.FindSubscriptionsByEvent(ctx, domainEvent.Type)
+	if err != nil {
+		err = errors.Wrap(err, "find webhook subscriptions by event")
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(domainEvent)
+	if err != nil {
+		err = errors.Wrap(err, "marshal webhook payload")
+		return
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]model.WebhookDelivery, len(subscriptions))
+	for i := range subscriptions {
+		deliveries[i] = model.WebhookDelivery{
+			SubscriptionID: int(subscriptions[i].ID),
+			EventID:        domainEvent.ID,
+			EventType:      domainEvent.Type,
+			Payload:        string(payload),
+			Status:         constant.DeliveryStatusPending,
+			NextAttemptAt:  now,
+		}
+	}
+
+	err = sink.repo.CreateDeliveries(ctx, deliveries)
+	if err != nil {
+		err = errors.Wrap(err, "create webhook deliveries")
+		return
+	}
+	return
+}